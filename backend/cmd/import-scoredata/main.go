@@ -0,0 +1,58 @@
+// Command import-scoredata stores the scores in Clone Hero's scoredata.bin,
+// skipping any already read from screenshots. Charts are matched to songs by
+// hash, so import the song catalog first (see import-songs).
+//
+// Usage:
+//
+//	DATABASE_URL=postgres://... import-scoredata -file "/path/to/Clone Hero/scoredata.bin" -player name
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"cloneheroer/internal/db"
+	"cloneheroer/internal/scoredata"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func main() {
+	path := flag.String("file", "", "path to scoredata.bin")
+	player := flag.String("player", "", "player name to store the scores under (required)")
+	flag.Parse()
+
+	if *path == "" {
+		log.Fatal("no scoredata.bin: pass -file")
+	}
+	// The file doesn't say who played; without a name the scores can't be
+	// matched to screenshots or profiles
+	name := strings.TrimSpace(*player)
+	if name == "" {
+		log.Fatal("no player name: pass -player with the name shown on your results screens")
+	}
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		log.Fatal("DATABASE_URL is required")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	pool, err := pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		log.Fatalf("failed to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	result, err := scoredata.NewIngester(db.NewRepo(pool), name).Ingest(ctx, *path)
+	if err != nil {
+		log.Fatalf("import failed after %d scores: %v", result.Imported, err)
+	}
+	log.Printf("imported %d scores (%d already stored, %d charts not in the catalog)", result.Imported, result.Duplicates, result.UnknownCharts)
+}
@@ -0,0 +1,60 @@
+// Command import-songs loads song metadata from a Clone Hero songs folder
+// into the catalog. The database must already be migrated (starting the
+// server once does that).
+//
+// Usage:
+//
+//	DATABASE_URL=postgres://... import-songs -dir "/path/to/Clone Hero/Songs" [-watch 10m]
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"cloneheroer/internal/catalog"
+	"cloneheroer/internal/db"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func main() {
+	songsDir := flag.String("dir", os.Getenv("SONGS_DIR"), "Clone Hero songs folder (default $SONGS_DIR)")
+	watch := flag.Duration("watch", 0, "keep running and rescan this often, e.g. 10m")
+	flag.Parse()
+
+	if *songsDir == "" {
+		log.Fatal("no songs folder: pass -dir or set SONGS_DIR")
+	}
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		log.Fatal("DATABASE_URL is required")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	pool, err := pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		log.Fatalf("failed to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	importer := catalog.NewImporter(*songsDir, db.NewRepo(pool))
+	if *watch > 0 {
+		log.Printf("watching songs folder %q every %s", *songsDir, *watch)
+		importer.Watch(ctx, *watch)
+		return
+	}
+
+	start := time.Now()
+	result, err := importer.Import(ctx)
+	if err != nil {
+		log.Fatalf("import failed: %v", err)
+	}
+	log.Printf("imported %d songs in %s (%d failed)", result.Imported, time.Since(start).Round(time.Millisecond), result.Failed)
+}
@@ -0,0 +1,107 @@
+// Command parse runs the screenshot parser on image files and prints what it
+// read as JSON, without touching the database.
+//
+// Usage:
+//
+//	parse [-debug] [-debug-file image]... [-debug-dir dir] [-layout name] [image...]
+//
+// With -debug, every region crop, preprocessed crop, the OCR text and the
+// result are written to a folder per image under -debug-dir, for every image
+// in the run. -debug-file does the same for one image only, which is parsed
+// along with the rest; repeat it for more.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"cloneheroer/internal/db"
+	"cloneheroer/internal/parser"
+)
+
+func main() {
+	debug := flag.Bool("debug", false, "write crops, OCR text and results for every image")
+	debugFiles := map[string]bool{}
+	var images []string
+	flag.Func("debug-file", "parse `image` and write crops, OCR text and results for it alone (repeatable)", func(path string) error {
+		if !debugFiles[path] {
+			debugFiles[path] = true
+			images = append(images, path)
+		}
+		return nil
+	})
+	debugDir := flag.String("debug-dir", envOr("DEBUG_DIR", parser.DefaultDebugDir), "folder for debug output (default $DEBUG_DIR or debug)")
+	layout := flag.String("layout", envOr("LAYOUT_PROFILE", parser.LayoutAuto), "layout profile")
+	timezone := flag.String("timezone", envOr("TIMEZONE", "Local"), "zone of the time stamped in file names, e.g. Europe/Berlin (default $TIMEZONE or Local)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] image...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	for _, path := range flag.Args() {
+		if !debugFiles[path] {
+			images = append(images, path)
+		}
+	}
+	if len(images) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	loc, err := time.LoadLocation(*timezone)
+	if err != nil {
+		log.Fatalf("invalid timezone: %v", err)
+	}
+
+	p, err := parser.NewParser(1920, 1080,
+		parser.WithLayout(*layout),
+		parser.WithLayoutsFile(os.Getenv("LAYOUTS_FILE")),
+		parser.WithPreprocessFile(os.Getenv("PREPROCESS_FILE")),
+		parser.WithOCRSettingsFile(os.Getenv("OCR_SETTINGS_FILE")),
+		parser.WithDebugDir(*debugDir),
+		parser.WithTimezone(loc),
+	)
+	if err != nil {
+		log.Fatalf("failed to create parser: %v", err)
+	}
+	defer p.Close()
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	failed := false
+	for _, imagePath := range images {
+		result := struct {
+			File     string              `json:"file"`
+			Score    *db.CreateScoreData `json:"score,omitempty"`
+			DebugDir string              `json:"debug_dir,omitempty"`
+			Error    string              `json:"error,omitempty"`
+		}{File: imagePath}
+
+		if *debug || debugFiles[imagePath] {
+			result.Score, result.DebugDir, err = p.ParseImageDebug(imagePath)
+		} else {
+			result.Score, err = p.ParseImage(imagePath)
+		}
+		if err != nil {
+			result.Error = err.Error()
+			failed = true
+		}
+		if err := enc.Encode(result); err != nil {
+			log.Fatalf("failed to write result: %v", err)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
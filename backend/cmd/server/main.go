@@ -8,11 +8,15 @@ import (
 	"os/signal"
 	"path/filepath"
 	"syscall"
+	"time"
 
+	"cloneheroer/internal/catalog"
 	"cloneheroer/internal/config"
 	"cloneheroer/internal/db"
 	"cloneheroer/internal/parser"
+	"cloneheroer/internal/pipeline"
 	"cloneheroer/internal/server"
+	"cloneheroer/internal/validate"
 	"cloneheroer/internal/watcher"
 
 	"github.com/golang-migrate/migrate/v4"
@@ -48,35 +52,72 @@ func main() {
 		}
 	}
 
-	repo := db.NewRepo(pool)
+	// Corrections made through the API re-run the checks that produced each
+	// score's warnings
+	repo := db.NewRepo(pool, db.WithMatchThreshold(cfg.MatchThreshold), db.WithValidator(validate.Score))
 
-	// Initialize parser with configurable image dimensions
-	imgParser, err := parser.NewParser(cfg.MaxImageWidth, cfg.MaxImageHeight)
+	// Clone Hero stamps screenshot names with the local time of the machine it runs on
+	timezone, err := time.LoadLocation(cfg.Timezone)
 	if err != nil {
-		log.Fatalf("failed to create parser: %v", err)
+		log.Fatalf("invalid TIMEZONE: %v", err)
 	}
-	defer imgParser.Close()
 
-	// Create file processor function
-	processFile := func(filePath string) error {
-		log.Printf("parsing image: %s", filePath)
-		scoreData, err := imgParser.ParseImage(filePath)
+	// Tesseract reads the screenshots, skipping the ones that aren't results
+	// screens and serving POST /parse, unless sidecar fixtures were asked for.
+	// Fixture mode never creates an OCR client, so it runs without Tesseract.
+	var extractor pipeline.ScoreExtractor
+	var processOpts []pipeline.Option
+	var serverOpts []server.Option
+	switch cfg.Extractor {
+	case "tesseract":
+		// Initialize parser with configurable image dimensions, layout profile, preprocessing and OCR settings
+		imgParser, err := parser.NewParser(
+			cfg.MaxImageWidth,
+			cfg.MaxImageHeight,
+			parser.WithLayout(cfg.LayoutProfile),
+			parser.WithLayoutsFile(cfg.LayoutsFile),
+			parser.WithPreprocessFile(cfg.PreprocessFile),
+			parser.WithOCRSettingsFile(cfg.OCRSettingsFile),
+			parser.WithPoolSize(cfg.OCRPoolSize),
+			parser.WithDebug(cfg.ParserDebug),
+			parser.WithDebugDir(cfg.DebugDir),
+			parser.WithTimezone(timezone),
+		)
 		if err != nil {
-			return fmt.Errorf("failed to parse image: %w", err)
+			log.Fatalf("failed to create parser: %v", err)
 		}
-
-		log.Printf("creating score for: %s - %s", scoreData.Artist, scoreData.SongName)
-		scoreID, err := repo.CreateScore(ctx, *scoreData)
-		if err != nil {
-			return fmt.Errorf("failed to create score: %w", err)
-		}
-
-		log.Printf("successfully created score with ID: %d", scoreID)
-		return nil
+		defer imgParser.Close()
+
+		extractor = imgParser
+		processOpts = append(processOpts, pipeline.WithClassifier(imgParser))
+		serverOpts = append(serverOpts, server.WithParser(imgParser))
+	case "fixture":
+		log.Printf("using JSON sidecar fixtures instead of OCR; POST /parse is disabled")
+		extractor = pipeline.NewFixtureExtractor()
+	default:
+		log.Fatalf("unknown EXTRACTOR %q (want tesseract or fixture)", cfg.Extractor)
+	}
+	// Screenshots that look like another capture of a stored score are
+	// flagged, or dropped with SKIP_DUPLICATES
+	if cfg.DuplicateWindow > 0 {
+		processOpts = append(processOpts, pipeline.WithDuplicates(repo, pipeline.DuplicatePolicy{
+			Window:      cfg.DuplicateWindow,
+			MaxDistance: cfg.DuplicateMaxDistance,
+			Skip:        cfg.SkipDuplicates,
+		}))
 	}
+	processFile := pipeline.NewFileProcessor(ctx, extractor, repo, processOpts...)
 
 	// Initialize and start file watcher
-	fileWatcher, err := watcher.NewWatcher(cfg.WatchDir, cfg.ProcessedDir, cfg.FailedDir, processFile)
+	// Backfilling existing files uses one worker per OCR client
+	fileWatcher, err := watcher.NewWatcher(
+		cfg.WatchDir,
+		cfg.ProcessedDir,
+		cfg.FailedDir,
+		processFile,
+		watcher.WithWorkers(cfg.OCRPoolSize),
+		watcher.WithSkippedDir(cfg.SkippedDir),
+	)
 	if err != nil {
 		log.Fatalf("failed to create watcher: %v", err)
 	}
@@ -87,8 +128,18 @@ func main() {
 	}
 	log.Printf("watching directory: %q", cfg.WatchDir)
 
+	// Keep the catalog in sync with the local songs folder
+	if cfg.SongsDir != "" {
+		if cfg.SongsScanInterval > 0 {
+			log.Printf("importing songs from %q every %s", cfg.SongsDir, cfg.SongsScanInterval)
+		} else {
+			log.Printf("importing songs from %q once", cfg.SongsDir)
+		}
+		go catalog.NewImporter(cfg.SongsDir, repo).Watch(ctx, cfg.SongsScanInterval)
+	}
+
 	// Start HTTP server
-	srv := server.New(repo)
+	srv := server.New(repo, serverOpts...)
 	addr := fmt.Sprintf(":%d", cfg.Port)
 	log.Printf("starting server on %s", addr)
 
@@ -0,0 +1,143 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"cloneheroer/internal/db"
+)
+
+// Store saves imported songs. *db.Repo is the Postgres implementation.
+type Store interface {
+	UpsertCatalogSong(ctx context.Context, song db.CatalogSong) (int64, error)
+}
+
+// Importer loads every song.ini under a songs folder into a Store. It
+// remembers when each file was last imported, so rescanning only touches
+// songs that were added or changed.
+type Importer struct {
+	songsDir string
+	store    Store
+
+	mu       sync.Mutex
+	imported map[string]time.Time
+}
+
+// Result counts what one Import did.
+type Result struct {
+	Imported  int
+	Unchanged int
+	Failed    int
+}
+
+// NewImporter creates an importer for songsDir.
+func NewImporter(songsDir string, store Store) *Importer {
+	return &Importer{
+		songsDir: songsDir,
+		store:    store,
+		imported: make(map[string]time.Time),
+	}
+}
+
+// Import walks the songs folder and upserts each new or modified song.ini.
+// A song.ini that can't be parsed or stored is logged and counted as failed;
+// only a folder that can't be walked is an error.
+func (i *Importer) Import(ctx context.Context) (Result, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var result Result
+	err := filepath.WalkDir(i.songsDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == i.songsDir {
+				return err
+			}
+			log.Printf("warning: skipping %q: %v", path, err)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if entry.IsDir() || !strings.EqualFold(entry.Name(), SongINI) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			log.Printf("warning: skipping %q: %v", path, err)
+			return nil
+		}
+		if last, ok := i.imported[path]; ok && last.Equal(info.ModTime()) {
+			result.Unchanged++
+			return nil
+		}
+
+		if err := i.importFile(ctx, path); err != nil {
+			log.Printf("error importing %q: %v", path, err)
+			result.Failed++
+			return nil
+		}
+		i.imported[path] = info.ModTime()
+		result.Imported++
+		return nil
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to scan songs directory: %w", err)
+	}
+	return result, nil
+}
+
+func (i *Importer) importFile(ctx context.Context, path string) error {
+	song, err := ParseSongINI(path)
+	if err != nil {
+		return err
+	}
+	if song.ChartHash, err = ChartHash(filepath.Dir(path)); err != nil {
+		return err
+	}
+	if _, err := i.store.UpsertCatalogSong(ctx, *song); err != nil {
+		return fmt.Errorf("failed to store song: %w", err)
+	}
+	return nil
+}
+
+// Watch imports the songs folder now and then every interval until ctx is
+// cancelled. Songs are spread over many nested folders, so rescanning is
+// simpler and more reliable than filesystem events here. An interval of 0 or
+// less imports once and returns.
+func (i *Importer) Watch(ctx context.Context, interval time.Duration) {
+	i.importAndLog(ctx)
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			i.importAndLog(ctx)
+		}
+	}
+}
+
+func (i *Importer) importAndLog(ctx context.Context) {
+	result, err := i.Import(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("error importing songs: %v", err)
+		}
+		return
+	}
+	if result.Imported > 0 || result.Failed > 0 {
+		log.Printf("imported %d songs from %q (%d failed, %d unchanged)", result.Imported, i.songsDir, result.Failed, result.Unchanged)
+	}
+}
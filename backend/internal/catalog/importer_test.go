@@ -0,0 +1,101 @@
+package catalog
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"cloneheroer/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImporter_Import(t *testing.T) {
+	store := &memStore{}
+	importer := NewImporter(_songsPath, store)
+
+	result, err := importer.Import(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, Result{Imported: 2, Failed: 1}, result)
+	assert.ElementsMatch(t, []string{"Master of Puppets", "Tripping Billies"}, store.names())
+	for _, song := range store.songs {
+		assert.Len(t, song.ChartHash, 32, song.Name)
+	}
+
+	// Nothing changed, so nothing is stored again
+	result, err = importer.Import(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, Result{Unchanged: 2, Failed: 1}, result)
+	assert.Len(t, store.names(), 2)
+}
+
+func TestImporter_ImportsChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	iniPath := filepath.Join(dir, "Band - Song", SongINI)
+	require.NoError(t, os.MkdirAll(filepath.Dir(iniPath), 0755))
+	require.NoError(t, os.WriteFile(iniPath, []byte("[song]\nname = Song\nartist = Band\n"), 0644))
+
+	store := &memStore{}
+	importer := NewImporter(dir, store)
+	_, err := importer.Import(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(iniPath, []byte("[song]\nname = Song\nartist = Band\nyear = 2001\n"), 0644))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(iniPath, later, later))
+
+	result, err := importer.Import(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+	require.Len(t, store.songs, 2)
+	assert.Equal(t, 2001, store.songs[1].Year)
+}
+
+func TestImporter_WatchWithoutInterval(t *testing.T) {
+	store := &memStore{}
+
+	done := make(chan struct{})
+	go func() {
+		NewImporter(_songsPath, store).Watch(context.Background(), 0)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch kept running without an interval")
+	}
+	assert.Len(t, store.names(), 2, "the folder is imported once")
+}
+
+func TestImporter_MissingFolder(t *testing.T) {
+	_, err := NewImporter(filepath.Join(t.TempDir(), "nope"), &memStore{}).Import(context.Background())
+	assert.Error(t, err)
+}
+
+// memStore is an in-memory Store.
+type memStore struct {
+	mu    sync.Mutex
+	songs []db.CatalogSong
+}
+
+func (m *memStore) UpsertCatalogSong(_ context.Context, song db.CatalogSong) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.songs = append(m.songs, song)
+	return int64(len(m.songs)), nil
+}
+
+func (m *memStore) names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var names []string
+	for _, song := range m.songs {
+		names = append(names, song.Name)
+	}
+	return names
+}
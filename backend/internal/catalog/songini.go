@@ -0,0 +1,170 @@
+// Package catalog imports song metadata from a Clone Hero songs folder.
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"cloneheroer/internal/db"
+)
+
+// SongINI is the name of the metadata file in every song folder.
+const SongINI = "song.ini"
+
+// chartFiles are the note files a song folder may hold, in the order Clone
+// Hero prefers them.
+var chartFiles = []string{"notes.mid", "notes.chart"}
+
+// richTextTag matches the formatting tags Clone Hero allows in song.ini
+// names, like <color=#FF0000> or </b>.
+var richTextTag = regexp.MustCompile(`</?[a-zA-Z]+(=[^>]*)?>`)
+
+// yearDigits finds the year in values like "2004" or ", 2004" (older charts
+// prefix it with a comma).
+var yearDigits = regexp.MustCompile(`\d{4}`)
+
+// ParseSongINI reads the [song] section of a song.ini file.
+func ParseSongINI(path string) (*db.CatalogSong, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", SongINI, err)
+	}
+
+	song, err := parseSongINI(decodeINI(contents))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return song, nil
+}
+
+// parseSongINI parses song.ini text. Keys are case-insensitive, and only the
+// [song] section is read.
+func parseSongINI(text string) (*db.CatalogSong, error) {
+	values := make(map[string]string)
+	inSong := false
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inSong = strings.EqualFold(strings.TrimSpace(line[1:len(line)-1]), "song")
+			continue
+		}
+		if !inSong {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		values[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	song := &db.CatalogSong{
+		Name:    cleanName(values["name"]),
+		Artist:  cleanName(values["artist"]),
+		Charter: cleanName(values["charter"]),
+		Album:   cleanName(values["album"]),
+		Genre:   cleanName(values["genre"]),
+	}
+	if song.Charter == "" {
+		// Charts from before Phase Shift call the charter "frets"
+		song.Charter = cleanName(values["frets"])
+	}
+	if song.Name == "" || song.Artist == "" {
+		return nil, fmt.Errorf("missing name or artist")
+	}
+
+	if year := yearDigits.FindString(values["year"]); year != "" {
+		song.Year, _ = strconv.Atoi(year)
+	}
+	if length, err := strconv.Atoi(values["song_length"]); err == nil && length > 0 {
+		song.SongLengthMs = length
+	}
+
+	for key, value := range values {
+		part, ok := strings.CutPrefix(key, "diff_")
+		if !ok {
+			continue
+		}
+		// -1 means the chart has no such part
+		rating, err := strconv.Atoi(value)
+		if err != nil || rating < 0 {
+			continue
+		}
+		if song.Difficulties == nil {
+			song.Difficulties = make(map[string]int)
+		}
+		song.Difficulties[part] = rating
+	}
+
+	return song, nil
+}
+
+// ChartHash returns the MD5 of the chart in songDir as lowercase hex, the
+// key Clone Hero uses for the song in scoredata.bin. It returns "" when the
+// folder has no chart.
+func ChartHash(songDir string) (string, error) {
+	for _, name := range chartFiles {
+		f, err := os.Open(filepath.Join(songDir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+
+		h := md5.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return "", fmt.Errorf("failed to hash %s: %w", name, err)
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+	return "", nil
+}
+
+// cleanName strips rich text tags and surrounding whitespace.
+func cleanName(s string) string {
+	return strings.TrimSpace(richTextTag.ReplaceAllString(s, ""))
+}
+
+// decodeINI returns the text of a song.ini, which some editors save as
+// UTF-16 or with a UTF-8 byte order mark.
+func decodeINI(contents []byte) string {
+	switch {
+	case bytes.HasPrefix(contents, []byte{0xEF, 0xBB, 0xBF}):
+		return string(contents[3:])
+	case bytes.HasPrefix(contents, []byte{0xFF, 0xFE}):
+		return decodeUTF16(contents[2:], func(b []byte) uint16 { return uint16(b[0]) | uint16(b[1])<<8 })
+	case bytes.HasPrefix(contents, []byte{0xFE, 0xFF}):
+		return decodeUTF16(contents[2:], func(b []byte) uint16 { return uint16(b[0])<<8 | uint16(b[1]) })
+	}
+	return string(contents)
+}
+
+func decodeUTF16(contents []byte, unit func([]byte) uint16) string {
+	units := make([]uint16, 0, len(contents)/2)
+	for i := 0; i+1 < len(contents); i += 2 {
+		units = append(units, unit(contents[i:i+2]))
+	}
+	return string(utf16.Decode(units))
+}
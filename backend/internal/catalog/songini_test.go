@@ -0,0 +1,100 @@
+package catalog
+
+import (
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+
+	"cloneheroer/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const _songsPath = "../../../testdata/songs"
+
+func TestParseSongINI(t *testing.T) {
+	testCases := []struct {
+		name     string
+		path     string
+		expected *db.CatalogSong
+	}{
+		{
+			name: "full metadata",
+			path: "Metallica - Master of Puppets",
+			expected: &db.CatalogSong{
+				Name:         "Master of Puppets",
+				Artist:       "Metallica",
+				Charter:      "Harmonix",
+				Album:        "Master of Puppets",
+				Year:         1986,
+				Genre:        "Thrash Metal",
+				SongLengthMs: 515000,
+				Difficulties: map[string]int{"band": 5, "guitar": 6, "bass": 5, "drums": 6, "drums_real": 6, "vocals": 4},
+			},
+		},
+		{
+			name: "BOM, CRLF, rich text and legacy keys",
+			path: filepath.Join("Setlists", "Dave Matthews Band - Tripping Billies"),
+			expected: &db.CatalogSong{
+				Name:         "Tripping Billies",
+				Artist:       "Dave Matthews Band",
+				Charter:      "SoConfined",
+				Album:        "Crash",
+				Year:         1996,
+				SongLengthMs: 361000,
+				Difficulties: map[string]int{"drums_real": 5, "bass": 3},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			song, err := ParseSongINI(filepath.Join(_songsPath, tc.path, SongINI))
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, song)
+		})
+	}
+}
+
+func TestParseSongINI_Errors(t *testing.T) {
+	_, err := ParseSongINI(filepath.Join(_songsPath, "Broken Chart", SongINI))
+	assert.ErrorContains(t, err, "missing name or artist")
+
+	_, err = ParseSongINI(filepath.Join(_songsPath, "nonexistent", SongINI))
+	assert.Error(t, err)
+}
+
+func TestParseSongINI_OnlySongSection(t *testing.T) {
+	song, err := parseSongINI("name = Wrong\n[other]\nartist = Wrong\n[song]\nname = Right\nartist = Band\n; artist = Comment\n[after]\nname = Wrong\n")
+	require.NoError(t, err)
+	assert.Equal(t, "Right", song.Name)
+	assert.Equal(t, "Band", song.Artist)
+	assert.Nil(t, song.Difficulties)
+}
+
+func TestDecodeINI_UTF16(t *testing.T) {
+	text := "[song]\nname = Motörhead Song\n"
+	units := utf16.Encode([]rune(text))
+
+	le := []byte{0xFF, 0xFE}
+	be := []byte{0xFE, 0xFF}
+	for _, u := range units {
+		le = append(le, byte(u), byte(u>>8))
+		be = append(be, byte(u>>8), byte(u))
+	}
+
+	assert.Equal(t, text, decodeINI(le))
+	assert.Equal(t, text, decodeINI(be))
+}
+
+func TestChartHash(t *testing.T) {
+	hash, err := ChartHash(filepath.Join(_songsPath, "Setlists", "Dave Matthews Band - Tripping Billies"))
+	require.NoError(t, err)
+	assert.Equal(t, "d1387f636f3c7247dacaddcb1aab74f9", hash)
+
+	// No notes file
+	hash, err = ChartHash(filepath.Join(_songsPath, "Broken Chart"))
+	require.NoError(t, err)
+	assert.Empty(t, hash)
+}
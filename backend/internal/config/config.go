@@ -4,21 +4,39 @@ import (
 	"log"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 )
 
 // Config holds runtime configuration loaded from environment variables.
 type Config struct {
-	WatchDir       string `env:"WATCH_DIR,required"`
-	DatabaseURL    string `env:"DATABASE_URL,required"`
-	Port           int    `env:"PORT" envDefault:"3000"`
-	LogLevel       string `env:"LOG_LEVEL" envDefault:"info"`
-	MigrateOnStart bool   `env:"MIGRATE_ON_START" envDefault:"true"`
-	ProcessedDir   string `env:"PROCESSED_DIR" envDefault:""`
-	FailedDir      string `env:"FAILED_DIR" envDefault:""`
-	MaxImageWidth  int    `env:"MAX_IMAGE_WIDTH" envDefault:"1920"`
-	MaxImageHeight int    `env:"MAX_IMAGE_HEIGHT" envDefault:"1080"`
+	WatchDir          string        `env:"WATCH_DIR,required"`
+	DatabaseURL       string        `env:"DATABASE_URL,required"`
+	Port              int           `env:"PORT" envDefault:"3000"`
+	LogLevel          string        `env:"LOG_LEVEL" envDefault:"info"`
+	MigrateOnStart    bool          `env:"MIGRATE_ON_START" envDefault:"true"`
+	ProcessedDir      string        `env:"PROCESSED_DIR" envDefault:""`
+	FailedDir         string        `env:"FAILED_DIR" envDefault:""`
+	SkippedDir        string        `env:"SKIPPED_DIR" envDefault:""`
+	MaxImageWidth     int           `env:"MAX_IMAGE_WIDTH" envDefault:"1920"`
+	MaxImageHeight    int           `env:"MAX_IMAGE_HEIGHT" envDefault:"1080"`
+	LayoutProfile     string        `env:"LAYOUT_PROFILE" envDefault:"auto"`
+	LayoutsFile       string        `env:"LAYOUTS_FILE" envDefault:""`
+	PreprocessFile    string        `env:"PREPROCESS_FILE" envDefault:""`
+	OCRSettingsFile   string        `env:"OCR_SETTINGS_FILE" envDefault:""`
+	OCRPoolSize       int           `env:"OCR_POOL_SIZE" envDefault:"2"`
+	Extractor         string        `env:"EXTRACTOR" envDefault:"tesseract"`
+	MatchThreshold    float64       `env:"MATCH_THRESHOLD" envDefault:"0.85"`
+	SongsDir          string        `env:"SONGS_DIR" envDefault:""`
+	SongsScanInterval time.Duration `env:"SONGS_SCAN_INTERVAL" envDefault:"10m"`
+	ParserDebug       bool          `env:"PARSER_DEBUG" envDefault:"false"`
+	DebugDir          string        `env:"DEBUG_DIR" envDefault:"debug"`
+	Timezone          string        `env:"TIMEZONE" envDefault:"Local"`
+	// DuplicateWindow of 0 turns duplicate detection off
+	DuplicateWindow      time.Duration `env:"DUPLICATE_WINDOW" envDefault:"2m"`
+	DuplicateMaxDistance int           `env:"DUPLICATE_MAX_DISTANCE" envDefault:"10"`
+	SkipDuplicates       bool          `env:"SKIP_DUPLICATES" envDefault:"false"`
 }
 
 // normalizePath normalizes a file path, handling spaces and ensuring it's absolute.
@@ -75,5 +93,51 @@ func Load() Config {
 		}
 	}
 
+	if cfg.SkippedDir != "" {
+		originalSkippedDir := cfg.SkippedDir
+		cfg.SkippedDir = normalizePath(cfg.SkippedDir)
+		if cfg.SkippedDir != originalSkippedDir {
+			log.Printf("normalized SKIPPED_DIR: %q -> %q", originalSkippedDir, cfg.SkippedDir)
+		}
+	}
+
+	if cfg.LayoutsFile != "" {
+		originalLayoutsFile := cfg.LayoutsFile
+		cfg.LayoutsFile = normalizePath(cfg.LayoutsFile)
+		if cfg.LayoutsFile != originalLayoutsFile {
+			log.Printf("normalized LAYOUTS_FILE: %q -> %q", originalLayoutsFile, cfg.LayoutsFile)
+		}
+	}
+
+	if cfg.PreprocessFile != "" {
+		originalPreprocessFile := cfg.PreprocessFile
+		cfg.PreprocessFile = normalizePath(cfg.PreprocessFile)
+		if cfg.PreprocessFile != originalPreprocessFile {
+			log.Printf("normalized PREPROCESS_FILE: %q -> %q", originalPreprocessFile, cfg.PreprocessFile)
+		}
+	}
+
+	if cfg.OCRSettingsFile != "" {
+		originalOCRSettingsFile := cfg.OCRSettingsFile
+		cfg.OCRSettingsFile = normalizePath(cfg.OCRSettingsFile)
+		if cfg.OCRSettingsFile != originalOCRSettingsFile {
+			log.Printf("normalized OCR_SETTINGS_FILE: %q -> %q", originalOCRSettingsFile, cfg.OCRSettingsFile)
+		}
+	}
+
+	originalDebugDir := cfg.DebugDir
+	cfg.DebugDir = normalizePath(cfg.DebugDir)
+	if cfg.DebugDir != originalDebugDir {
+		log.Printf("normalized DEBUG_DIR: %q -> %q", originalDebugDir, cfg.DebugDir)
+	}
+
+	if cfg.SongsDir != "" {
+		originalSongsDir := cfg.SongsDir
+		cfg.SongsDir = normalizePath(cfg.SongsDir)
+		if cfg.SongsDir != originalSongsDir {
+			log.Printf("normalized SONGS_DIR: %q -> %q", originalSongsDir, cfg.SongsDir)
+		}
+	}
+
 	return cfg
 }
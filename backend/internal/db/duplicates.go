@@ -0,0 +1,142 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotFound is returned when the row to change doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+// ImageHashRef is the screenshot hash of a stored score.
+type ImageHashRef struct {
+	ScoreID   int64
+	ImageHash string
+	CreatedAt time.Time
+}
+
+// ImageHashesBetween returns the screenshot hashes of scores created between
+// from and to, inclusive. Scores already flagged as duplicates are left out so
+// new captures are compared with the originals.
+func (r *Repo) ImageHashesBetween(ctx context.Context, from, to time.Time) ([]ImageHashRef, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, image_hash, created_at
+		FROM scores
+		WHERE image_hash IS NOT NULL
+		  AND NOT duplicate
+		  AND created_at BETWEEN $1 AND $2
+		ORDER BY id
+	`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ImageHashRef
+	for rows.Next() {
+		var ref ImageHashRef
+		if err := rows.Scan(&ref.ScoreID, &ref.ImageHash, &ref.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, ref)
+	}
+	return out, rows.Err()
+}
+
+// Duplicate is a score flagged as a likely second capture of Original.
+// Original is nil if it has been deleted since; the score stays flagged.
+type Duplicate struct {
+	Score    Score  `json:"score"`
+	Original *Score `json:"original"`
+	// Distance is how many bits the screenshots' hashes differ in
+	Distance *int `json:"distance,omitempty"`
+}
+
+// ListDuplicates returns paginated scores flagged as duplicates, newest first,
+// each with the score it duplicates.
+func (r *Repo) ListDuplicates(ctx context.Context, limit, offset int32) ([]Duplicate, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+scoreColumns+`
+		FROM scores
+		WHERE duplicate
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1 OFFSET $2
+	`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	scores, err := scanScores(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(scores))
+	for _, s := range scores {
+		if s.DuplicateOf != nil {
+			ids = append(ids, *s.DuplicateOf)
+		}
+	}
+	rows, err = r.pool.Query(ctx, `SELECT `+scoreColumns+` FROM scores WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	originals, err := scanScores(rows)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.attachPlayers(ctx, scores); err != nil {
+		return nil, err
+	}
+	if err := r.attachPlayers(ctx, originals); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]*Score, len(originals))
+	for i := range originals {
+		byID[originals[i].ID] = &originals[i]
+	}
+
+	out := make([]Duplicate, 0, len(scores))
+	for _, s := range scores {
+		d := Duplicate{Score: s}
+		if s.DuplicateOf != nil {
+			d.Original = byID[*s.DuplicateOf]
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// ResolveDuplicate settles a score flagged as a duplicate: it's deleted when
+// discard is true, and otherwise kept as a score in its own right. It returns
+// ErrNotFound when no flagged score has the ID.
+func (r *Repo) ResolveDuplicate(ctx context.Context, id int64, discard bool) error {
+	sql := `UPDATE scores SET duplicate = false, duplicate_of = NULL WHERE id = $1 AND duplicate`
+	if discard {
+		sql = `DELETE FROM scores WHERE id = $1 AND duplicate`
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	keys, err := personalBestKeysWhere(ctx, tx, "p.score_id = $1", id)
+	if err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, sql, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	// A kept score starts counting towards its players' personal bests
+	if err := rebuildPersonalBestKeys(ctx, tx, keys); err != nil {
+		return fmt.Errorf("failed to update personal bests: %w", err)
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDuplicates(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	at := time.Now()
+	data := CreateScoreData{
+		Artist:    "Repo Test Artist " + at.Format(time.RFC3339Nano),
+		SongName:  "Duplicates",
+		Players:   testPlayers[:1],
+		ImageHash: "ffff0000ffff0000ffff0000ffff0000ffff0000ffff0000ffff0000ffff0000",
+		CreatedAt: at,
+	}
+	originalID, err := repo.CreateScore(ctx, data)
+	require.NoError(t, err)
+	data.DuplicateOf = &originalID
+	duplicateID, err := repo.CreateScore(ctx, data)
+	require.NoError(t, err)
+
+	refs, err := repo.ImageHashesBetween(ctx, at.Add(-time.Second), at.Add(time.Second))
+	require.NoError(t, err)
+	var ids []int64
+	for _, ref := range refs {
+		ids = append(ids, ref.ScoreID)
+	}
+	assert.Contains(t, ids, originalID)
+	assert.NotContains(t, ids, duplicateID, "new captures are compared with the original only")
+
+	// Deleting the original leaves the second capture flagged
+	_, err = repo.pool.Exec(ctx, `DELETE FROM scores WHERE id = $1`, originalID)
+	require.NoError(t, err)
+	duplicate := findDuplicate(t, repo, duplicateID)
+	require.NotNil(t, duplicate)
+	assert.True(t, duplicate.Score.Duplicate)
+	assert.Nil(t, duplicate.Score.DuplicateOf)
+	assert.Nil(t, duplicate.Original)
+
+	t.Run("keep", func(t *testing.T) {
+		require.NoError(t, repo.ResolveDuplicate(ctx, duplicateID, false))
+		assert.Nil(t, findDuplicate(t, repo, duplicateID))
+		score, err := repo.GetScore(ctx, duplicateID)
+		require.NoError(t, err)
+		assert.False(t, score.Duplicate)
+
+		assert.ErrorIs(t, repo.ResolveDuplicate(ctx, duplicateID, true), ErrNotFound, "no longer flagged")
+	})
+}
+
+// findDuplicate returns the listed duplicate with the score ID, or nil.
+func findDuplicate(t *testing.T, repo *Repo, id int64) *Duplicate {
+	t.Helper()
+
+	duplicates, err := repo.ListDuplicates(context.Background(), 1000, 0)
+	require.NoError(t, err)
+	for _, d := range duplicates {
+		if d.Score.ID == id {
+			return &d
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package db
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"cloneheroer/internal/levenshtein"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DefaultMatchThreshold is how similar (0-1) an OCR'd name must be to a
+// catalog name to be snapped to it. "Meta1lica" scores 0.89 against "Metallica".
+const DefaultMatchThreshold = 0.85
+
+// Option configures a Repo.
+type Option func(*Repo)
+
+// WithMatchThreshold sets how similar an OCR'd artist or song name must be to
+// an existing one before CreateScore uses the existing name instead. 1 only
+// corrects differences in case, punctuation and spacing; above 1 turns
+// correction off.
+func WithMatchThreshold(threshold float64) Option {
+	return func(r *Repo) {
+		r.matchThreshold = threshold
+	}
+}
+
+// querier is the part of pgx shared by the pool and transactions.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// correctArtist returns the catalog artist closest to name, or name when
+// nothing is close enough.
+func (r *Repo) correctArtist(ctx context.Context, q querier, name string) (string, error) {
+	if r.correctionOff() {
+		return name, nil
+	}
+	if err := r.setTrigramCutoff(ctx, q); err != nil {
+		return "", err
+	}
+	candidates, err := queryNames(ctx, q, `SELECT name FROM artists WHERE name % $1`, name)
+	if err != nil {
+		return "", err
+	}
+	return closestName(name, candidates, r.matchThreshold), nil
+}
+
+// correctSong returns the song by artist closest to name, or name when
+// nothing is close enough. Songs are only matched within the same artist.
+func (r *Repo) correctSong(ctx context.Context, q querier, artist, name string) (string, error) {
+	if r.correctionOff() {
+		return name, nil
+	}
+	if err := r.setTrigramCutoff(ctx, q); err != nil {
+		return "", err
+	}
+	candidates, err := queryNames(ctx, q, `
+		SELECT s.name FROM songs s
+		JOIN artists a ON a.id = s.artist_id
+		WHERE a.name = $1 AND s.name % $2
+	`, artist, name)
+	if err != nil {
+		return "", err
+	}
+	return closestName(name, candidates, r.matchThreshold), nil
+}
+
+// correctionOff reports whether the threshold is above 1, which no name can
+// reach, so there's no point fetching candidates.
+func (r *Repo) correctionOff() bool {
+	return r.matchThreshold > 1
+}
+
+// setTrigramCutoff sets how similar, by pg_trgm, names must be for the %
+// operator to fetch them as candidates, for the rest of q's transaction.
+func (r *Repo) setTrigramCutoff(ctx context.Context, q querier) error {
+	_, err := q.Exec(ctx, `SELECT set_config('pg_trgm.similarity_threshold', $1, true)`,
+		strconv.FormatFloat(trigramCutoff(r.matchThreshold), 'f', 3, 64))
+	return err
+}
+
+// trigramCutoff returns a pg_trgm similarity that every name within
+// threshold of a name, by similarity, reaches. Each edit changes at most
+// three trigrams, so e edits in n runes leave a trigram similarity of at
+// least (n-3e)/(n+3e); the result is halved for the word boundaries and
+// padding that estimate leaves out.
+func trigramCutoff(threshold float64) float64 {
+	edits := 3 * (1 - min(threshold, 1))
+	return max(0, (1-edits)/(1+edits)/2)
+}
+
+func queryNames(ctx context.Context, q querier, sql string, args ...any) ([]string, error) {
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// closestName returns the candidate most similar to name if its similarity
+// is at least threshold, and name otherwise. An exact match always wins.
+func closestName(name string, candidates []string, threshold float64) string {
+	if name == "" {
+		return name
+	}
+
+	best, bestScore := name, 0.0
+	for _, candidate := range candidates {
+		if candidate == name {
+			return name
+		}
+		if score := similarity(name, candidate); score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+	if bestScore < threshold {
+		return name
+	}
+	return best
+}
+
+// similarity compares two names ignoring case, punctuation and spacing. It
+// returns 1 minus the edit distance over the longer length, so 1 is
+// identical and 0 shares nothing.
+func similarity(a, b string) float64 {
+	a, b = normalizeName(a), normalizeName(b)
+	longest := max(utf8.RuneCountInString(a), utf8.RuneCountInString(b))
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(levenshtein.Distance(a, b))/float64(longest)
+}
+
+// normalizeName lowercases s, drops punctuation and collapses whitespace.
+func normalizeName(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		case unicode.IsSpace(r):
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
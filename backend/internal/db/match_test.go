@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClosestName(t *testing.T) {
+	artists := []string{"Metallica", "Megadeth", "Dave Matthews Band", "Hail The Sun", "Tool", "Toto"}
+
+	testCases := []struct {
+		name      string
+		input     string
+		threshold float64
+		expected  string
+	}{
+		{name: "exact match", input: "Metallica", threshold: DefaultMatchThreshold, expected: "Metallica"},
+		{name: "digit for letter", input: "Meta1lica", threshold: DefaultMatchThreshold, expected: "Metallica"},
+		{name: "trailing punctuation", input: "Metallica.", threshold: DefaultMatchThreshold, expected: "Metallica"},
+		{name: "case and spacing", input: "dave  matthews BAND", threshold: DefaultMatchThreshold, expected: "Dave Matthews Band"},
+		{name: "dropped letter", input: "Dave Mathews Band", threshold: DefaultMatchThreshold, expected: "Dave Matthews Band"},
+		{name: "new artist", input: "Mastodon", threshold: DefaultMatchThreshold, expected: "Mastodon"},
+		{name: "short names need to be close", input: "Tote", threshold: DefaultMatchThreshold, expected: "Tote"},
+		{name: "empty", input: "", threshold: DefaultMatchThreshold, expected: ""},
+		{name: "threshold 1 only fixes formatting", input: "Meta1lica", threshold: 1, expected: "Meta1lica"},
+		{name: "threshold 1 still fixes punctuation", input: "Metallica.", threshold: 1, expected: "Metallica"},
+		{name: "threshold above 1 disables", input: "Metallica.", threshold: 1.1, expected: "Metallica."},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, closestName(tc.input, artists, tc.threshold))
+		})
+	}
+}
+
+func TestClosestName_NoCandidates(t *testing.T) {
+	assert.Equal(t, "Metallica", closestName("Metallica", nil, DefaultMatchThreshold))
+}
+
+func TestSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, similarity("Metallica", "metallica!"))
+	assert.InDelta(t, 0.889, similarity("Meta1lica", "Metallica"), 0.001)
+	assert.Equal(t, 0.0, similarity("", "..."))
+	assert.Less(t, similarity("Tool", "Toto"), DefaultMatchThreshold)
+}
+
+func TestCorrection_Off(t *testing.T) {
+	// No querier: correction above 1 mustn't reach the database
+	repo := NewRepo(nil, WithMatchThreshold(1.1))
+
+	artist, err := repo.correctArtist(context.Background(), nil, "Meta1lica")
+	require.NoError(t, err)
+	assert.Equal(t, "Meta1lica", artist)
+	song, err := repo.correctSong(context.Background(), nil, "Metallica", "0ne")
+	require.NoError(t, err)
+	assert.Equal(t, "0ne", song)
+}
+
+func TestTrigramCutoff(t *testing.T) {
+	assert.InDelta(t, 0.5, trigramCutoff(1), 1e-9, "formatting differences share every trigram")
+	assert.InDelta(t, 0.5, trigramCutoff(1.1), 1e-9)
+	assert.InDelta(t, 0.19, trigramCutoff(DefaultMatchThreshold), 0.01)
+	assert.Zero(t, trigramCutoff(0.5), "loose thresholds fetch every name")
+}
@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// MergeArtists folds artist from into artist into, in one transaction: from's
+// songs move to into, merging with any song of the same name, their scores
+// take into's name and from is deleted. It returns ErrNotFound when either
+// artist doesn't exist.
+func (r *Repo) MergeArtists(ctx context.Context, into, from int64) error {
+	if into == from {
+		return errors.New("can't merge an artist into itself")
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	names := map[int64]string{}
+	rows, err := tx.Query(ctx, `SELECT id, name FROM artists WHERE id = ANY($1) FOR UPDATE`, []int64{into, from})
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			rows.Close()
+			return err
+		}
+		names[id] = name
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(names) != 2 {
+		return ErrNotFound
+	}
+
+	// Songs both artists have can't both move, so they're merged
+	rows, err = tx.Query(ctx, `
+		SELECT i.id, f.id
+		FROM songs f
+		JOIN songs i ON i.name = f.name AND i.artist_id = $1
+		WHERE f.artist_id = $2
+	`, into, from)
+	if err != nil {
+		return err
+	}
+	var pairs [][2]int64
+	for rows.Next() {
+		var pair [2]int64
+		if err := rows.Scan(&pair[0], &pair[1]); err != nil {
+			rows.Close()
+			return err
+		}
+		pairs = append(pairs, pair)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, pair := range pairs {
+		if err := mergeSongs(ctx, tx, pair[0], pair[1]); err != nil {
+			return fmt.Errorf("failed to merge song %d into %d: %w", pair[1], pair[0], err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE songs SET artist_id = $1 WHERE artist_id = $2`, into, from); err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE scores SET artist = $1
+		WHERE artist = $2 OR song_id IN (SELECT id FROM songs WHERE artist_id = $3)
+	`, names[into], names[from], into)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM artists WHERE id = $1`, from); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// MergeSongs folds song from into song into, in one transaction: from's
+// scores move to into and take its artist's name, the charters and catalog
+// details are combined, personal bests are recomputed and from is deleted.
+// It returns ErrNotFound when either song doesn't exist.
+func (r *Repo) MergeSongs(ctx context.Context, into, from int64) error {
+	if into == from {
+		return errors.New("can't merge a song into itself")
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var count int
+	if err := tx.QueryRow(ctx, `SELECT count(*) FROM (SELECT id FROM songs WHERE id = ANY($1) FOR UPDATE) s`, []int64{into, from}).Scan(&count); err != nil {
+		return err
+	}
+	if count != 2 {
+		return ErrNotFound
+	}
+
+	if err := mergeSongs(ctx, tx, into, from); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// mergeSongs moves song from's scores to song into and deletes from.
+// Charters keep into's order with from's new ones after them; catalog
+// details into lacks come from from.
+func mergeSongs(ctx context.Context, tx pgx.Tx, into, from int64) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE songs i SET
+			charters = ARRAY(
+				SELECT c FROM unnest(i.charters || f.charters) WITH ORDINALITY AS t(c, n)
+				GROUP BY c
+				ORDER BY min(n)
+			),
+			album = COALESCE(i.album, f.album),
+			year = COALESCE(i.year, f.year),
+			genre = COALESCE(i.genre, f.genre),
+			song_length_ms = COALESCE(i.song_length_ms, f.song_length_ms),
+			difficulties = COALESCE(i.difficulties, f.difficulties),
+			chart_hash = COALESCE(i.chart_hash, f.chart_hash)
+		FROM songs f
+		WHERE i.id = $1 AND f.id = $2
+	`, into, from)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE scores SET
+			song_id = $1,
+			artist = COALESCE((SELECT a.name FROM songs s JOIN artists a ON a.id = s.artist_id WHERE s.id = $1), artist)
+		WHERE song_id = $2
+	`, into, from)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM songs WHERE id = $1`, from); err != nil {
+		return err
+	}
+	if err := rebuildPersonalBests(ctx, tx, into); err != nil {
+		return fmt.Errorf("failed to rebuild personal bests: %w", err)
+	}
+	return nil
+}
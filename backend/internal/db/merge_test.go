@@ -0,0 +1,132 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloneheroer/internal/db/dbtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeArtists(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	suffix := " " + time.Now().Format(time.RFC3339Nano)
+	artist, ocrArtist := "Hail The Sun"+suffix, "Ha1l"+suffix
+	start := time.Now().Add(-time.Hour)
+
+	// The same song under both artists, and one only the OCR artist has
+	intoSong, err := repo.UpsertCatalogSong(ctx, CatalogSong{Name: "Discography", Artist: artist, Charter: "Acai"})
+	require.NoError(t, err)
+	fromSong, err := repo.UpsertCatalogSong(ctx, CatalogSong{Name: "Discography", Artist: ocrArtist, Charter: "Miscellany", Album: "Mental Knife", Year: 2018})
+	require.NoError(t, err)
+	movedSong, err := repo.UpsertCatalogSong(ctx, CatalogSong{Name: "Black Serene", Artist: ocrArtist})
+	require.NoError(t, err)
+
+	var scoreIDs []int64
+	for i, run := range []struct {
+		artist, song string
+		score        int64
+	}{
+		{artist, "Discography", 100000},
+		{ocrArtist, "Discography", 120000},
+		{ocrArtist, "Black Serene", 90000},
+	} {
+		id, err := repo.CreateScore(ctx, CreateScoreData{
+			Artist:    run.artist,
+			SongName:  run.song,
+			Players:   []Player{{Name: "Bren", Instrument: "guitar", Difficulty: "Expert", Score: run.score}},
+			CreatedAt: start.Add(time.Duration(i) * time.Minute),
+		})
+		require.NoError(t, err)
+		scoreIDs = append(scoreIDs, id)
+	}
+
+	var intoID, fromID int64
+	require.NoError(t, repo.pool.QueryRow(ctx, `SELECT artist_id FROM songs WHERE id = $1`, intoSong).Scan(&intoID))
+	require.NoError(t, repo.pool.QueryRow(ctx, `SELECT artist_id FROM songs WHERE id = $1`, fromSong).Scan(&fromID))
+
+	require.NoError(t, repo.MergeArtists(ctx, intoID, fromID))
+
+	wantSongs := []int64{intoSong, intoSong, movedSong}
+	for i, id := range scoreIDs {
+		score, err := repo.GetScore(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, &wantSongs[i], score.SongID, "score %d", i+1)
+		assert.Equal(t, artist, score.Artist, "score %d", i+1)
+	}
+
+	var charters []string
+	var album *string
+	var year *int
+	require.NoError(t, repo.pool.QueryRow(ctx, `SELECT charters, album, year FROM songs WHERE id = $1`, intoSong).Scan(&charters, &album, &year))
+	assert.Equal(t, []string{"Acai", "Miscellany"}, charters)
+	assert.Equal(t, dbtest.Ptr("Mental Knife"), album, "details the kept song lacked are filled in")
+	assert.Equal(t, dbtest.Ptr(2018), year)
+
+	var artistID int64
+	require.NoError(t, repo.pool.QueryRow(ctx, `SELECT artist_id FROM songs WHERE id = $1`, movedSong).Scan(&artistID))
+	assert.Equal(t, intoID, artistID)
+
+	t.Run("history is kept", func(t *testing.T) {
+		bests, err := repo.ListPersonalBests(ctx, 10, 0, PersonalBestFilter{SongID: &intoSong})
+		require.NoError(t, err)
+		require.Len(t, bests, 1)
+		assert.Equal(t, int64(120000), bests[0].Score)
+		assert.Equal(t, artist, *bests[0].Artist)
+
+		history, err := repo.PersonalBestHistory(ctx, bests[0].ID)
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		assert.Equal(t, scoreIDs[0], history[0].ScoreID)
+		assert.Equal(t, scoreIDs[1], history[1].ScoreID)
+		assert.Equal(t, dbtest.Ptr(int64(100000)), history[1].PreviousBest)
+	})
+
+	t.Run("merged artist is gone", func(t *testing.T) {
+		assert.ErrorIs(t, repo.MergeArtists(ctx, intoID, fromID), ErrNotFound)
+	})
+}
+
+func TestMergeSongs(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	artist := "Repo Test Artist " + time.Now().Format(time.RFC3339Nano)
+	intoSong, err := repo.UpsertCatalogSong(ctx, CatalogSong{Name: "Tripping Billies", Artist: artist, Charter: "Acai"})
+	require.NoError(t, err)
+	fromSong, err := repo.UpsertCatalogSong(ctx, CatalogSong{Name: "Tripp1ng Bi11ies", Artist: artist, Charter: "Acai"})
+	require.NoError(t, err)
+
+	scoreID, err := repo.CreateScore(ctx, CreateScoreData{
+		Artist:    artist,
+		SongName:  "Tripp1ng Bi11ies",
+		Players:   []Player{{Name: "Bren", Score: 1000}},
+		CreatedAt: time.Now(),
+	})
+	require.NoError(t, err)
+
+	// Renaming onto the real song is refused; merging is the way
+	assert.ErrorIs(t, repo.UpdateSong(ctx, fromSong, dbtest.Ptr("Tripping Billies"), nil, nil), ErrConflict)
+
+	require.NoError(t, repo.MergeSongs(ctx, intoSong, fromSong))
+
+	score, err := repo.GetScore(ctx, scoreID)
+	require.NoError(t, err)
+	assert.Equal(t, &intoSong, score.SongID)
+	var charters []string
+	require.NoError(t, repo.pool.QueryRow(ctx, `SELECT charters FROM songs WHERE id = $1`, intoSong).Scan(&charters))
+	assert.Equal(t, []string{"Acai"}, charters, "charters are not repeated")
+
+	bests, err := repo.ListPersonalBests(ctx, 10, 0, PersonalBestFilter{SongID: &intoSong})
+	require.NoError(t, err)
+	require.Len(t, bests, 1)
+	assert.Equal(t, &scoreID, bests[0].ScoreID)
+
+	assert.ErrorIs(t, repo.MergeSongs(ctx, intoSong, fromSong), ErrNotFound)
+	assert.Error(t, repo.MergeSongs(ctx, intoSong, intoSong))
+}
@@ -0,0 +1,343 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PersonalBest is a player's best run of a song on one instrument and
+// difficulty. Score is the best score; Accuracy, BestStreak and FullCombo are
+// the best of each over every run, which may come from other runs. Runs under
+// every alias of a profile share one personal best.
+type PersonalBest struct {
+	ID int64 `json:"id"`
+	// PlayerName is the player's name, or the profile's name for a profile's
+	// personal best
+	PlayerName string   `json:"player_name"`
+	ProfileID  *int64   `json:"profile_id,omitempty"`
+	SongID     int64    `json:"song_id"`
+	SongName   string   `json:"song_name"`
+	Artist     *string  `json:"artist,omitempty"`
+	Instrument string   `json:"instrument"`
+	Difficulty string   `json:"difficulty"`
+	Score      int64    `json:"score"`
+	Accuracy   *float64 `json:"accuracy,omitempty"`
+	BestStreak *int     `json:"best_streak,omitempty"`
+	FullCombo  bool     `json:"full_combo"`
+	// PlayerID and ScoreID are the run that set Score, nil if it has been
+	// deleted since
+	PlayerID *int64 `json:"player_id,omitempty"`
+	ScoreID  *int64 `json:"score_id,omitempty"`
+	// AchievedAt is when Score was played, nil when its source doesn't say
+	AchievedAt *time.Time `json:"achieved_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// PersonalBestFilter narrows ListPersonalBests results. Zero values match
+// everything.
+type PersonalBestFilter struct {
+	// PlayerName matches a player's name, or any alias of a profile
+	PlayerName string
+	SongID     *int64
+	Instrument string
+	Difficulty string
+}
+
+// personalBestColumns are the columns scanPersonalBest reads, in order, from
+// personal_bests pb joined with songs, artists and players.
+const personalBestColumns = `pb.id, pb.player_name, pb.profile_id, pb.song_id, songs.name, artists.name, pb.instrument, pb.difficulty, pb.score, pb.accuracy, pb.best_streak, pb.full_combo, pb.player_id, players.score_id, pb.achieved_at, pb.updated_at`
+
+const personalBestJoins = `
+	JOIN songs ON songs.id = pb.song_id
+	LEFT JOIN artists ON artists.id = songs.artist_id
+	LEFT JOIN players ON players.id = pb.player_id`
+
+func scanPersonalBest(row pgx.Row) (PersonalBest, error) {
+	var pb PersonalBest
+	err := row.Scan(&pb.ID, &pb.PlayerName, &pb.ProfileID, &pb.SongID, &pb.SongName, &pb.Artist, &pb.Instrument, &pb.Difficulty,
+		&pb.Score, &pb.Accuracy, &pb.BestStreak, &pb.FullCombo, &pb.PlayerID, &pb.ScoreID, &pb.AchievedAt, &pb.UpdatedAt)
+	return pb, err
+}
+
+// ListPersonalBests returns paginated personal bests matching filter, most
+// recently achieved first.
+func (r *Repo) ListPersonalBests(ctx context.Context, limit, offset int32, filter PersonalBestFilter) ([]PersonalBest, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+personalBestColumns+`
+		FROM personal_bests pb`+personalBestJoins+`
+		WHERE ($3 = '' OR pb.player_name = $3 OR pb.profile_id = (SELECT profile_id FROM profile_aliases WHERE alias = $3))
+		  AND ($4::integer IS NULL OR pb.song_id = $4)
+		  AND ($5 = '' OR pb.instrument = $5)
+		  AND ($6 = '' OR pb.difficulty = $6)
+		ORDER BY pb.achieved_at DESC NULLS LAST, pb.id DESC
+		LIMIT $1 OFFSET $2
+	`, limit, offset, filter.PlayerName, filter.SongID, filter.Instrument, filter.Difficulty)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []PersonalBest
+	for rows.Next() {
+		pb, err := scanPersonalBest(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, pb)
+	}
+	return out, rows.Err()
+}
+
+// PersonalBestRun is a run that set a new personal best.
+type PersonalBestRun struct {
+	PlayerID   int64    `json:"player_id"`
+	ScoreID    int64    `json:"score_id"`
+	Score      int64    `json:"score"`
+	Accuracy   *float64 `json:"accuracy,omitempty"`
+	BestStreak *int     `json:"best_streak,omitempty"`
+	FullCombo  bool     `json:"full_combo"`
+	// PreviousBest is the best score before this run, nil for the first
+	PreviousBest *int64     `json:"previous_best,omitempty"`
+	AchievedAt   *time.Time `json:"achieved_at"`
+}
+
+// PersonalBestHistory returns the runs that raised the personal best with
+// the given ID, oldest first. It returns ErrNotFound when no personal best has
+// the ID.
+func (r *Repo) PersonalBestHistory(ctx context.Context, id int64) ([]PersonalBestRun, error) {
+	var exists bool
+	if err := r.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM personal_bests WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT p.id, p.score_id, p.score, p.accuracy, p.best_streak, p.full_combo, p.previous_best, s.created_at
+		FROM players p
+		JOIN scores s ON s.id = p.score_id
+		WHERE p.personal_best_id = $1 AND p.new_pb
+		ORDER BY s.created_at NULLS FIRST, p.id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []PersonalBestRun{}
+	for rows.Next() {
+		var run PersonalBestRun
+		if err := rows.Scan(&run.PlayerID, &run.ScoreID, &run.Score, &run.Accuracy, &run.BestStreak, &run.FullCombo, &run.PreviousBest, &run.AchievedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, run)
+	}
+	return out, rows.Err()
+}
+
+// personalBestKey is what a personal best is kept per: one player's runs of
+// a song on one instrument and difficulty. The player is a profile when
+// profileID is set, with playerName its name, and otherwise a bare name.
+type personalBestKey struct {
+	songID     int64
+	profileID  int64
+	playerName string
+	instrument string
+	difficulty string
+}
+
+// profile returns the key's profile ID, nil for a bare name.
+func (k personalBestKey) profile() *int64 {
+	if k.profileID == 0 {
+		return nil
+	}
+	return &k.profileID
+}
+
+func comparePersonalBestKeys(a, b personalBestKey) int {
+	return cmp.Or(
+		cmp.Compare(a.songID, b.songID),
+		cmp.Compare(a.profileID, b.profileID),
+		cmp.Compare(a.playerName, b.playerName),
+		cmp.Compare(a.instrument, b.instrument),
+		cmp.Compare(a.difficulty, b.difficulty),
+	)
+}
+
+// personalBestKeysWhere returns the personal bests the players rows p
+// matching where count towards, with arg as $1. Runs without a name or song
+// can't be told apart from anyone else's, so they count towards none.
+func personalBestKeysWhere(ctx context.Context, tx pgx.Tx, where string, arg any) ([]personalBestKey, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT s.song_id, COALESCE(p.profile_id, 0), COALESCE(pr.name, p.name), COALESCE(p.instrument, ''), COALESCE(p.difficulty, '')
+		FROM players p
+		JOIN scores s ON s.id = p.score_id
+		JOIN songs ON songs.id = s.song_id
+		LEFT JOIN profiles pr ON pr.id = p.profile_id
+		WHERE `+where+` AND p.name <> '' AND songs.name <> ''
+	`, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []personalBestKey
+	for rows.Next() {
+		var key personalBestKey
+		if err := rows.Scan(&key.songID, &key.profileID, &key.playerName, &key.instrument, &key.difficulty); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// rebuildPersonalBests rebuilds every personal best on a song, like after
+// scores were moved to it.
+func rebuildPersonalBests(ctx context.Context, tx pgx.Tx, songID int64) error {
+	keys, err := personalBestKeysWhere(ctx, tx, "s.song_id = $1", songID)
+	if err != nil {
+		return err
+	}
+
+	// and the ones no run counts towards any more, so they're removed
+	rows, err := tx.Query(ctx, `SELECT COALESCE(profile_id, 0), player_name, instrument, difficulty FROM personal_bests WHERE song_id = $1`, songID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		key := personalBestKey{songID: songID}
+		if err := rows.Scan(&key.profileID, &key.playerName, &key.instrument, &key.difficulty); err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return rebuildPersonalBestKeys(ctx, tx, keys)
+}
+
+// rebuildPersonalBestKeys rebuilds each personal best in keys once, locking
+// them in one order so concurrent rebuilds can't deadlock.
+func rebuildPersonalBestKeys(ctx context.Context, tx pgx.Tx, keys []personalBestKey) error {
+	keys = slices.Clone(keys)
+	slices.SortFunc(keys, comparePersonalBestKeys)
+	for _, key := range slices.Compact(keys) {
+		if err := rebuildPersonalBest(ctx, tx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// personalBestRuns selects the players rows p, joined with their scores s,
+// whose runs make up the personal best with key $1-$5.
+const personalBestRuns = `
+	FROM players p
+	JOIN scores s ON s.id = p.score_id
+	WHERE s.song_id = $1
+	  AND p.profile_id IS NOT DISTINCT FROM $2::integer
+	  AND ($2::integer IS NOT NULL OR p.name = $3)
+	  AND COALESCE(p.instrument, '') = $4
+	  AND COALESCE(p.difficulty, '') = $5`
+
+// rebuildPersonalBest recomputes one personal best, and the new_pb flags of
+// the runs behind it, from every stored run replayed in capture order. Runs
+// with no capture time count as played before any dated one, and scores
+// flagged as duplicates don't count. The personal best is removed when no
+// run is left.
+func rebuildPersonalBest(ctx context.Context, tx pgx.Tx, key personalBestKey) error {
+	args := []any{key.songID, key.profile(), key.playerName, key.instrument, key.difficulty}
+
+	// Creating the row first gives a first run of the song a row to lock too
+	conflict := `(player_name, song_id, instrument, difficulty) WHERE profile_id IS NULL`
+	if key.profileID != 0 {
+		conflict = `(profile_id, song_id, instrument, difficulty) WHERE profile_id IS NOT NULL`
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO personal_bests (song_id, profile_id, player_name, instrument, difficulty, score)
+		VALUES ($1, $2, $3, $4, $5, 0)
+		ON CONFLICT `+conflict+` DO NOTHING
+	`, args...)
+	if err != nil {
+		return err
+	}
+	var id int64
+	err = tx.QueryRow(ctx, `
+		SELECT id FROM personal_bests
+		WHERE song_id = $1
+		  AND profile_id IS NOT DISTINCT FROM $2::integer
+		  AND ($2::integer IS NOT NULL OR player_name = $3)
+		  AND instrument = $4
+		  AND difficulty = $5
+		FOR UPDATE
+	`, args...).Scan(&id)
+	if err != nil {
+		return err
+	}
+	args = append(args, id)
+
+	// Runs corrected onto another personal best are picked up by its rebuild
+	_, err = tx.Exec(ctx, `UPDATE players SET personal_best_id = NULL, new_pb = false, previous_best = NULL WHERE personal_best_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		WITH runs AS (
+			SELECT p.id,
+			       p.score,
+			       NOT s.duplicate AS counted,
+			       MAX(p.score) FILTER (WHERE NOT s.duplicate) OVER (
+			           ORDER BY s.created_at NULLS FIRST, p.id
+			           ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING
+			       ) AS previous_best
+			`+personalBestRuns+`
+		)
+		UPDATE players
+		SET personal_best_id = CASE WHEN runs.counted THEN $6::integer END,
+		    new_pb = runs.counted AND runs.score IS NOT NULL AND (runs.previous_best IS NULL OR runs.score > runs.previous_best),
+		    previous_best = CASE WHEN runs.counted THEN runs.previous_best END
+		FROM runs
+		WHERE players.id = runs.id
+	`, args...)
+	if err != nil {
+		return err
+	}
+
+	// The first run to reach the best score set it
+	tag, err := tx.Exec(ctx, `
+		UPDATE personal_bests pb SET
+			player_name = $3,
+			score = best.score,
+			player_id = best.id,
+			achieved_at = best.created_at,
+			accuracy = totals.accuracy,
+			best_streak = totals.best_streak,
+			full_combo = totals.full_combo,
+			updated_at = now()
+		FROM (
+			SELECT p.id, p.score, s.created_at
+			`+personalBestRuns+` AND NOT s.duplicate AND p.score IS NOT NULL
+			ORDER BY p.score DESC, s.created_at NULLS FIRST, p.id
+			LIMIT 1
+		) best, (
+			SELECT MAX(p.accuracy) AS accuracy, MAX(p.best_streak) AS best_streak, COALESCE(bool_or(p.full_combo), false) AS full_combo
+			`+personalBestRuns+` AND NOT s.duplicate
+		) totals
+		WHERE pb.id = $6
+	`, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		_, err = tx.Exec(ctx, `DELETE FROM personal_bests WHERE id = $1`, id)
+	}
+	return err
+}
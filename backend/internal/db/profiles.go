@@ -0,0 +1,226 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrConflict is returned when a change would break a uniqueness rule, like
+// two profiles with one name.
+var ErrConflict = errors.New("conflict")
+
+// isUniqueViolation reports whether err is Postgres rejecting a duplicate key.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// Profile is a person behind one or more player names. Stored player rows
+// whose name exactly matches one of the aliases are linked to it.
+type Profile struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Aliases   []string  `json:"aliases"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// profileQuery selects profiles with their aliases; callers add WHERE,
+// GROUP BY p.id and the rest.
+const profileQuery = `
+	SELECT p.id, p.name, COALESCE(array_agg(a.alias ORDER BY a.alias) FILTER (WHERE a.alias IS NOT NULL), '{}'), p.created_at
+	FROM profiles p
+	LEFT JOIN profile_aliases a ON a.profile_id = p.id`
+
+// ListProfiles returns paginated profiles ordered by name.
+func (r *Repo) ListProfiles(ctx context.Context, limit, offset int32) ([]Profile, error) {
+	rows, err := r.pool.Query(ctx, profileQuery+`
+		GROUP BY p.id
+		ORDER BY p.name
+		LIMIT $1 OFFSET $2
+	`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Profile
+	for rows.Next() {
+		var p Profile
+		if err := rows.Scan(&p.ID, &p.Name, &p.Aliases, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// GetProfile returns one profile. It returns ErrNotFound when no profile has
+// the ID.
+func (r *Repo) GetProfile(ctx context.Context, id int64) (*Profile, error) {
+	var p Profile
+	err := r.pool.QueryRow(ctx, profileQuery+`
+		WHERE p.id = $1
+		GROUP BY p.id
+	`, id).Scan(&p.ID, &p.Name, &p.Aliases, &p.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// CreateProfile creates a profile with aliases, its name included. It
+// returns ErrConflict when a profile already has the name or one of the
+// aliases; AssignAlias moves an alias between profiles.
+func (r *Repo) CreateProfile(ctx context.Context, name string, aliases []string) (*Profile, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id int64
+	err = tx.QueryRow(ctx, `INSERT INTO profiles (name) VALUES ($1) RETURNING id`, name).Scan(&id)
+	if isUniqueViolation(err) {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	aliases = append([]string{name}, aliases...)
+	slices.Sort(aliases)
+	for _, alias := range slices.Compact(aliases) {
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO profile_aliases (alias, profile_id) VALUES ($1, $2)
+			ON CONFLICT (alias) DO NOTHING
+		`, alias, id)
+		if err != nil {
+			return nil, err
+		}
+		if tag.RowsAffected() == 0 {
+			return nil, ErrConflict
+		}
+		if err := linkPlayers(ctx, tx, id, alias); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return r.GetProfile(ctx, id)
+}
+
+// AssignAlias makes alias one of the profile's names, taking it over from any
+// profile that had it, and links the stored players with that name. It
+// returns ErrNotFound when no profile has the ID.
+func (r *Repo) AssignAlias(ctx context.Context, profileID int64, alias string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM profiles WHERE id = $1)`, profileID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	if err := assignAlias(ctx, tx, profileID, alias); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func assignAlias(ctx context.Context, tx pgx.Tx, profileID int64, alias string) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO profile_aliases (alias, profile_id) VALUES ($1, $2)
+		ON CONFLICT (alias) DO UPDATE SET profile_id = EXCLUDED.profile_id
+	`, alias, profileID)
+	if err != nil {
+		return err
+	}
+	return linkPlayers(ctx, tx, profileID, alias)
+}
+
+// linkPlayers links the stored players named alias to the profile and moves
+// their runs to the profile's personal bests.
+func linkPlayers(ctx context.Context, tx pgx.Tx, profileID int64, alias string) error {
+	before, err := personalBestKeysWhere(ctx, tx, "p.name = $1", alias)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE players SET profile_id = $1 WHERE name = $2`, profileID, alias); err != nil {
+		return err
+	}
+	after, err := personalBestKeysWhere(ctx, tx, "p.name = $1", alias)
+	if err != nil {
+		return err
+	}
+	if err := rebuildPersonalBestKeys(ctx, tx, append(before, after...)); err != nil {
+		return fmt.Errorf("failed to update personal bests: %w", err)
+	}
+	return nil
+}
+
+// MergeProfiles moves the aliases and players of profile from to profile
+// into and deletes from. from's name is kept as an alias. It returns
+// ErrNotFound when either profile doesn't exist.
+func (r *Repo) MergeProfiles(ctx context.Context, into, from int64) error {
+	if into == from {
+		return errors.New("can't merge a profile into itself")
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var count int
+	if err := tx.QueryRow(ctx, `SELECT count(*) FROM profiles WHERE id = ANY($1)`, []int64{into, from}).Scan(&count); err != nil {
+		return err
+	}
+	if count != 2 {
+		return ErrNotFound
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO profile_aliases (alias, profile_id)
+		SELECT name, $1 FROM profiles WHERE id = $2
+		ON CONFLICT (alias) DO NOTHING
+	`, into, from); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE profile_aliases SET profile_id = $1 WHERE profile_id = $2`, into, from); err != nil {
+		return err
+	}
+	before, err := personalBestKeysWhere(ctx, tx, "p.profile_id = $1", from)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE players SET profile_id = $1 WHERE profile_id = $2`, into, from); err != nil {
+		return err
+	}
+	after, err := personalBestKeysWhere(ctx, tx, "p.profile_id = $1", into)
+	if err != nil {
+		return err
+	}
+	// Before from is deleted, so its emptied personal bests can be locked
+	if err := rebuildPersonalBestKeys(ctx, tx, append(before, after...)); err != nil {
+		return fmt.Errorf("failed to update personal bests: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM profiles WHERE id = $1`, from); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloneheroer/internal/db/dbtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfiles(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	suffix := " " + time.Now().Format(time.RFC3339Nano)
+	bren, brenOCR, brenOld, jules := "Bren"+suffix, "8ren"+suffix, "BrenOld"+suffix, "Jules"+suffix
+
+	profile, err := repo.CreateProfile(ctx, bren, []string{brenOCR})
+	require.NoError(t, err)
+	assert.Equal(t, bren, profile.Name)
+	assert.ElementsMatch(t, []string{bren, brenOCR}, profile.Aliases, "the name is an alias too")
+
+	_, err = repo.CreateProfile(ctx, bren, nil)
+	assert.ErrorIs(t, err, ErrConflict)
+	_, err = repo.CreateProfile(ctx, "Someone"+suffix, []string{brenOCR})
+	assert.ErrorIs(t, err, ErrConflict, "another profile's alias isn't taken over")
+
+	// New rows link to the profile when their name is an alias
+	scoreID, err := repo.CreateScore(ctx, CreateScoreData{
+		Artist:    "Repo Test Artist" + suffix,
+		SongName:  "Profiles",
+		Players:   []Player{{Name: brenOCR, Score: 1000}, {Name: brenOld, Score: 900}, {Name: jules, Score: 800}},
+		CreatedAt: time.Now(),
+	})
+	require.NoError(t, err)
+	players, err := repo.ListPlayers(ctx, scoreID)
+	require.NoError(t, err)
+	require.Len(t, players, 3)
+	assert.Equal(t, &profile.ID, players[0].ProfileID)
+	assert.Nil(t, players[1].ProfileID)
+	assert.Nil(t, players[2].ProfileID)
+
+	t.Run("assigning an alias links stored rows", func(t *testing.T) {
+		require.NoError(t, repo.AssignAlias(ctx, profile.ID, brenOld))
+		players, err := repo.ListPlayers(ctx, scoreID)
+		require.NoError(t, err)
+		assert.Equal(t, &profile.ID, players[1].ProfileID)
+
+		assert.ErrorIs(t, repo.AssignAlias(ctx, -1, "Nobody"), ErrNotFound)
+	})
+
+	t.Run("merging moves aliases and players", func(t *testing.T) {
+		other, err := repo.CreateProfile(ctx, jules, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, repo.MergeProfiles(ctx, profile.ID, other.ID))
+
+		merged, err := repo.GetProfile(ctx, profile.ID)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{bren, brenOCR, brenOld, jules}, merged.Aliases)
+		_, err = repo.GetProfile(ctx, other.ID)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		players, err := repo.ListPlayers(ctx, scoreID)
+		require.NoError(t, err)
+		for _, p := range players {
+			assert.Equal(t, &profile.ID, p.ProfileID, p.Name)
+		}
+
+		// Every alias's runs now count towards the profile's one personal best
+		score, err := repo.GetScore(ctx, scoreID)
+		require.NoError(t, err)
+		bests, err := repo.ListPersonalBests(ctx, 10, 0, PersonalBestFilter{SongID: score.SongID})
+		require.NoError(t, err)
+		require.Len(t, bests, 1)
+		assert.Equal(t, &profile.ID, bests[0].ProfileID)
+		assert.Equal(t, bren, bests[0].PlayerName)
+		assert.Equal(t, int64(1000), bests[0].Score)
+		bests, err = repo.ListPersonalBests(ctx, 10, 0, PersonalBestFilter{SongID: score.SongID, PlayerName: jules})
+		require.NoError(t, err)
+		assert.Len(t, bests, 1, "any alias finds the profile's personal bests")
+
+		assert.ErrorIs(t, repo.MergeProfiles(ctx, profile.ID, -1), ErrNotFound)
+		assert.Error(t, repo.MergeProfiles(ctx, profile.ID, profile.ID))
+	})
+
+	t.Run("renaming a player relinks it", func(t *testing.T) {
+		players, err := repo.ListPlayers(ctx, scoreID)
+		require.NoError(t, err)
+
+		require.NoError(t, repo.UpdatePlayer(ctx, players[0].ID, PlayerUpdate{Name: dbtest.Ptr("Stranger" + suffix)}))
+		players, err = repo.ListPlayers(ctx, scoreID)
+		require.NoError(t, err)
+		assert.Nil(t, players[0].ProfileID)
+
+		require.NoError(t, repo.UpdatePlayer(ctx, players[0].ID, PlayerUpdate{Name: dbtest.Ptr(bren)}))
+		players, err = repo.ListPlayers(ctx, scoreID)
+		require.NoError(t, err)
+		assert.Equal(t, &profile.ID, players[0].ProfileID)
+	})
+}
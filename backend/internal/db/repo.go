@@ -3,50 +3,195 @@ package db
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // Repo provides database operations using pgxpool.
 type Repo struct {
-	pool *pgxpool.Pool
+	pool           *pgxpool.Pool
+	matchThreshold float64
+	validate       func(CreateScoreData) []Warning
 }
 
 // NewRepo creates a Repo wrapping the provided pgx pool.
-func NewRepo(pool *pgxpool.Pool) *Repo {
-	return &Repo{pool: pool}
+func NewRepo(pool *pgxpool.Pool, opts ...Option) *Repo {
+	r := &Repo{pool: pool, matchThreshold: DefaultMatchThreshold}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// WithValidator sets the consistency check UpdateScore and UpdatePlayer
+// re-run after a correction, replacing the score's stored warnings.
+// validate.Score is the implementation; without one, corrections leave the
+// warnings as they were.
+func WithValidator(validate func(CreateScoreData) []Warning) Option {
+	return func(r *Repo) {
+		r.validate = validate
+	}
 }
 
 // Score represents a stored score row.
 type Score struct {
-	ID            int64          `json:"id"`
-	SongID        *int64         `json:"song_id,omitempty"`
-	Artist        string         `json:"artist"`
-	Charter       *string        `json:"charter,omitempty"`
-	TotalScore    *int64         `json:"total_score,omitempty"`
-	StarsAchieved *int           `json:"stars_achieved,omitempty"`
-	Players       map[string]any `json:"players,omitempty"`
-	CreatedAt     time.Time      `json:"created_at"`
+	ID     int64  `json:"id"`
+	SongID *int64 `json:"song_id,omitempty"`
+	// SongName is the name of the song SongID points at
+	SongName *string `json:"song_name,omitempty"`
+	Artist   string  `json:"artist"`
+	Charter  *string `json:"charter,omitempty"`
+	// OCRArtist and OCRSongName are the names as read from the screenshot,
+	// before they were matched against the catalog
+	OCRArtist     *string `json:"ocr_artist,omitempty"`
+	OCRSongName   *string `json:"ocr_song_name,omitempty"`
+	Source        string  `json:"source"`
+	TotalScore    *int64  `json:"total_score,omitempty"`
+	StarsAchieved *int    `json:"stars_achieved,omitempty"`
+	GoldStars     bool    `json:"gold_stars"`
+	// Players are the score's player rows, nil when they weren't asked for
+	Players       []Player       `json:"players"`
+	Confidence    *float64       `json:"confidence,omitempty"`
+	OCRConfidence *OCRConfidence `json:"ocr_confidence,omitempty"`
+	Warnings      []Warning      `json:"warnings,omitempty"`
+	// ImageHash is the perceptual hash of the screenshot the score was read from
+	ImageHash *string `json:"image_hash,omitempty"`
+	// Duplicate flags the score as a likely second capture of a stored one
+	Duplicate bool `json:"duplicate"`
+	// DuplicateOf is the score this one looks like a second capture of, nil
+	// once that score is deleted
+	DuplicateOf *int64 `json:"duplicate_of,omitempty"`
+	// CreatedAt is when the run was played, nil when the source doesn't say
+	CreatedAt *time.Time `json:"created_at"`
+	// CreatedAtSource is where CreatedAt came from, one of the TimeSource
+	// constants; nil for scores stored before it was recorded
+	CreatedAtSource *string `json:"created_at_source,omitempty"`
+}
+
+// ScoreFilter narrows and orders ListScores results. Zero values mean no
+// filtering and newest first.
+type ScoreFilter struct {
+	MinConfidence *float64
+	MaxConfidence *float64
+	SortBy        string // "created_at" or "confidence"
+	Order         string // "asc" or "desc"
+	// HasWarnings keeps only scores with (true) or without (false) warnings
+	HasWarnings *bool
+	// OmitPlayers leaves out the player rows, saving a query
+	OmitPlayers bool
 }
 
-// ListScores returns paginated scores.
-func (r *Repo) ListScores(ctx context.Context, limit, offset int32) ([]Score, error) {
+// orderBy returns the ORDER BY clause for the filter.
+func (f ScoreFilter) orderBy() (string, error) {
+	column := "created_at"
+	switch f.SortBy {
+	case "", "created_at":
+	case "confidence":
+		column = "confidence"
+	default:
+		return "", fmt.Errorf("invalid sort field %q", f.SortBy)
+	}
+
+	direction := "DESC"
+	switch strings.ToLower(f.Order) {
+	case "", "desc":
+	case "asc":
+		direction = "ASC"
+	default:
+		return "", fmt.Errorf("invalid sort order %q", f.Order)
+	}
+
+	// Scores without confidence (parsed before it was recorded) sort last either way
+	return fmt.Sprintf("%s %s NULLS LAST, id DESC", column, direction), nil
+}
+
+// ListScores returns paginated scores matching filter.
+func (r *Repo) ListScores(ctx context.Context, limit, offset int32, filter ScoreFilter) ([]Score, error) {
+	orderBy, err := filter.orderBy()
+	if err != nil {
+		return nil, err
+	}
+
 	rows, err := r.pool.Query(ctx, `
-        SELECT id, song_id, artist, charter, total_score, stars_achieved, players, created_at
+        SELECT `+scoreColumns+`
         FROM scores
-        ORDER BY created_at DESC
+        WHERE ($3::numeric IS NULL OR confidence >= $3)
+          AND ($4::numeric IS NULL OR confidence <= $4)
+          AND ($5::boolean IS NULL OR (warnings IS NOT NULL) = $5)
+        ORDER BY `+orderBy+`
         LIMIT $1 OFFSET $2
-    `, limit, offset)
+    `, limit, offset, filter.MinConfidence, filter.MaxConfidence, filter.HasWarnings)
 	if err != nil {
 		return nil, err
 	}
+	scores, err := scanScores(rows)
+	if err != nil || filter.OmitPlayers {
+		return scores, err
+	}
+	if err := r.attachPlayers(ctx, scores); err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
+
+// GetScore returns one score with its players. It returns ErrNotFound when
+// no score has the ID.
+func (r *Repo) GetScore(ctx context.Context, id int64) (*Score, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+scoreColumns+` FROM scores WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	scores, err := scanScores(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(scores) == 0 {
+		return nil, ErrNotFound
+	}
+	if err := r.attachPlayers(ctx, scores); err != nil {
+		return nil, err
+	}
+	return &scores[0], nil
+}
+
+// attachPlayers loads the player rows of every score in one query.
+func (r *Repo) attachPlayers(ctx context.Context, scores []Score) error {
+	if len(scores) == 0 {
+		return nil
+	}
+	ids := make([]int64, 0, len(scores))
+	for _, s := range scores {
+		ids = append(ids, s.ID)
+	}
+	players, err := r.playersByScore(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("failed to load players: %w", err)
+	}
+	for i := range scores {
+		scores[i].Players = players[scores[i].ID]
+		if scores[i].Players == nil {
+			scores[i].Players = []Player{}
+		}
+	}
+	return nil
+}
+
+// scoreColumns are the columns scanScores reads, in order, from scores.
+// The legacy players JSONB column is never set; player rows come from the
+// players table.
+const scoreColumns = `id, song_id, (SELECT name FROM songs WHERE songs.id = scores.song_id), artist, charter, ocr_artist, ocr_song_name, source, total_score, stars_achieved, gold_stars, confidence, ocr_confidence, warnings, image_hash, duplicate, duplicate_of, created_at, created_at_source`
+
+// scanScores reads every row of a query selecting scoreColumns and closes rows.
+func scanScores(rows pgx.Rows) ([]Score, error) {
 	defer rows.Close()
 
 	var out []Score
 	for rows.Next() {
 		var s Score
-		var playersData map[string]any
 		var songID *int64
 		var charter *string
 		var totalScore *int64
@@ -54,12 +199,23 @@ func (r *Repo) ListScores(ctx context.Context, limit, offset int32) ([]Score, er
 		if err := rows.Scan(
 			&s.ID,
 			&songID,
+			&s.SongName,
 			&s.Artist,
 			&charter,
+			&s.OCRArtist,
+			&s.OCRSongName,
+			&s.Source,
 			&totalScore,
 			&stars,
-			&playersData,
+			&s.GoldStars,
+			&s.Confidence,
+			&s.OCRConfidence,
+			&s.Warnings,
+			&s.ImageHash,
+			&s.Duplicate,
+			&s.DuplicateOf,
 			&s.CreatedAt,
+			&s.CreatedAtSource,
 		); err != nil {
 			return nil, err
 		}
@@ -67,10 +223,9 @@ func (r *Repo) ListScores(ctx context.Context, limit, offset int32) ([]Score, er
 		s.Charter = charter
 		s.TotalScore = totalScore
 		s.StarsAchieved = stars
-		s.Players = playersData
 		out = append(out, s)
 	}
-	return out, nil
+	return out, rows.Err()
 }
 
 // Artist represents an artist row.
@@ -104,19 +259,26 @@ func (r *Repo) ListArtists(ctx context.Context, limit, offset int32) ([]Artist,
 	return out, nil
 }
 
-// Song represents a song row.
+// Song represents a song row. Album through Difficulties are only known for
+// songs imported from a song.ini.
 type Song struct {
-	ID        int64     `json:"id"`
-	Name      string    `json:"name"`
-	ArtistID  *int64    `json:"artist_id,omitempty"`
-	Charters  []string  `json:"charters"`
-	CreatedAt time.Time `json:"created_at"`
+	ID           int64          `json:"id"`
+	Name         string         `json:"name"`
+	ArtistID     *int64         `json:"artist_id,omitempty"`
+	Charters     []string       `json:"charters"`
+	Album        *string        `json:"album,omitempty"`
+	Year         *int           `json:"year,omitempty"`
+	Genre        *string        `json:"genre,omitempty"`
+	SongLengthMs *int           `json:"song_length_ms,omitempty"`
+	Difficulties map[string]int `json:"difficulties,omitempty"`
+	ChartHash    *string        `json:"chart_hash,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
 }
 
 // ListSongs returns paginated songs.
 func (r *Repo) ListSongs(ctx context.Context, limit, offset int32) ([]Song, error) {
 	rows, err := r.pool.Query(ctx, `
-        SELECT id, name, artist_id, charters, created_at
+        SELECT id, name, artist_id, charters, album, year, genre, song_length_ms, difficulties, chart_hash, created_at
         FROM songs
         ORDER BY name ASC
         LIMIT $1 OFFSET $2
@@ -130,7 +292,7 @@ func (r *Repo) ListSongs(ctx context.Context, limit, offset int32) ([]Song, erro
 	for rows.Next() {
 		var s Song
 		var artistID *int64
-		if err := rows.Scan(&s.ID, &s.Name, &artistID, &s.Charters, &s.CreatedAt); err != nil {
+		if err := rows.Scan(&s.ID, &s.Name, &artistID, &s.Charters, &s.Album, &s.Year, &s.Genre, &s.SongLengthMs, &s.Difficulties, &s.ChartHash, &s.CreatedAt); err != nil {
 			return nil, err
 		}
 		s.ArtistID = artistID
@@ -139,114 +301,442 @@ func (r *Repo) ListSongs(ctx context.Context, limit, offset int32) ([]Song, erro
 	return out, nil
 }
 
-// UpdateArtist partially updates an artist.
+// CatalogSong is a song's metadata from the game's song library.
+// Difficulties maps a part ("guitar", "drums", ...) to its 0-6 rating and
+// leaves out parts the chart doesn't have.
+type CatalogSong struct {
+	Name         string         `json:"name"`
+	Artist       string         `json:"artist"`
+	Charter      string         `json:"charter,omitempty"`
+	Album        string         `json:"album,omitempty"`
+	Year         int            `json:"year,omitempty"`
+	Genre        string         `json:"genre,omitempty"`
+	SongLengthMs int            `json:"song_length_ms,omitempty"`
+	Difficulties map[string]int `json:"difficulties,omitempty"`
+	ChartHash    string         `json:"chart_hash,omitempty"`
+}
+
+// UpsertCatalogSong creates or updates a song and its artist from library
+// metadata. Existing metadata is overwritten; the charter is added to the
+// song's charters. It returns the song's ID.
+func (r *Repo) UpsertCatalogSong(ctx context.Context, song CatalogSong) (int64, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var artistID int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO artists (name) VALUES ($1)
+		ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+		RETURNING id
+	`, song.Artist).Scan(&artistID)
+	if err != nil {
+		return 0, err
+	}
+
+	charters := []string{}
+	if song.Charter != "" {
+		charters = []string{song.Charter}
+	}
+
+	var songID int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO songs (name, artist_id, charters, album, year, genre, song_length_ms, difficulties, chart_hash)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, 0), NULLIF($6, ''), NULLIF($7, 0), $8, NULLIF($10, ''))
+		ON CONFLICT (name, artist_id) DO UPDATE SET
+			album = EXCLUDED.album,
+			year = EXCLUDED.year,
+			genre = EXCLUDED.genre,
+			song_length_ms = EXCLUDED.song_length_ms,
+			difficulties = EXCLUDED.difficulties,
+			chart_hash = EXCLUDED.chart_hash,
+			charters = CASE
+				WHEN $9 = '' OR $9 = ANY(songs.charters) THEN songs.charters
+				ELSE array_append(songs.charters, $9)
+			END
+		RETURNING id
+	`, song.Name, artistID, charters, song.Album, song.Year, song.Genre, song.SongLengthMs, song.Difficulties, song.Charter, song.ChartHash).Scan(&songID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return songID, nil
+}
+
+// SongRef identifies a catalog song by ID and names.
+type SongRef struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Artist string `json:"artist"`
+}
+
+// SongByChartHash returns the song whose chart has the given MD5, or nil if
+// no imported song has it.
+func (r *Repo) SongByChartHash(ctx context.Context, hash string) (*SongRef, error) {
+	var song SongRef
+	err := r.pool.QueryRow(ctx, `
+		SELECT s.id, s.name, a.name
+		FROM songs s
+		JOIN artists a ON a.id = s.artist_id
+		WHERE s.chart_hash = $1
+		ORDER BY s.id
+		LIMIT 1
+	`, hash).Scan(&song.ID, &song.Name, &song.Artist)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &song, nil
+}
+
+// HasPlayerScore reports whether a stored score on the song has p's run:
+// the same player, instrument, difficulty and score, which identifies it
+// whatever it was read from. Names that are aliases of one profile are the
+// same player.
+func (r *Repo) HasPlayerScore(ctx context.Context, songID int64, p Player) (bool, error) {
+	var exists bool
+	err := r.pool.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM scores s
+			JOIN players p ON p.score_id = s.id
+			WHERE s.song_id = $1
+			  AND (p.name = $2 OR p.profile_id = (SELECT profile_id FROM profile_aliases WHERE alias = $2))
+			  AND COALESCE(p.instrument, '') = $3
+			  AND COALESCE(p.difficulty, '') = $4
+			  AND p.score = $5
+		)
+	`, songID, p.Name, p.Instrument, p.Difficulty, p.Score).Scan(&exists)
+	return exists, err
+}
+
+// UpdateArtist partially updates an artist, renaming its songs' scores with
+// it. It returns ErrNotFound when no artist has the ID, and ErrConflict when
+// another artist has the name; MergeArtists folds the two together instead.
 func (r *Repo) UpdateArtist(ctx context.Context, id int64, name *string) error {
 	if name == nil {
 		return errors.New("no fields to update")
 	}
-	_, err := r.pool.Exec(ctx, `UPDATE artists SET name = $1 WHERE id = $2`, name, id)
-	return err
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE artists SET name = $1 WHERE id = $2`, name, id)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE scores SET artist = $1
+		WHERE song_id IN (SELECT id FROM songs WHERE artist_id = $2)
+	`, name, id)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 // UpdateSong partially updates a song. Charters replaces the slice if provided.
+// Moving the song to another artist renames its scores' artist too. It returns
+// ErrNotFound when no song has the ID, and ErrConflict when the artist
+// already has a song with the name; MergeSongs folds the two together instead.
 func (r *Repo) UpdateSong(ctx context.Context, id int64, name *string, artistID *int64, charters []string) error {
 	if name == nil && artistID == nil && charters == nil {
 		return errors.New("no fields to update")
 	}
 
-	if charters != nil {
-		_, err := r.pool.Exec(ctx, `UPDATE songs SET charters = $1 WHERE id = $2`, charters, id)
-		if err != nil {
-			return err
-		}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
 	}
-	if name != nil {
-		if _, err := r.pool.Exec(ctx, `UPDATE songs SET name = $1 WHERE id = $2`, name, id); err != nil {
-			return err
-		}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE songs SET
+			name = COALESCE($2, name),
+			artist_id = COALESCE($3, artist_id),
+			charters = COALESCE($4, charters)
+		WHERE id = $1
+	`, id, name, artistID, charters)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
 	}
 	if artistID != nil {
-		if _, err := r.pool.Exec(ctx, `UPDATE songs SET artist_id = $1 WHERE id = $2`, artistID, id); err != nil {
+		_, err := tx.Exec(ctx, `
+			UPDATE scores SET artist = (SELECT name FROM artists WHERE id = $2)
+			WHERE song_id = $1
+		`, id, *artistID)
+		if err != nil {
 			return err
 		}
 	}
-	return nil
+	return tx.Commit(ctx)
 }
 
-// UpdateScore updates score fields.
+// UpdateScore updates score fields. It returns ErrNotFound when no score has
+// the ID.
 func (r *Repo) UpdateScore(ctx context.Context, id int64, totalScore *int64, stars *int, charter *string) error {
 	if totalScore == nil && stars == nil && charter == nil {
 		return errors.New("no fields to update")
 	}
-	if totalScore != nil {
-		if _, err := r.pool.Exec(ctx, `UPDATE scores SET total_score = $1 WHERE id = $2`, totalScore, id); err != nil {
-			return err
-		}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
 	}
-	if stars != nil {
-		if _, err := r.pool.Exec(ctx, `UPDATE scores SET stars_achieved = $1 WHERE id = $2`, stars, id); err != nil {
-			return err
-		}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE scores SET
+			total_score = COALESCE($2, total_score),
+			stars_achieved = COALESCE($3, stars_achieved),
+			charter = COALESCE($4, charter)
+		WHERE id = $1
+	`, id, totalScore, stars, charter)
+	if err != nil {
+		return err
 	}
-	if charter != nil {
-		if _, err := r.pool.Exec(ctx, `UPDATE scores SET charter = $1 WHERE id = $2`, charter, id); err != nil {
-			return err
-		}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
 	}
-	return nil
+	if err := r.revalidate(ctx, tx, id); err != nil {
+		return fmt.Errorf("failed to validate score: %w", err)
+	}
+	return tx.Commit(ctx)
 }
 
-// UpdatePlayer updates player stats for manual corrections.
-func (r *Repo) UpdatePlayer(ctx context.Context, id int64, name *string, instrument *string, difficulty *string, score *int64, combo *int, accuracy *float64, misses *int, rank *int) error {
-	if name == nil && instrument == nil && difficulty == nil && score == nil && combo == nil && accuracy == nil && misses == nil && rank == nil {
+// revalidate re-runs the validator on a stored score and replaces its
+// warnings. It does nothing without WithValidator.
+func (r *Repo) revalidate(ctx context.Context, tx pgx.Tx, scoreID int64) error {
+	if r.validate == nil {
+		return nil
+	}
+
+	rows, err := tx.Query(ctx, `SELECT `+scoreColumns+` FROM scores WHERE id = $1`, scoreID)
+	if err != nil {
+		return err
+	}
+	scores, err := scanScores(rows)
+	if err != nil {
+		return err
+	}
+	if len(scores) == 0 {
+		return ErrNotFound
+	}
+	players, err := queryPlayers(ctx, tx, []int64{scoreID})
+	if err != nil {
+		return err
+	}
+
+	s := scores[0]
+	data := CreateScoreData{Artist: s.Artist, GoldStars: s.GoldStars, Players: players[scoreID]}
+	if s.SongName != nil {
+		data.SongName = *s.SongName
+	}
+	if s.Charter != nil {
+		data.Charter = *s.Charter
+	}
+	if s.TotalScore != nil {
+		data.TotalScore = *s.TotalScore
+	}
+	if s.StarsAchieved != nil {
+		data.StarsAchieved = *s.StarsAchieved
+	}
+
+	// NULL rather than an empty list so has_warnings can filter on it
+	var warnings any
+	if w := r.validate(data); len(w) > 0 {
+		warnings = w
+	}
+	_, err = tx.Exec(ctx, `UPDATE scores SET warnings = $1 WHERE id = $2`, warnings, scoreID)
+	return err
+}
+
+// PlayerUpdate holds the player fields to correct. Nil fields are left as
+// they are.
+type PlayerUpdate struct {
+	Name           *string
+	Instrument     *string
+	Difficulty     *string
+	Score          *int64
+	Accuracy       *float64
+	TotalNotes     *int
+	NotesHit       *int
+	NotesMissed    *int
+	BestStreak     *int
+	Overhits       *int
+	AvgMultiplier  *float64
+	StarPowerHit   *int
+	StarPowerTotal *int
+	FullCombo      *bool
+	Rank           *int
+}
+
+// UpdatePlayer updates player stats for manual corrections, re-runs the
+// validator on its score and rebuilds the personal bests the run counts
+// towards. A new name links the player to the profile with that alias, if
+// any. It returns ErrNotFound when no player has the ID.
+func (r *Repo) UpdatePlayer(ctx context.Context, id int64, u PlayerUpdate) error {
+	var sets []string
+	args := []any{id}
+	set := func(column string, value any) {
+		args = append(args, value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	if u.Name != nil {
+		set("name", *u.Name)
+		// A renamed player belongs to whichever profile has the new name
+		sets = append(sets, fmt.Sprintf("profile_id = (SELECT profile_id FROM profile_aliases WHERE alias = $%d)", len(args)))
+	}
+	if u.Instrument != nil {
+		set("instrument", *u.Instrument)
+	}
+	if u.Difficulty != nil {
+		set("difficulty", *u.Difficulty)
+	}
+	if u.Score != nil {
+		set("score", *u.Score)
+	}
+	if u.Accuracy != nil {
+		set("accuracy", *u.Accuracy)
+	}
+	if u.TotalNotes != nil {
+		set("total_notes", *u.TotalNotes)
+	}
+	if u.NotesHit != nil {
+		set("notes_hit", *u.NotesHit)
+	}
+	if u.NotesMissed != nil {
+		set("notes_missed", *u.NotesMissed)
+	}
+	if u.BestStreak != nil {
+		set("best_streak", *u.BestStreak)
+	}
+	if u.Overhits != nil {
+		set("overhits", *u.Overhits)
+	}
+	if u.AvgMultiplier != nil {
+		set("avg_multiplier", *u.AvgMultiplier)
+	}
+	if u.StarPowerHit != nil {
+		set("star_power_hit", *u.StarPowerHit)
+	}
+	if u.StarPowerTotal != nil {
+		set("star_power_total", *u.StarPowerTotal)
+	}
+	if u.FullCombo != nil {
+		set("full_combo", *u.FullCombo)
+	}
+	if u.Rank != nil {
+		set("rank", *u.Rank)
+	}
+	if len(sets) == 0 {
 		return errors.New("no fields to update")
 	}
 
-	if name != nil {
-		if _, err := r.pool.Exec(ctx, `UPDATE players SET name = $1 WHERE id = $2`, name, id); err != nil {
-			return err
-		}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
 	}
-	if instrument != nil {
-		if _, err := r.pool.Exec(ctx, `UPDATE players SET instrument = $1 WHERE id = $2`, instrument, id); err != nil {
-			return err
-		}
+	defer tx.Rollback(ctx)
+
+	before, err := personalBestKeysWhere(ctx, tx, "p.id = $1", id)
+	if err != nil {
+		return err
 	}
-	if difficulty != nil {
-		if _, err := r.pool.Exec(ctx, `UPDATE players SET difficulty = $1 WHERE id = $2`, difficulty, id); err != nil {
-			return err
-		}
+	var scoreID int64
+	err = tx.QueryRow(ctx, `UPDATE players SET `+strings.Join(sets, ", ")+` WHERE id = $1 RETURNING score_id`, args...).Scan(&scoreID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
 	}
-	if score != nil {
-		if _, err := r.pool.Exec(ctx, `UPDATE players SET score = $1 WHERE id = $2`, score, id); err != nil {
-			return err
-		}
+	if err != nil {
+		return err
 	}
-	if combo != nil {
-		if _, err := r.pool.Exec(ctx, `UPDATE players SET combo = $1 WHERE id = $2`, combo, id); err != nil {
-			return err
-		}
+	if err := r.revalidate(ctx, tx, scoreID); err != nil {
+		return fmt.Errorf("failed to validate score: %w", err)
 	}
-	if accuracy != nil {
-		if _, err := r.pool.Exec(ctx, `UPDATE players SET accuracy = $1 WHERE id = $2`, accuracy, id); err != nil {
-			return err
-		}
+
+	// A corrected name, part or score moves the run between personal bests
+	// or changes what it set
+	after, err := personalBestKeysWhere(ctx, tx, "p.id = $1", id)
+	if err != nil {
+		return err
 	}
-	if misses != nil {
-		if _, err := r.pool.Exec(ctx, `UPDATE players SET misses = $1 WHERE id = $2`, misses, id); err != nil {
-			return err
-		}
+	if err := rebuildPersonalBestKeys(ctx, tx, append(before, after...)); err != nil {
+		return fmt.Errorf("failed to update personal bests: %w", err)
 	}
-	if rank != nil {
-		if _, err := r.pool.Exec(ctx, `UPDATE players SET rank = $1 WHERE id = $2`, rank, id); err != nil {
-			return err
+	return tx.Commit(ctx)
+}
+
+// ListPlayers returns the players of a score in the order they were stored.
+func (r *Repo) ListPlayers(ctx context.Context, scoreID int64) ([]Player, error) {
+	players, err := r.playersByScore(ctx, []int64{scoreID})
+	if err != nil {
+		return nil, err
+	}
+	return players[scoreID], nil
+}
+
+// playersByScore returns the players of each of the scores, keyed by score
+// ID, in the order they were stored.
+func (r *Repo) playersByScore(ctx context.Context, scoreIDs []int64) (map[int64][]Player, error) {
+	return queryPlayers(ctx, r.pool, scoreIDs)
+}
+
+func queryPlayers(ctx context.Context, q querier, scoreIDs []int64) (map[int64][]Player, error) {
+	rows, err := q.Query(ctx, `
+		SELECT score_id, id, name, COALESCE(instrument, ''), COALESCE(difficulty, ''), COALESCE(score, 0), COALESCE(accuracy, 0),
+		       COALESCE(total_notes, 0), COALESCE(notes_hit, 0), COALESCE(notes_missed, 0), COALESCE(best_streak, 0),
+		       COALESCE(overhits, 0), COALESCE(avg_multiplier, 0), COALESCE(star_power_hit, 0), COALESCE(star_power_total, 0),
+		       full_combo, COALESCE(rank, 0), new_pb, previous_best, profile_id
+		FROM players
+		WHERE score_id = ANY($1)
+		ORDER BY score_id, id
+	`, scoreIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[int64][]Player{}
+	for rows.Next() {
+		var scoreID int64
+		var p Player
+		if err := rows.Scan(&scoreID, &p.ID, &p.Name, &p.Instrument, &p.Difficulty, &p.Score, &p.Accuracy,
+			&p.TotalNotes, &p.NotesHit, &p.NotesMissed, &p.BestStreak,
+			&p.Overhits, &p.AvgMultiplier, &p.StarPowerHit, &p.StarPowerTotal,
+			&p.FullCombo, &p.Rank, &p.NewPB, &p.PreviousBest, &p.ProfileID); err != nil {
+			return nil, err
 		}
+		out[scoreID] = append(out[scoreID], p)
 	}
-	return nil
+	return out, rows.Err()
 }
 
 // Player represents a player in a score.
 type Player struct {
+	// ID is the stored player's ID, zero until the player is stored
+	ID            int64   `json:"id,omitempty"`
 	Name          string  `json:"name"`
 	Instrument    string  `json:"instrument,omitempty"`
 	Difficulty    string  `json:"difficulty,omitempty"`
@@ -258,22 +748,117 @@ type Player struct {
 	BestStreak    int     `json:"best_streak,omitempty"`
 	Overhits      int     `json:"overhits,omitempty"`
 	AvgMultiplier float64 `json:"avg_multiplier,omitempty"`
-	Rank          int     `json:"rank,omitempty"`
+	// StarPowerHit of StarPowerTotal star power phrases were completed
+	StarPowerHit   int  `json:"star_power_hit,omitempty"`
+	StarPowerTotal int  `json:"star_power_total,omitempty"`
+	FullCombo      bool `json:"full_combo,omitempty"`
+	Rank           int  `json:"rank,omitempty"`
+	// NewPB marks a stored run that beat the player's best score on the song,
+	// instrument and difficulty, or was their first. PreviousBest is the best
+	// score before it
+	NewPB        bool   `json:"new_pb,omitempty"`
+	PreviousBest *int64 `json:"previous_best,omitempty"`
+	// ProfileID is the profile with an alias matching Name, if any
+	ProfileID *int64 `json:"profile_id,omitempty"`
 }
 
 // CreateScoreData holds all data needed to create a score.
 type CreateScoreData struct {
-	Artist        string
-	SongName      string
-	Charter       string
-	TotalScore    int64
-	StarsAchieved int
-	Players       []Player
-	CreatedAt     time.Time
+	Artist        string         `json:"artist"`
+	SongName      string         `json:"song_name"`
+	Charter       string         `json:"charter"`
+	TotalScore    int64          `json:"total_score"`
+	StarsAchieved int            `json:"stars_achieved"`
+	GoldStars     bool           `json:"gold_stars"`
+	Players       []Player       `json:"players"`
+	CreatedAt     time.Time      `json:"created_at"`
+	Confidence    *OCRConfidence `json:"confidence,omitempty"`
+	// CreatedAtSource is where CreatedAt came from, one of the TimeSource
+	// constants. A zero CreatedAt is stored as unknown
+	CreatedAtSource string `json:"created_at_source,omitempty"`
+	// Warnings are the consistency rules the parsed score breaks
+	Warnings []Warning `json:"warnings,omitempty"`
+	// Source is where the score was read from, SourceScreenshot when empty
+	Source string `json:"source,omitempty"`
+	// ImageHash is the perceptual hash of the screenshot, empty when unknown
+	ImageHash string `json:"image_hash,omitempty"`
+	// DuplicateOf flags the score as a likely second capture of a stored one
+	DuplicateOf *int64 `json:"duplicate_of,omitempty"`
+}
+
+// Warning is a consistency rule a score breaks, like player scores that don't
+// add up to the total score.
+type Warning struct {
+	Rule    string `json:"rule"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Normalized instrument values stored in Player.Instrument, shared by every
+// source so runs from screenshots and scoredata.bin compare equal.
+const (
+	InstrumentGuitar   = "guitar"
+	InstrumentBass     = "bass"
+	InstrumentRhythm   = "rhythm"
+	InstrumentCoop     = "co-op"
+	InstrumentKeys     = "keys"
+	InstrumentProDrums = "pro drums"
+	InstrumentDrums    = "drums"
+	InstrumentNoPart   = "no part"
+)
+
+// Score sources.
+const (
+	SourceScreenshot = "screenshot"
+	SourceScoreData  = "scoredata"
+)
+
+// Where a score's capture time came from.
+const (
+	// TimeSourceFilename is the stamp in a screenshot's file name
+	TimeSourceFilename = "filename"
+	// TimeSourceMetadata is a PNG text chunk or EXIF date in the image
+	TimeSourceMetadata = "metadata"
+	// TimeSourceModTime is the file's modification time
+	TimeSourceModTime = "mtime"
+	// TimeSourceUnknown means the source records no capture time, so none
+	// is stored
+	TimeSourceUnknown = "unknown"
+)
+
+// OCRConfidence holds Tesseract's confidence (0-100) for each OCR'd region and
+// each parsed field. Player fields are keyed like "players.0.score".
+type OCRConfidence struct {
+	Regions map[string]float64 `json:"regions,omitempty"`
+	Fields  map[string]float64 `json:"fields,omitempty"`
+}
+
+// Lowest returns the lowest field confidence, or the lowest region confidence
+// when no field was parsed. It returns nil when nothing was recorded.
+func (c *OCRConfidence) Lowest() *float64 {
+	if c == nil {
+		return nil
+	}
+	values := c.Fields
+	if len(values) == 0 {
+		values = c.Regions
+	}
+
+	var lowest *float64
+	for _, v := range values {
+		if lowest == nil || v < *lowest {
+			lowest = &v
+		}
+	}
+	return lowest
 }
 
 // CreateScore creates a new score with artist, song, and players.
 // It handles creating or finding the artist and song, then creates the score and players.
+// Each named player's run is folded into their personal best on the song and
+// flagged when it's a new one, and linked to the profile with a matching alias.
+// OCR'd artist and song names close to ones already stored are snapped to
+// them; the score keeps the names as read in ocr_artist and ocr_song_name.
 func (r *Repo) CreateScore(ctx context.Context, data CreateScoreData) (int64, error) {
 	tx, err := r.pool.Begin(ctx)
 	if err != nil {
@@ -281,6 +866,30 @@ func (r *Repo) CreateScore(ctx context.Context, data CreateScoreData) (int64, er
 	}
 	defer tx.Rollback(ctx)
 
+	source := data.Source
+	if source == "" {
+		source = SourceScreenshot
+	}
+
+	// NULL rather than an empty list so has_warnings can filter on it
+	var warnings any
+	if len(data.Warnings) > 0 {
+		warnings = data.Warnings
+	}
+	// NULL when the source doesn't say when the run was played
+	var createdAt *time.Time
+	if !data.CreatedAt.IsZero() {
+		createdAt = &data.CreatedAt
+	}
+
+	ocrArtist, ocrSongName := data.Artist, data.SongName
+	if data.Artist, err = r.correctArtist(ctx, tx, data.Artist); err != nil {
+		return 0, err
+	}
+	if data.SongName, err = r.correctSong(ctx, tx, data.Artist, data.SongName); err != nil {
+		return 0, err
+	}
+
 	// Get or create artist
 	var artistID int64
 	err = tx.QueryRow(ctx, `
@@ -295,7 +904,7 @@ func (r *Repo) CreateScore(ctx context.Context, data CreateScoreData) (int64, er
 	// Get or create song
 	var songID int64
 	var charters []string
-	if data.Charter != "" && data.Charter != "" {
+	if data.Charter != "" {
 		charters = []string{data.Charter}
 	} else {
 		charters = []string{}
@@ -312,7 +921,7 @@ func (r *Repo) CreateScore(ctx context.Context, data CreateScoreData) (int64, er
 	}
 
 	// Update charters array if charter is provided (add if not already present)
-	if data.Charter != "" && data.Charter != "" {
+	if data.Charter != "" {
 		_, err = tx.Exec(ctx, `
 			UPDATE songs SET charters = array_append(charters, $1)
 			WHERE id = $2 AND NOT ($1 = ANY(charters))
@@ -325,10 +934,10 @@ func (r *Repo) CreateScore(ctx context.Context, data CreateScoreData) (int64, er
 	// Create score
 	var scoreID int64
 	err = tx.QueryRow(ctx, `
-		INSERT INTO scores (song_id, artist, charter, total_score, stars_achieved, players, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO scores (song_id, artist, charter, ocr_artist, ocr_song_name, source, total_score, stars_achieved, gold_stars, players, confidence, ocr_confidence, warnings, image_hash, duplicate, duplicate_of, created_at, created_at_source)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NULLIF($14, ''), $15, $16, $17, NULLIF($18, ''))
 		RETURNING id
-	`, songID, data.Artist, data.Charter, data.TotalScore, data.StarsAchieved, nil, data.CreatedAt).Scan(&scoreID)
+	`, songID, data.Artist, data.Charter, ocrArtist, ocrSongName, source, data.TotalScore, data.StarsAchieved, data.GoldStars, nil, data.Confidence.Lowest(), data.Confidence, warnings, data.ImageHash, data.DuplicateOf != nil, data.DuplicateOf, createdAt, data.CreatedAtSource).Scan(&scoreID)
 	if err != nil {
 		return 0, err
 	}
@@ -336,14 +945,24 @@ func (r *Repo) CreateScore(ctx context.Context, data CreateScoreData) (int64, er
 	// Create players
 	for _, p := range data.Players {
 		_, err = tx.Exec(ctx, `
-			INSERT INTO players (score_id, name, instrument, difficulty, score, best_streak, accuracy, notes_missed, rank, created_at)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-		`, scoreID, p.Name, p.Instrument, p.Difficulty, p.Score, p.BestStreak, p.Accuracy, p.NotesMissed, p.Rank, data.CreatedAt)
+			INSERT INTO players (score_id, name, instrument, difficulty, score, best_streak, accuracy, notes_missed, total_notes, notes_hit, overhits, avg_multiplier, star_power_hit, star_power_total, full_combo, rank, created_at, profile_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, (SELECT profile_id FROM profile_aliases WHERE alias = $2))
+		`, scoreID, p.Name, p.Instrument, p.Difficulty, p.Score, p.BestStreak, p.Accuracy, p.NotesMissed, p.TotalNotes, p.NotesHit, p.Overhits, p.AvgMultiplier, p.StarPowerHit, p.StarPowerTotal, p.FullCombo, p.Rank, createdAt)
 		if err != nil {
 			return 0, err
 		}
 	}
 
+	// Rebuilt rather than raised, as the run may have been played before
+	// ones already stored
+	keys, err := personalBestKeysWhere(ctx, tx, "p.score_id = $1", scoreID)
+	if err != nil {
+		return 0, err
+	}
+	if err := rebuildPersonalBestKeys(ctx, tx, keys); err != nil {
+		return 0, fmt.Errorf("failed to update personal bests: %w", err)
+	}
+
 	if err = tx.Commit(ctx); err != nil {
 		return 0, err
 	}
@@ -0,0 +1,487 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloneheroer/internal/db/dbtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPlayers sets every stored Player field, so a column the repo forgets
+// shows up as a zero value.
+var testPlayers = []Player{
+	{
+		Name:           "Bren",
+		Instrument:     "guitar",
+		Difficulty:     "Expert",
+		Score:          331450,
+		Accuracy:       98.5,
+		TotalNotes:     1860,
+		NotesHit:       1832,
+		NotesMissed:    28,
+		BestStreak:     612,
+		Overhits:       14,
+		AvgMultiplier:  3.42,
+		StarPowerHit:   7,
+		StarPowerTotal: 8,
+		Rank:           1,
+	},
+	{
+		Name:           "Jules",
+		Instrument:     "bass",
+		Difficulty:     "Hard",
+		Score:          115803,
+		Accuracy:       100,
+		TotalNotes:     904,
+		NotesHit:       904,
+		BestStreak:     904,
+		AvgMultiplier:  3.9,
+		StarPowerHit:   5,
+		StarPowerTotal: 5,
+		FullCombo:      true,
+		Rank:           2,
+	},
+}
+
+func TestCreateScore_PlayersRoundTrip(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	scoreID, err := repo.CreateScore(ctx, CreateScoreData{
+		Artist:        "Repo Test Artist " + time.Now().Format(time.RFC3339Nano),
+		SongName:      "Round Trip",
+		TotalScore:    447253,
+		StarsAchieved: 5,
+		Players:       testPlayers,
+		CreatedAt:     time.Now(),
+	})
+	require.NoError(t, err)
+
+	players, err := repo.ListPlayers(ctx, scoreID)
+	require.NoError(t, err)
+	require.Len(t, players, len(testPlayers))
+	for i, want := range testPlayers {
+		assert.NotZero(t, players[i].ID)
+		want.ID = players[i].ID
+		// A player's first run of a song is their personal best
+		want.NewPB = true
+		assert.Equal(t, want, players[i])
+	}
+}
+
+func TestCreateScore_PersonalBests(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	artist := "Repo Test Artist " + time.Now().Format(time.RFC3339Nano)
+	start := time.Now().Add(-time.Hour)
+	runs := []Player{
+		{Name: "Bren", Instrument: "guitar", Difficulty: "Expert", Score: 100000, Accuracy: 95, BestStreak: 300},
+		{Name: "Bren", Instrument: "guitar", Difficulty: "Expert", Score: 90000, Accuracy: 100, BestStreak: 904, FullCombo: true},
+		{Name: "Bren", Instrument: "guitar", Difficulty: "Expert", Score: 120000, Accuracy: 98, BestStreak: 500},
+		// Another difficulty is a separate personal best
+		{Name: "Bren", Instrument: "guitar", Difficulty: "Hard", Score: 80000},
+	}
+	var scoreIDs []int64
+	for i, run := range runs {
+		id, err := repo.CreateScore(ctx, CreateScoreData{
+			Artist:    artist,
+			SongName:  "Personal Bests",
+			Players:   []Player{run},
+			CreatedAt: start.Add(time.Duration(i) * time.Minute),
+		})
+		require.NoError(t, err)
+		scoreIDs = append(scoreIDs, id)
+	}
+
+	wantFlags := []struct {
+		newPB    bool
+		previous *int64
+	}{
+		{newPB: true},
+		{newPB: false, previous: dbtest.Ptr(int64(100000))},
+		{newPB: true, previous: dbtest.Ptr(int64(100000))},
+		{newPB: true},
+	}
+	for i, want := range wantFlags {
+		players, err := repo.ListPlayers(ctx, scoreIDs[i])
+		require.NoError(t, err)
+		require.Len(t, players, 1)
+		assert.Equal(t, want.newPB, players[0].NewPB, "run %d", i+1)
+		assert.Equal(t, want.previous, players[0].PreviousBest, "run %d", i+1)
+	}
+
+	score, err := repo.GetScore(ctx, scoreIDs[0])
+	require.NoError(t, err)
+	bests, err := repo.ListPersonalBests(ctx, 10, 0, PersonalBestFilter{SongID: score.SongID, Difficulty: "Expert"})
+	require.NoError(t, err)
+	require.Len(t, bests, 1)
+	best := bests[0]
+	assert.Equal(t, "Bren", best.PlayerName)
+	assert.Equal(t, "Personal Bests", best.SongName)
+	assert.Equal(t, int64(120000), best.Score)
+	assert.Equal(t, dbtest.Ptr(100.0), best.Accuracy, "the best accuracy of any run")
+	assert.Equal(t, dbtest.Ptr(904), best.BestStreak, "the best streak of any run")
+	assert.True(t, best.FullCombo)
+	assert.Equal(t, &scoreIDs[2], best.ScoreID)
+
+	history, err := repo.PersonalBestHistory(ctx, best.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, scoreIDs[0], history[0].ScoreID)
+	assert.Nil(t, history[0].PreviousBest)
+	assert.Equal(t, scoreIDs[2], history[1].ScoreID)
+	assert.Equal(t, dbtest.Ptr(int64(100000)), history[1].PreviousBest)
+
+	_, err = repo.PersonalBestHistory(ctx, -1)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestPersonalBests_Rebuilt(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	artist := "Repo Test Artist " + time.Now().Format(time.RFC3339Nano)
+	start := time.Now().Add(-time.Hour)
+	create := func(score int64, at time.Time, duplicateOf *int64) int64 {
+		t.Helper()
+		id, err := repo.CreateScore(ctx, CreateScoreData{
+			Artist:      artist,
+			SongName:    "Rebuilt",
+			Players:     []Player{{Name: "Bren", Instrument: "guitar", Difficulty: "Expert", Score: score}},
+			DuplicateOf: duplicateOf,
+			CreatedAt:   at,
+		})
+		require.NoError(t, err)
+		return id
+	}
+	var songID *int64
+	best := func() *PersonalBest {
+		t.Helper()
+		bests, err := repo.ListPersonalBests(ctx, 10, 0, PersonalBestFilter{SongID: songID, PlayerName: "Bren"})
+		require.NoError(t, err)
+		if len(bests) == 0 {
+			return nil
+		}
+		require.Len(t, bests, 1)
+		return &bests[0]
+	}
+	player := func(scoreID int64) Player {
+		t.Helper()
+		players, err := repo.ListPlayers(ctx, scoreID)
+		require.NoError(t, err)
+		require.Len(t, players, 1)
+		return players[0]
+	}
+
+	// Stored out of order: the earlier run set the best the later one missed
+	later := create(100000, start.Add(time.Minute), nil)
+	first := create(120000, start, nil)
+	score, err := repo.GetScore(ctx, first)
+	require.NoError(t, err)
+	songID = score.SongID
+	assert.True(t, player(first).NewPB)
+	assert.False(t, player(later).NewPB)
+	assert.Equal(t, dbtest.Ptr(int64(120000)), player(later).PreviousBest)
+	assert.Equal(t, int64(120000), best().Score)
+
+	t.Run("duplicates don't count", func(t *testing.T) {
+		duplicate := create(150000, start.Add(2*time.Minute), &first)
+		assert.Equal(t, int64(120000), best().Score)
+		assert.False(t, player(duplicate).NewPB)
+
+		require.NoError(t, repo.ResolveDuplicate(ctx, duplicate, true))
+		assert.Equal(t, int64(120000), best().Score)
+	})
+
+	t.Run("corrected score", func(t *testing.T) {
+		// 120000 was misread; the run was 90000
+		require.NoError(t, repo.UpdatePlayer(ctx, player(first).ID, PlayerUpdate{Score: dbtest.Ptr(int64(90000))}))
+		pb := best()
+		assert.Equal(t, int64(100000), pb.Score)
+		assert.Equal(t, &later, pb.ScoreID)
+		assert.True(t, player(later).NewPB)
+		assert.Equal(t, dbtest.Ptr(int64(90000)), player(later).PreviousBest)
+
+		history, err := repo.PersonalBestHistory(ctx, pb.ID)
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		assert.Equal(t, first, history[0].ScoreID)
+		assert.Equal(t, later, history[1].ScoreID)
+	})
+
+	t.Run("corrected name", func(t *testing.T) {
+		require.NoError(t, repo.UpdatePlayer(ctx, player(first).ID, PlayerUpdate{Name: dbtest.Ptr("Jules")}))
+		require.NoError(t, repo.UpdatePlayer(ctx, player(later).ID, PlayerUpdate{Name: dbtest.Ptr("Jules")}))
+		assert.Nil(t, best(), "no runs are left under the old name")
+	})
+}
+
+func TestCreateScore_UnknownTime(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	scoreID, err := repo.CreateScore(ctx, CreateScoreData{
+		Artist:          "Repo Test Artist " + time.Now().Format(time.RFC3339Nano),
+		SongName:        "No Date",
+		Players:         []Player{{Name: "Bren", Score: 1000}},
+		CreatedAtSource: TimeSourceUnknown,
+		Source:          SourceScoreData,
+	})
+	require.NoError(t, err)
+
+	score, err := repo.GetScore(ctx, scoreID)
+	require.NoError(t, err)
+	assert.Nil(t, score.CreatedAt)
+	assert.Equal(t, dbtest.Ptr(TimeSourceUnknown), score.CreatedAtSource)
+
+	bests, err := repo.ListPersonalBests(ctx, 10, 0, PersonalBestFilter{SongID: score.SongID})
+	require.NoError(t, err)
+	require.Len(t, bests, 1)
+	assert.Nil(t, bests[0].AchievedAt)
+}
+
+func TestGetScore(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	artist := "Repo Test Artist " + time.Now().Format(time.RFC3339Nano)
+	scoreID, err := repo.CreateScore(ctx, CreateScoreData{
+		Artist:     artist,
+		SongName:   "Detail",
+		TotalScore: 447253,
+		Players:    testPlayers,
+		CreatedAt:  time.Now(),
+	})
+	require.NoError(t, err)
+
+	score, err := repo.GetScore(ctx, scoreID)
+	require.NoError(t, err)
+	assert.Equal(t, artist, score.Artist)
+	require.NotNil(t, score.SongName)
+	assert.Equal(t, "Detail", *score.SongName)
+	require.Len(t, score.Players, len(testPlayers))
+	for i, p := range score.Players {
+		assert.NotZero(t, p.ID, "player IDs are needed to correct them")
+		assert.Equal(t, testPlayers[i].Name, p.Name)
+	}
+
+	t.Run("lists include players", func(t *testing.T) {
+		scores, err := repo.ListScores(ctx, 1000, 0, ScoreFilter{})
+		require.NoError(t, err)
+		var found *Score
+		for i := range scores {
+			if scores[i].ID == scoreID {
+				found = &scores[i]
+			}
+		}
+		require.NotNil(t, found)
+		assert.Equal(t, score.Players, found.Players)
+	})
+
+	t.Run("lists without players", func(t *testing.T) {
+		scores, err := repo.ListScores(ctx, 1000, 0, ScoreFilter{OmitPlayers: true})
+		require.NoError(t, err)
+		for _, s := range scores {
+			assert.Nil(t, s.Players)
+		}
+	})
+
+	t.Run("unknown score", func(t *testing.T) {
+		_, err := repo.GetScore(ctx, -1)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestUpdateArtistAndSong(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	artist := "Repo Test Artist " + time.Now().Format(time.RFC3339Nano)
+	scoreID, err := repo.CreateScore(ctx, CreateScoreData{
+		Artist:    artist,
+		SongName:  "Renames",
+		Players:   testPlayers[:1],
+		CreatedAt: time.Now(),
+	})
+	require.NoError(t, err)
+	score, err := repo.GetScore(ctx, scoreID)
+	require.NoError(t, err)
+	var artistID int64
+	require.NoError(t, repo.pool.QueryRow(ctx, `SELECT artist_id FROM songs WHERE id = $1`, *score.SongID).Scan(&artistID))
+
+	require.NoError(t, repo.UpdateArtist(ctx, artistID, dbtest.Ptr(artist+" (fixed)")))
+	score, err = repo.GetScore(ctx, scoreID)
+	require.NoError(t, err)
+	assert.Equal(t, artist+" (fixed)", score.Artist, "the score's artist follows the rename")
+
+	otherSong, err := repo.UpsertCatalogSong(ctx, CatalogSong{Name: "Elsewhere", Artist: artist + " other"})
+	require.NoError(t, err)
+	var otherArtistID int64
+	require.NoError(t, repo.pool.QueryRow(ctx, `SELECT artist_id FROM songs WHERE id = $1`, otherSong).Scan(&otherArtistID))
+	require.NoError(t, repo.UpdateSong(ctx, *score.SongID, nil, &otherArtistID, nil))
+	score, err = repo.GetScore(ctx, scoreID)
+	require.NoError(t, err)
+	assert.Equal(t, artist+" other", score.Artist, "the score's artist follows the move")
+
+	assert.ErrorIs(t, repo.UpdateArtist(ctx, -1, dbtest.Ptr("Nobody")), ErrNotFound)
+	assert.ErrorIs(t, repo.UpdateSong(ctx, -1, dbtest.Ptr("Nothing"), nil, nil), ErrNotFound)
+}
+
+func TestUpdatePlayer(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	scoreID, err := repo.CreateScore(ctx, CreateScoreData{
+		Artist:    "Repo Test Artist " + time.Now().Format(time.RFC3339Nano),
+		SongName:  "Corrections",
+		Players:   testPlayers[:1],
+		CreatedAt: time.Now(),
+	})
+	require.NoError(t, err)
+	players, err := repo.ListPlayers(ctx, scoreID)
+	require.NoError(t, err)
+	require.Len(t, players, 1)
+	id := players[0].ID
+
+	update := PlayerUpdate{
+		Name:           dbtest.Ptr("Bren B"),
+		Instrument:     dbtest.Ptr("drums"),
+		Difficulty:     dbtest.Ptr("Hard"),
+		Score:          dbtest.Ptr(int64(331460)),
+		Accuracy:       dbtest.Ptr(99.0),
+		TotalNotes:     dbtest.Ptr(1861),
+		NotesHit:       dbtest.Ptr(1842),
+		NotesMissed:    dbtest.Ptr(19),
+		BestStreak:     dbtest.Ptr(700),
+		Overhits:       dbtest.Ptr(3),
+		AvgMultiplier:  dbtest.Ptr(3.5),
+		StarPowerHit:   dbtest.Ptr(8),
+		StarPowerTotal: dbtest.Ptr(9),
+		FullCombo:      dbtest.Ptr(true),
+		Rank:           dbtest.Ptr(2),
+	}
+	require.NoError(t, repo.UpdatePlayer(ctx, id, update))
+
+	players, err = repo.ListPlayers(ctx, scoreID)
+	require.NoError(t, err)
+	require.Len(t, players, 1)
+	assert.Equal(t, Player{
+		ID:             id,
+		Name:           "Bren B",
+		Instrument:     "drums",
+		Difficulty:     "Hard",
+		Score:          331460,
+		Accuracy:       99,
+		TotalNotes:     1861,
+		NotesHit:       1842,
+		NotesMissed:    19,
+		BestStreak:     700,
+		Overhits:       3,
+		AvgMultiplier:  3.5,
+		StarPowerHit:   8,
+		StarPowerTotal: 9,
+		FullCombo:      true,
+		Rank:           2,
+	}, players[0])
+
+	t.Run("only the given fields change", func(t *testing.T) {
+		require.NoError(t, repo.UpdatePlayer(ctx, id, PlayerUpdate{NotesMissed: dbtest.Ptr(20)}))
+		players, err := repo.ListPlayers(ctx, scoreID)
+		require.NoError(t, err)
+		assert.Equal(t, 20, players[0].NotesMissed)
+		assert.Equal(t, 700, players[0].BestStreak)
+	})
+
+	t.Run("no fields", func(t *testing.T) {
+		assert.Error(t, repo.UpdatePlayer(ctx, id, PlayerUpdate{}))
+	})
+
+	t.Run("unknown player", func(t *testing.T) {
+		err := repo.UpdatePlayer(ctx, -1, PlayerUpdate{Rank: dbtest.Ptr(1)})
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestRevalidate(t *testing.T) {
+	// validate imports db, so a stand-in rule checks the corrected fields
+	tooHigh := Warning{Rule: "player_score_exceeds_total", Field: "players.0.score", Message: "too high"}
+	repo := newTestRepo(t, WithValidator(func(data CreateScoreData) []Warning {
+		if data.Players[0].Score > data.TotalScore {
+			return []Warning{tooHigh}
+		}
+		return nil
+	}))
+	ctx := context.Background()
+
+	scoreID, err := repo.CreateScore(ctx, CreateScoreData{
+		Artist:     "Repo Test Artist " + time.Now().Format(time.RFC3339Nano),
+		SongName:   "Revalidate",
+		TotalScore: 33145,
+		Players:    testPlayers[:1],
+		Warnings:   []Warning{tooHigh},
+		CreatedAt:  time.Now(),
+	})
+	require.NoError(t, err)
+	players, err := repo.ListPlayers(ctx, scoreID)
+	require.NoError(t, err)
+
+	// Fixing the misread player score clears the warning
+	require.NoError(t, repo.UpdatePlayer(ctx, players[0].ID, PlayerUpdate{Score: dbtest.Ptr(int64(33145))}))
+	score, err := repo.GetScore(ctx, scoreID)
+	require.NoError(t, err)
+	assert.Empty(t, score.Warnings)
+
+	// and a bad correction brings it back
+	require.NoError(t, repo.UpdateScore(ctx, scoreID, dbtest.Ptr(int64(3314)), nil, nil))
+	score, err = repo.GetScore(ctx, scoreID)
+	require.NoError(t, err)
+	assert.Equal(t, []Warning{tooHigh}, score.Warnings)
+
+	t.Run("unknown score", func(t *testing.T) {
+		err := repo.UpdateScore(ctx, -1, dbtest.Ptr(int64(1)), nil, nil)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestHasPlayerScore(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	scoreID, err := repo.CreateScore(ctx, CreateScoreData{
+		Artist:    "Repo Test Artist " + time.Now().Format(time.RFC3339Nano),
+		SongName:  "Same Run",
+		Players:   []Player{{Name: "Bren", Instrument: "guitar", Difficulty: "Expert", Score: 100000}},
+		CreatedAt: time.Now(),
+	})
+	require.NoError(t, err)
+	score, err := repo.GetScore(ctx, scoreID)
+	require.NoError(t, err)
+
+	run := Player{Name: "Bren", Instrument: "guitar", Difficulty: "Expert", Score: 100000}
+	exists, err := repo.HasPlayerScore(ctx, *score.SongID, run)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	for name, other := range map[string]Player{
+		"other player":     {Name: "Jules", Instrument: "guitar", Difficulty: "Expert", Score: 100000},
+		"other instrument": {Name: "Bren", Instrument: "bass", Difficulty: "Expert", Score: 100000},
+		"other difficulty": {Name: "Bren", Instrument: "guitar", Difficulty: "Hard", Score: 100000},
+		"other score":      {Name: "Bren", Instrument: "guitar", Difficulty: "Expert", Score: 100001},
+	} {
+		exists, err := repo.HasPlayerScore(ctx, *score.SongID, other)
+		require.NoError(t, err)
+		assert.False(t, exists, name)
+	}
+}
+
+// newTestRepo returns a Repo on the database TEST_DATABASE_URL points at,
+// migrated to the latest version, and skips the test when it isn't set.
+func newTestRepo(t *testing.T, opts ...Option) *Repo {
+	t.Helper()
+	return NewRepo(dbtest.Pool(t), opts...)
+}
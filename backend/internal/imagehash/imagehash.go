@@ -0,0 +1,163 @@
+// Package imagehash computes perceptual hashes of screenshots, so the same
+// results screen captured twice can be recognised even though the files
+// differ byte for byte.
+package imagehash
+
+import (
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"math/bits"
+	"os"
+	"sort"
+
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// thumbSize is the side of the grayscale thumbnail the DCT runs on
+	thumbSize = 64
+	// freqSize is the side of the block of lowest frequencies kept, one bit each
+	freqSize = 16
+)
+
+// Hash is a 256-bit perceptual hash. Similar images have hashes a small
+// Hamming distance apart.
+//
+// Results screens share their background and layout, so 64-bit hashes of two
+// different scores can be a couple of bits apart; 256 bits keep enough of the
+// text to tell them apart.
+type Hash [freqSize * freqSize / 64]uint64
+
+// Bits is the number of bits in a Hash, the largest possible distance.
+const Bits = freqSize * freqSize
+
+// Distance returns the number of bits h and other differ in, 0 to Bits.
+func (h Hash) Distance(other Hash) int {
+	d := 0
+	for i := range h {
+		d += bits.OnesCount64(h[i] ^ other[i])
+	}
+	return d
+}
+
+// String formats the hash as 64 hex digits.
+func (h Hash) String() string {
+	b := make([]byte, 0, len(h)*8)
+	for _, word := range h {
+		for shift := 56; shift >= 0; shift -= 8 {
+			b = append(b, byte(word>>shift))
+		}
+	}
+	return hex.EncodeToString(b)
+}
+
+// Parse reads a hash formatted by String.
+func Parse(s string) (Hash, error) {
+	var h Hash
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return h, fmt.Errorf("invalid image hash: %w", err)
+	}
+	if len(b) != len(h)*8 {
+		return h, fmt.Errorf("invalid image hash: want %d hex digits, got %d", len(h)*16, len(s))
+	}
+	for i := range h {
+		for _, c := range b[i*8 : i*8+8] {
+			h[i] = h[i]<<8 | uint64(c)
+		}
+	}
+	return h, nil
+}
+
+// File decodes the image at path and hashes it.
+func File(path string) (Hash, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Hash{}, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return Hash{}, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return Compute(img), nil
+}
+
+// Compute returns the DCT hash of img: it shrinks img to a grayscale
+// thumbnail, takes the lowest frequencies of its DCT and sets a bit for each
+// one above their median. It ignores scale, compression artifacts and small
+// brightness changes.
+func Compute(img image.Image) Hash {
+	pixels := thumbnail(img)
+
+	// Separable 2D DCT-II, rows then columns, keeping only the lowest
+	// frequencies of each
+	var rows [thumbSize][freqSize]float64
+	for y := 0; y < thumbSize; y++ {
+		for u := 0; u < freqSize; u++ {
+			rows[y][u] = dct(u, func(x int) float64 { return pixels[y][x] })
+		}
+	}
+	coeffs := make([]float64, 0, Bits)
+	for v := 0; v < freqSize; v++ {
+		for u := 0; u < freqSize; u++ {
+			coeffs = append(coeffs, dct(v, func(y int) float64 { return rows[y][u] }))
+		}
+	}
+
+	// The DC term is the mean brightness and would skew the median
+	sorted := append([]float64(nil), coeffs[1:]...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+
+	var h Hash
+	for i, c := range coeffs {
+		if c > median {
+			h[i/64] |= 1 << uint(63-i%64)
+		}
+	}
+	return h
+}
+
+// dct returns frequency k of the thumbSize samples returned by at.
+func dct(k int, at func(int) float64) float64 {
+	var sum float64
+	for n := 0; n < thumbSize; n++ {
+		sum += at(n) * math.Cos(math.Pi/thumbSize*(float64(n)+0.5)*float64(k))
+	}
+	return sum
+}
+
+// thumbnail shrinks img to thumbSize x thumbSize luminance values by
+// averaging the pixels each cell covers.
+func thumbnail(img image.Image) [thumbSize][thumbSize]float64 {
+	var out [thumbSize][thumbSize]float64
+	b := img.Bounds()
+	if b.Empty() {
+		return out
+	}
+
+	for ty := 0; ty < thumbSize; ty++ {
+		y0 := b.Min.Y + ty*b.Dy()/thumbSize
+		y1 := max(b.Min.Y+(ty+1)*b.Dy()/thumbSize, y0+1)
+		for tx := 0; tx < thumbSize; tx++ {
+			x0 := b.Min.X + tx*b.Dx()/thumbSize
+			x1 := max(b.Min.X+(tx+1)*b.Dx()/thumbSize, x0+1)
+
+			var sum float64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					r, g, bl, _ := img.At(x, y).RGBA()
+					sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)
+				}
+			}
+			out[ty][tx] = sum / float64((y1-y0)*(x1-x0)) / 0xffff
+		}
+	}
+	return out
+}
@@ -0,0 +1,111 @@
+package imagehash
+
+import (
+	"image"
+	"image/color"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/draw"
+)
+
+const _scoresPath = "../../../testdata/scores"
+
+func TestFile_SecondCapture(t *testing.T) {
+	first := hashFile(t, "clonehero-Discography-20250930000459.png")
+	second := hashFile(t, "clonehero-Discography-20250930000459-50.png")
+
+	assert.LessOrEqual(t, first.Distance(second), 10, "two captures of one results screen")
+}
+
+func TestFile_DifferentScores(t *testing.T) {
+	images, err := filepath.Glob(filepath.Join(_scoresPath, "*.png"))
+	require.NoError(t, err)
+
+	hashes := map[string]Hash{}
+	for _, path := range images {
+		hashes[filepath.Base(path)] = hashFile(t, filepath.Base(path))
+	}
+
+	// Every pair except the double capture is a different score on the same
+	// kind of screen
+	for a, ha := range hashes {
+		for b, hb := range hashes {
+			if a >= b || (a == "clonehero-Discography-20250930000459-50.png" && b == "clonehero-Discography-20250930000459.png") {
+				continue
+			}
+			assert.Greater(t, ha.Distance(hb), 10, "%s vs %s", a, b)
+		}
+	}
+}
+
+func TestFile_Errors(t *testing.T) {
+	_, err := File(filepath.Join(t.TempDir(), "missing.png"))
+	assert.Error(t, err)
+
+	_, err = File("imagehash.go")
+	assert.Error(t, err, "not an image")
+}
+
+func TestCompute_IgnoresScale(t *testing.T) {
+	img := gradient(640, 360)
+	scaled := image.NewRGBA(image.Rect(0, 0, 1920, 1080))
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	assert.LessOrEqual(t, Compute(img).Distance(Compute(scaled)), 4)
+}
+
+func TestCompute_EmptyImage(t *testing.T) {
+	assert.NotPanics(t, func() {
+		Compute(image.NewGray(image.Rect(0, 0, 0, 0)))
+	})
+}
+
+func TestDistance(t *testing.T) {
+	a := Hash{0, 0, 0, 0}
+	b := Hash{1, 0, 0xff, 1 << 63}
+
+	assert.Equal(t, 0, a.Distance(a))
+	assert.Equal(t, 10, a.Distance(b))
+	assert.Equal(t, Bits, a.Distance(Hash{^uint64(0), ^uint64(0), ^uint64(0), ^uint64(0)}))
+}
+
+func TestParse(t *testing.T) {
+	h := Hash{0x0123456789abcdef, 0, 1, ^uint64(0)}
+	s := h.String()
+	assert.Equal(t, "0123456789abcdef00000000000000000000000000000001ffffffffffffffff", s)
+
+	parsed, err := Parse(s)
+	require.NoError(t, err)
+	assert.Equal(t, h, parsed)
+
+	for _, invalid := range []string{"", "0123", "zz23456789abcdef00000000000000000000000000000001ffffffffffffffff"} {
+		_, err := Parse(invalid)
+		assert.Error(t, err, "%q", invalid)
+	}
+}
+
+func hashFile(t *testing.T, name string) Hash {
+	t.Helper()
+	h, err := File(filepath.Join(_scoresPath, name))
+	require.NoError(t, err)
+	return h
+}
+
+// gradient draws a diagonal gradient with a bright block, enough structure
+// for the low frequencies to mean something.
+func gradient(w, h int) image.Image {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := uint8((x + y) * 255 / (w + h))
+			if x > w/4 && x < w/2 && y > h/3 && y < h*2/3 {
+				v = 255
+			}
+			img.SetGray(x, y, color.Gray{Y: v})
+		}
+	}
+	return img
+}
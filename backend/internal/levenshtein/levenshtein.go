@@ -0,0 +1,26 @@
+// Package levenshtein measures how far apart two strings are, for snapping
+// OCR'd names to the catalog and for scoring the parser against its goldens.
+package levenshtein
+
+// Distance returns the number of single-rune insertions, deletions and
+// substitutions needed to turn a into b.
+func Distance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	curr := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		curr[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(br)]
+}
@@ -0,0 +1,25 @@
+package levenshtein
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistance(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"Motörhead", "Motorhead", 1},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, Distance(tc.a, tc.b), "%q -> %q", tc.a, tc.b)
+	}
+}
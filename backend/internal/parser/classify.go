@@ -0,0 +1,51 @@
+package parser
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+// resultsAnchors are labels only the results screen's player stats show.
+var resultsAnchors = []string{
+	"notes hit",
+	"notes missed",
+	"total notes",
+	"best streak",
+	"overstrums",
+	"overhits",
+}
+
+// IsResultsScreen reports whether the screenshot at imagePath shows a results
+// screen rather than gameplay, a menu or song select. The player panels' dark
+// name headers are checked first, as they need no OCR; failing that, the
+// player area is read and searched for stat labels like "Notes Hit".
+func (p *Parser) IsResultsScreen(imagePath string) (bool, error) {
+	img, err := p.loadImage(imagePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to load image: %w", err)
+	}
+
+	if columns := findPlayerColumns(img, p.layoutFor(img).Panel); len(columns) > 0 {
+		return true, nil
+	}
+
+	res := p.ocrRegion(nil, "players", PipelinePlayers, cropRegion(img, p.layoutFor(img).Players))
+	if hasResultsAnchor(res.Text) {
+		return true, nil
+	}
+	log.Printf("%s has no player panels or stat labels", filepath.Base(imagePath))
+	return false, nil
+}
+
+// hasResultsAnchor reports whether text contains any of resultsAnchors.
+func hasResultsAnchor(text string) bool {
+	lower := strings.ToLower(strings.Join(strings.Fields(text), " "))
+	for _, anchor := range resultsAnchors {
+		if strings.Contains(lower, anchor) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsResultsScreen(t *testing.T) {
+	parser, close := newTestParser(t)
+	defer close()
+
+	testCases := []struct {
+		name string
+		file string
+		want bool
+	}{
+		{name: "one player", file: "scores/clonehero-Discography-20250930000459.png", want: true},
+		{name: "four players", file: "scores/clonehero-Adolescents-20251019101128.png", want: true},
+		{name: "downscaled", file: "scores/clonehero-Discography-20250930000459-50.png", want: true},
+		{name: "song header only", file: "scores/fragments/tripping-billies-top-section.png", want: false},
+		{name: "score only", file: "scores/fragments/tripping-billies-only-score.png", want: false},
+		{name: "blank", file: "images/test-empty.png", want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parser.IsResultsScreen(filepath.Join(_testImagePath, tc.file))
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+// TestIsResultsScreen_OtherScreens checks Clone Hero captures of gameplay,
+// menus and song select, when some have been dropped into testdata/screens,
+// are all told apart from results screens.
+func TestIsResultsScreen_OtherScreens(t *testing.T) {
+	var paths []string
+	for _, pattern := range []string{"*.png", "*.jpg"} {
+		matches, err := filepath.Glob(filepath.Join(_testImagePath, "screens", pattern))
+		require.NoError(t, err)
+		paths = append(paths, matches...)
+	}
+	if len(paths) == 0 {
+		t.Skip("no gameplay, menu or song select screenshots in testdata/screens")
+	}
+
+	parser, close := newTestParser(t)
+	defer close()
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			got, err := parser.IsResultsScreen(path)
+			require.NoError(t, err)
+			assert.False(t, got)
+		})
+	}
+}
+
+func TestIsResultsScreen_InvalidImage(t *testing.T) {
+	parser, close := newTestParser(t)
+	defer close()
+
+	_, err := parser.IsResultsScreen("nonexistent.png")
+	assert.Error(t, err)
+}
+
+func TestHasResultsAnchor(t *testing.T) {
+	testCases := []struct {
+		name string
+		text string
+		want bool
+	}{
+		{name: "stat label", text: "Notes Hit 1,852 / 1,860", want: true},
+		{name: "label split across lines", text: "Best\nStreak 412", want: true},
+		{name: "guitar overstrums", text: "OVERSTRUMS 3", want: true},
+		{name: "song select", text: "Hail The Sun\nDiscography\nCharter: Acai", want: false},
+		{name: "gameplay", text: "x4\n123,456", want: false},
+		{name: "empty", text: "", want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, hasResultsAnchor(tc.text))
+		})
+	}
+}
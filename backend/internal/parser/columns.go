@@ -0,0 +1,456 @@
+package parser
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"cloneheroer/internal/db"
+)
+
+var (
+	accuracyRe  = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*%`)
+	statValueRe = regexp.MustCompile(`(\d[\d,]*(?:\.\d+)?)\s*[xX]?\s*$`)
+	// Star power phrases read "3 / 5" or "3 of 5"
+	starPowerRe = regexp.MustCompile(`(\d+)\s*(?:/|of)\s*(\d+)`)
+)
+
+// findPlayerColumns locates the player panels by their opaque dark name
+// headers and returns them left to right. It returns nil when the image
+// doesn't look like a results screen.
+func findPlayerColumns(img image.Image, panel PanelLayout) []image.Rectangle {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+	if width == 0 || height == 0 {
+		return nil
+	}
+
+	expectedWidth := float64(height) * panel.Width / 100
+	pitch := float64(height) * panel.Pitch / 100
+	if expectedWidth <= 0 || pitch <= 0 {
+		return nil
+	}
+
+	probeTop := bounds.Min.Y + pctOf(height, panel.HeaderProbeTop)
+	probeBottom := bounds.Min.Y + pctOf(height, panel.HeaderProbeBottom)
+	if probeBottom <= probeTop {
+		probeBottom = probeTop + 1
+	}
+
+	// Mark every x whose sampled header rows all have the header colour
+	isHeader := make([]bool, width)
+	for x := 0; x < width; x++ {
+		isHeader[x] = true
+		for y := probeTop; y < probeBottom; y++ {
+			if !isPanelHeaderColor(img.At(bounds.Min.X+x, y)) {
+				isHeader[x] = false
+				break
+			}
+		}
+	}
+
+	top := bounds.Min.Y + pctOf(height, panel.Top)
+	bottom := bounds.Min.Y + pctOf(height, panel.Bottom)
+
+	var columns []image.Rectangle
+	for _, run := range findRuns(isHeader, 2) {
+		runWidth := float64(run[1] - run[0])
+		if runWidth < expectedWidth*0.8 {
+			continue
+		}
+
+		// Panels over a background close to the header colour merge into one run
+		count := int(math.Round((runWidth + pitch - expectedWidth) / pitch))
+		if count < 1 {
+			count = 1
+		}
+		for i := 0; i < count; i++ {
+			left := run[0] + int(float64(i)*pitch)
+			right := left + int(expectedWidth)
+			if count == 1 {
+				right = run[1]
+			}
+			columns = append(columns, image.Rect(bounds.Min.X+left, top, bounds.Min.X+right, bottom))
+		}
+	}
+
+	if len(columns) > maxPlayers {
+		log.Printf("warning: found %d player columns, keeping the first %d", len(columns), maxPlayers)
+		columns = columns[:maxPlayers]
+	}
+	return columns
+}
+
+// findRuns returns [start, end) pairs of consecutive true values, bridging
+// gaps of up to maxGap false values.
+func findRuns(values []bool, maxGap int) [][2]int {
+	var runs [][2]int
+	start, lastTrue := -1, -1
+	for i, v := range values {
+		if !v {
+			continue
+		}
+		if start >= 0 && i-lastTrue-1 > maxGap {
+			runs = append(runs, [2]int{start, lastTrue + 1})
+			start = -1
+		}
+		if start < 0 {
+			start = i
+		}
+		lastTrue = i
+	}
+	if start >= 0 {
+		runs = append(runs, [2]int{start, lastTrue + 1})
+	}
+	return runs
+}
+
+// isPanelHeaderColor reports whether c is the near-black neutral grey Clone
+// Hero fills the player name headers with.
+func isPanelHeaderColor(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	r, g, b = r>>8, g>>8, b>>8
+	lum := luminance(c)
+	spread := max(r, g, b) - min(r, g, b)
+	return lum >= 14 && lum <= 40 && spread <= 12
+}
+
+// extractPlayerColumn OCRs the name, summary and stats areas of one player
+// panel, then the score, accuracy and stat values again with digit-only
+// settings when the layout defines their areas.
+func (p *Parser) extractPlayerColumn(run *parseRun, img image.Image, column image.Rectangle, panel PanelLayout, index int) db.Player {
+	height := img.Bounds().Dy()
+	top := img.Bounds().Min.Y
+	areaFrom := func(leftPct, fromPct, toPct float64) image.Image {
+		left := column.Min.X + int(float64(column.Dx())*leftPct/100)
+		return cropImage(img, left, top+pctOf(height, fromPct), column.Max.X, top+pctOf(height, toPct))
+	}
+	area := func(fromPct, toPct float64) image.Image {
+		return areaFrom(0, fromPct, toPct)
+	}
+
+	prefix := fmt.Sprintf("players.%d", index)
+	panelText := panelOCR{
+		name:    p.ocrRegion(run, prefix+".name", PipelinePlayerName, area(panel.Top, panel.NameBottom)),
+		summary: p.ocrRegion(run, prefix+".summary", PipelinePlayerSummary, area(panel.SummaryTop, panel.SummaryBottom)),
+		stats:   p.ocrRegion(run, prefix+".stats", PipelinePlayerStats, area(panel.StatsTop, panel.StatsBottom)),
+	}
+	if panel.ScoreTop > 0 {
+		panelText.score = p.ocrRegion(run, prefix+".score", PipelinePlayerScore, area(panel.ScoreTop, panel.SummaryBottom))
+	}
+	if panel.AccuracyBottom > 0 {
+		panelText.accuracy = p.ocrRegion(run, prefix+".accuracy", PipelinePlayerAccuracy, areaFrom(panel.AccuracyLeft, panel.SummaryTop, panel.AccuracyBottom))
+	}
+	if panel.StatsValueLeft > 0 {
+		panelText.statValues = p.ocrRegion(run, prefix+".stats_values", PipelinePlayerStatsValues, areaFrom(panel.StatsValueLeft, panel.StatsTop, panel.StatsBottom))
+	}
+
+	player, confidence := parsePlayerColumn(panelText)
+	run.recordFields(prefix, confidence)
+	if player.Instrument == "" {
+		iconArea := image.Rect(column.Min.X, top+pctOf(height, panel.IconTop), column.Max.X, top+pctOf(height, panel.IconBottom))
+		player.Instrument = p.detectColumnInstrument(img, iconArea)
+	}
+
+	log.Printf("extracted player column %v: %+v", column, player)
+	return player
+}
+
+// detectColumnInstrument returns the best matching instrument icon in area, if any.
+func (p *Parser) detectColumnInstrument(img image.Image, area image.Rectangle) string {
+	if len(p.instruments) == 0 {
+		return ""
+	}
+
+	var best iconMatch
+	for _, m := range p.matchInstrumentIcons(img, area) {
+		if m.score > best.score {
+			best = m
+		}
+	}
+	return best.instrument
+}
+
+// panelOCR holds the OCR results of one player panel's areas. The digit-only
+// reads of the score, accuracy and stat values are empty when the layout
+// doesn't define their areas.
+type panelOCR struct {
+	name, summary, stats        ocrResult
+	score, accuracy, statValues ocrResult
+}
+
+// parsePlayerColumn builds a player from the OCR results of one panel's areas.
+// Numbers come from the digit-only reads when those parse and from the mixed
+// text otherwise. It also returns the confidence of each field it filled,
+// keyed by JSON name.
+func parsePlayerColumn(panel panelOCR) (db.Player, map[string]float64) {
+	player := db.Player{}
+	confidence := map[string]float64{}
+	name, summary, stats := panel.name, panel.summary, panel.stats
+
+	if lines := filterEmpty(strings.Split(strings.TrimSpace(name.Text), "\n")); len(lines) > 0 {
+		player.Name = strings.TrimSpace(lines[0])
+		confidence["name"] = name.lineConfidence(lines[0])
+	}
+
+	for _, line := range filterEmpty(strings.Split(summary.Text, "\n")) {
+		line = strings.TrimSpace(line)
+		lower := strings.ToLower(line)
+
+		if strings.Contains(lower, "no part") {
+			player.Instrument = db.InstrumentNoPart
+			continue
+		}
+
+		if isFullComboText(lower) {
+			player.FullCombo = true
+			continue
+		}
+
+		if hasDifficulty(line) {
+			player.Difficulty = normalizeDifficulty(line)
+			confidence["difficulty"] = summary.lineConfidence(line)
+		}
+
+		if matches := accuracyRe.FindStringSubmatch(line); len(matches) > 1 {
+			if val, err := strconv.ParseFloat(matches[1], 64); err == nil {
+				player.Accuracy = val
+				confidence["accuracy"] = summary.lineConfidence(line)
+			}
+			continue
+		}
+
+		// The score is the only plain number in the summary; the last one wins
+		// so star glyphs misread as digits above it don't stick
+		if isNumeric(line) {
+			cleaned := nonDigitRe.ReplaceAllString(line, "")
+			if val, err := strconv.ParseInt(cleaned, 10, 64); err == nil {
+				player.Score = val
+				confidence["score"] = summary.lineConfidence(line)
+			}
+		}
+	}
+
+	for _, line := range filterEmpty(strings.Split(stats.Text, "\n")) {
+		lower := strings.ToLower(strings.TrimSpace(line))
+		if isFullComboText(lower) {
+			player.FullCombo = true
+			continue
+		}
+		if isStarPowerText(lower) {
+			if hit, total, ok := parseStarPower(line); ok {
+				player.StarPowerHit, player.StarPowerTotal = hit, total
+				confidence["star_power_hit"] = stats.lineConfidence(line)
+				if total > 0 {
+					confidence["star_power_total"] = stats.lineConfidence(line)
+				}
+			}
+			continue
+		}
+
+		matches := statValueRe.FindStringSubmatch(strings.TrimSpace(line))
+		if len(matches) < 2 {
+			continue
+		}
+		if field := statField(lower); field != "" && setStat(&player, field, matches[1]) {
+			confidence[field] = stats.lineConfidence(line)
+		}
+	}
+
+	applyDigitReads(&player, confidence, panel)
+	completeNoteCounts(&player, confidence)
+	return player, confidence
+}
+
+// statField returns the JSON name of the stat a lower-cased stats line is
+// labelled with, or "" when the label isn't known.
+func statField(lower string) string {
+	switch {
+	case strings.Contains(lower, "total notes"):
+		return "total_notes"
+	case strings.Contains(lower, "notes hit"):
+		return "notes_hit"
+	case strings.Contains(lower, "notes missed"):
+		return "notes_missed"
+	case strings.Contains(lower, "best streak"):
+		return "best_streak"
+	case strings.Contains(lower, "overhits"), strings.Contains(lower, "overstrums"):
+		return "overhits"
+	case strings.Contains(lower, "multiplier"):
+		return "avg_multiplier"
+	}
+	return ""
+}
+
+// setStat parses value, like "1,852" or "2.921", into the player's field. It
+// reports whether the value parsed.
+func setStat(player *db.Player, field, value string) bool {
+	value = strings.ReplaceAll(value, ",", "")
+	if field == "avg_multiplier" {
+		val, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return false
+		}
+		player.AvgMultiplier = val
+		return true
+	}
+
+	val, err := strconv.Atoi(value)
+	if err != nil {
+		return false
+	}
+	switch field {
+	case "total_notes":
+		player.TotalNotes = val
+	case "notes_hit":
+		player.NotesHit = val
+	case "notes_missed":
+		player.NotesMissed = val
+	case "best_streak":
+		player.BestStreak = val
+	case "overhits":
+		player.Overhits = val
+	default:
+		return false
+	}
+	return true
+}
+
+// applyDigitReads overwrites numbers parsed from the mixed text with the
+// digit-only reads of the same areas. Stat values carry no labels, so each is
+// paired with the label line it sits level with in the full-width stats read.
+func applyDigitReads(player *db.Player, confidence map[string]float64, panel panelOCR) {
+	if lines := filterEmpty(strings.Split(panel.score.Text, "\n")); len(lines) > 0 {
+		line := lines[len(lines)-1]
+		cleaned := nonDigitRe.ReplaceAllString(line, "")
+		if val, err := strconv.ParseInt(cleaned, 10, 64); err == nil {
+			player.Score = val
+			confidence["score"] = panel.score.lineConfidence(line)
+		}
+	}
+
+	for _, line := range filterEmpty(strings.Split(panel.accuracy.Text, "\n")) {
+		if matches := accuracyRe.FindStringSubmatch(line); len(matches) > 1 {
+			if val, err := strconv.ParseFloat(matches[1], 64); err == nil && val <= 100 {
+				player.Accuracy = val
+				confidence["accuracy"] = panel.accuracy.lineConfidence(line)
+				break
+			}
+		}
+	}
+
+	for _, label := range panel.stats.Lines {
+		field := statField(strings.ToLower(label.Text))
+		if field == "" {
+			continue
+		}
+		value, ok := levelLine(panel.stats, label, panel.statValues)
+		if !ok {
+			continue
+		}
+		matches := statValueRe.FindStringSubmatch(strings.TrimSpace(value.Text))
+		if len(matches) > 1 && setStat(player, field, matches[1]) {
+			confidence[field] = value.Confidence
+		}
+	}
+}
+
+// levelLine finds the line of other whose vertical centre falls within line,
+// a line of res. Both results must come from crops spanning the same rows;
+// positions are compared as fractions of each image's height.
+func levelLine(res ocrResult, line ocrLine, other ocrResult) (ocrLine, bool) {
+	if res.Height == 0 || other.Height == 0 || line.Box.Empty() {
+		return ocrLine{}, false
+	}
+	top := float64(line.Box.Min.Y) / float64(res.Height)
+	bottom := float64(line.Box.Max.Y) / float64(res.Height)
+	for _, candidate := range other.Lines {
+		if candidate.Box.Empty() {
+			continue
+		}
+		centre := float64(candidate.Box.Min.Y+candidate.Box.Max.Y) / 2 / float64(other.Height)
+		if centre >= top && centre <= bottom {
+			return candidate, true
+		}
+	}
+	return ocrLine{}, false
+}
+
+// isFullComboText reports whether a lower-cased line is Clone Hero's full
+// combo banner.
+func isFullComboText(lower string) bool {
+	return strings.Contains(lower, "full combo")
+}
+
+// isStarPowerText reports whether a lower-cased line is the star power
+// phrases stat.
+func isStarPowerText(lower string) bool {
+	return strings.Contains(lower, "star power") || strings.Contains(lower, "sp phrases")
+}
+
+// parseStarPower reads the phrases hit and the phrase count from a star power
+// line. A lone number is taken as the phrases hit.
+func parseStarPower(line string) (hit, total int, ok bool) {
+	if m := starPowerRe.FindStringSubmatch(line); len(m) > 2 {
+		hit, _ = strconv.Atoi(m[1])
+		total, _ = strconv.Atoi(m[2])
+		return hit, total, true
+	}
+	if m := statValueRe.FindStringSubmatch(strings.TrimSpace(line)); len(m) > 1 {
+		if val, err := strconv.Atoi(strings.ReplaceAll(m[1], ",", "")); err == nil {
+			return val, 0, true
+		}
+	}
+	return 0, 0, false
+}
+
+// completeNoteCounts derives a note count OCR didn't read from the other two
+// and marks the player as a full combo when every note was hit. read holds
+// the fields that were actually read, keyed by JSON name. Overstrums and
+// overhits aren't considered: a run that missed no notes is a full combo.
+func completeNoteCounts(player *db.Player, read map[string]float64) {
+	known := 0
+	for _, field := range []string{"total_notes", "notes_hit", "notes_missed"} {
+		if _, ok := read[field]; ok {
+			known++
+		}
+	}
+	// Two of the three are needed both to derive the third and to trust a zero
+	if known < 2 {
+		return
+	}
+
+	if _, ok := read["total_notes"]; !ok {
+		player.TotalNotes = player.NotesHit + player.NotesMissed
+	}
+	if _, ok := read["notes_hit"]; !ok {
+		player.NotesHit = max(player.TotalNotes-player.NotesMissed, 0)
+	}
+	if _, ok := read["notes_missed"]; !ok {
+		player.NotesMissed = max(player.TotalNotes-player.NotesHit, 0)
+	}
+
+	if player.TotalNotes > 0 && player.NotesHit == player.TotalNotes && player.NotesMissed == 0 {
+		player.FullCombo = true
+	}
+}
+
+// hasDifficulty reports whether s names one of the four difficulties.
+func hasDifficulty(s string) bool {
+	lower := strings.ToLower(s)
+	return strings.Contains(lower, "easy") ||
+		strings.Contains(lower, "medium") ||
+		strings.Contains(lower, "hard") ||
+		strings.Contains(lower, "expert")
+}
+
+// pctOf returns pct percent of size, rounded down.
+func pctOf(size int, pct float64) int {
+	return int(float64(size) * pct / 100)
+}
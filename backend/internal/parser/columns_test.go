@@ -0,0 +1,270 @@
+package parser
+
+import (
+	"image"
+	"path/filepath"
+	"testing"
+
+	"cloneheroer/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindPlayerColumns(t *testing.T) {
+	testCases := []struct {
+		name      string
+		filepath  string
+		wantCount int
+	}{
+		{
+			name:      "single player",
+			filepath:  filepath.Join(_testImagePath, "scores", "clonehero-Discography-20250930000459.png"),
+			wantCount: 1,
+		},
+		{
+			name:      "two players",
+			filepath:  filepath.Join(_testImagePath, "scores", "clonehero-Tripping-Billies-20251209195440.png"),
+			wantCount: 2,
+		},
+		{
+			name:      "two players over a colourful background",
+			filepath:  filepath.Join(_testImagePath, "scores", "clonehero-atreyu-20251129173107.png"),
+			wantCount: 2,
+		},
+		{
+			name:      "three players",
+			filepath:  filepath.Join(_testImagePath, "scores", "clonehero-Made-Your-Mark-20251019165816.png"),
+			wantCount: 3,
+		},
+		{
+			name:      "four players",
+			filepath:  filepath.Join(_testImagePath, "scores", "clonehero-Adolescents-20251019101128.png"),
+			wantCount: 4,
+		},
+		{
+			name:      "not a results screen",
+			filepath:  filepath.Join(_testImagePath, "images", "test-players.png"),
+			wantCount: 0,
+		},
+	}
+
+	parser := newLayoutOnlyParser(t)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			img, err := parser.loadImage(tc.filepath)
+			require.NoError(t, err)
+
+			panel := parser.layoutFor(img).Panel
+			columns := findPlayerColumns(img, panel)
+			require.Len(t, columns, tc.wantCount)
+
+			height := img.Bounds().Dy()
+			for i, column := range columns {
+				assert.InDelta(t, float64(height)*panel.Width/100, column.Dx(), 4, "column %d width", i)
+				if i > 0 {
+					assert.Greater(t, column.Min.X, columns[i-1].Max.X, "columns should be left to right without overlap")
+				}
+			}
+		})
+	}
+}
+
+func TestFindPlayerColumns_DetectsColumnInstruments(t *testing.T) {
+	templates, err := loadInstrumentTemplates(_instrumentIconsPath)
+	require.NoError(t, err)
+	parser := newLayoutOnlyParser(t)
+	parser.instruments = templates
+
+	instruments := []string{db.InstrumentKeys, db.InstrumentProDrums, db.InstrumentRhythm}
+	img := syntheticResultsScreen(t, 1920, 1080, instruments)
+
+	panel := parser.layoutFor(img).Panel
+	columns := findPlayerColumns(img, panel)
+	require.Len(t, columns, len(instruments))
+	for i, column := range columns {
+		height := img.Bounds().Dy()
+		iconArea := column
+		iconArea.Min.Y = pctOf(height, panel.IconTop)
+		iconArea.Max.Y = pctOf(height, panel.IconBottom)
+		assert.Equal(t, instruments[i], parser.detectColumnInstrument(img, iconArea))
+	}
+}
+
+func TestParsePlayerColumn(t *testing.T) {
+	testCases := []struct {
+		name        string
+		nameText    string
+		summaryText string
+		statsText   string
+		expected    db.Player
+	}{
+		{
+			name:        "full panel",
+			nameText:    "_gem_\n",
+			summaryText: "Expert 95%\n\n378,745\n",
+			statsText:   "Total Notes 1,852\nNotes Hit 1,773\nNotes Missed 79\nBest Streak 173\nAvg. Multiplier 2.921x\nOverhits 94\n",
+			expected: db.Player{
+				Name:          "_gem_",
+				Difficulty:    "Expert",
+				Accuracy:      95,
+				Score:         378745,
+				TotalNotes:    1852,
+				NotesHit:      1773,
+				NotesMissed:   79,
+				BestStreak:    173,
+				AvgMultiplier: 2.921,
+				Overhits:      94,
+			},
+		},
+		{
+			name:        "star power phrases",
+			nameText:    "_gem_",
+			summaryText: "Expert 95%\n378,745",
+			statsText:   "Total Notes 1,852\nNotes Hit 1,773\nStar Power Phrases 7 / 9\n",
+			expected: db.Player{
+				Name:           "_gem_",
+				Difficulty:     "Expert",
+				Accuracy:       95,
+				Score:          378745,
+				TotalNotes:     1852,
+				NotesHit:       1773,
+				NotesMissed:    79,
+				StarPowerHit:   7,
+				StarPowerTotal: 9,
+			},
+		},
+		{
+			name:        "every note hit is a full combo",
+			nameText:    "zac",
+			summaryText: "Medium 100%\n41,785",
+			statsText:   "Total Notes 357\nNotes Hit 357\nBest Streak 357\n",
+			expected: db.Player{
+				Name:       "zac",
+				Difficulty: "Medium",
+				Accuracy:   100,
+				Score:      41785,
+				TotalNotes: 357,
+				NotesHit:   357,
+				BestStreak: 357,
+				FullCombo:  true,
+			},
+		},
+		{
+			name:        "full combo banner",
+			nameText:    "zac",
+			summaryText: "Medium 100%\nFULL COMBO\n41,785",
+			expected: db.Player{
+				Name:       "zac",
+				Difficulty: "Medium",
+				Accuracy:   100,
+				Score:      41785,
+				FullCombo:  true,
+			},
+		},
+		{
+			name:        "unread note counts are not a full combo",
+			nameText:    "zac",
+			summaryText: "Medium 100%\n41,785",
+			statsText:   "Total Notes 357\nBest Streak 357\n",
+			expected: db.Player{
+				Name:       "zac",
+				Difficulty: "Medium",
+				Accuracy:   100,
+				Score:      41785,
+				TotalNotes: 357,
+				BestStreak: 357,
+			},
+		},
+		{
+			name:        "difficulty and accuracy on separate lines with overstrums",
+			nameText:    "A_Hole_Pro",
+			summaryText: "Medium\n81%\n34,793",
+			statsText:   "Notes Missed 67\nBest Streak 45\nAvg. Multiplier 1.117x\nOverstrums 123",
+			expected: db.Player{
+				Name:          "A_Hole_Pro",
+				Difficulty:    "Medium",
+				Accuracy:      81,
+				Score:         34793,
+				NotesMissed:   67,
+				BestStreak:    45,
+				AvgMultiplier: 1.117,
+				Overhits:      123,
+			},
+		},
+		{
+			name:        "no part",
+			nameText:    "A_Hole_Pro",
+			summaryText: "No Part\n0",
+			statsText:   "Notes Missed 0\nBest Streak 0",
+			expected: db.Player{
+				Name:       "A_Hole_Pro",
+				Instrument: db.InstrumentNoPart,
+			},
+		},
+		{
+			name:     "empty text",
+			expected: db.Player{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			player, _ := parsePlayerColumn(panelOCR{name: textResult(tc.nameText), summary: textResult(tc.summaryText), stats: textResult(tc.statsText)})
+			assert.Equal(t, tc.expected, player)
+		})
+	}
+}
+
+func TestParsePlayerColumn_DigitReads(t *testing.T) {
+	// The stats crop is 200px tall and the values crop, upscaled, 400px
+	stats := newOCRResult([]ocrLine{
+		{Text: "Notes Hit 1,2B4", Confidence: 60, Box: image.Rect(0, 10, 300, 40)},
+		{Text: "Best Streak S12", Confidence: 60, Box: image.Rect(0, 60, 300, 90)},
+		{Text: "Overhits 7", Confidence: 65, Box: image.Rect(0, 110, 300, 140)},
+	})
+	stats.Height = 200
+	values := newOCRResult([]ocrLine{
+		{Text: "1,234", Confidence: 90, Box: image.Rect(0, 20, 100, 80)},
+		{Text: "512", Confidence: 92, Box: image.Rect(0, 120, 100, 180)},
+	})
+	values.Height = 400
+
+	player, confidence := parsePlayerColumn(panelOCR{
+		summary:    textResult("Expert\n98.2S%\n123,4S6"),
+		stats:      stats,
+		score:      newOCRResult([]ocrLine{{Text: "123,456", Confidence: 88}}),
+		accuracy:   newOCRResult([]ocrLine{{Text: "98.25%", Confidence: 87}}),
+		statValues: values,
+	})
+
+	assert.Equal(t, int64(123456), player.Score)
+	assert.Equal(t, 98.25, player.Accuracy)
+	assert.Equal(t, 1234, player.NotesHit)
+	assert.Equal(t, 512, player.BestStreak)
+	assert.Equal(t, 7, player.Overhits, "a label with no value level with it keeps its mixed read")
+	assert.Equal(t, 88.0, confidence["score"])
+	assert.Equal(t, 87.0, confidence["accuracy"])
+	assert.Equal(t, 90.0, confidence["notes_hit"])
+	assert.Equal(t, 65.0, confidence["overhits"])
+}
+
+func TestParsePlayerColumn_DigitReadsWithoutBoxes(t *testing.T) {
+	// Without positions the values can't be told apart, so the labelled
+	// reads stand
+	player, _ := parsePlayerColumn(panelOCR{
+		stats:      textResult("Notes Hit 1,234\nBest Streak 512"),
+		statValues: textResult("9,999\n999"),
+	})
+
+	assert.Equal(t, 1234, player.NotesHit)
+	assert.Equal(t, 512, player.BestStreak)
+}
+
+func TestFindRuns(t *testing.T) {
+	values := []bool{false, true, true, false, true, false, false, false, true, true}
+
+	assert.Equal(t, [][2]int{{1, 5}, {8, 10}}, findRuns(values, 2))
+	assert.Equal(t, [][2]int{{1, 3}, {4, 5}, {8, 10}}, findRuns(values, 0))
+	assert.Nil(t, findRuns([]bool{false, false}, 2))
+}
@@ -0,0 +1,120 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DefaultDebugDir is where debug dumps go when no directory is configured.
+const DefaultDebugDir = "debug"
+
+// WithDebug dumps every parsed image when enabled. See ParseImageDebug.
+func WithDebug(enabled bool) Option {
+	return func(p *Parser) {
+		p.debug = enabled
+	}
+}
+
+// WithDebugDir sets the directory debug dumps are written under.
+func WithDebugDir(dir string) Option {
+	return func(p *Parser) {
+		p.debugDir = dir
+	}
+}
+
+// debugDump writes what the parser saw for one image into its own folder:
+// each region's crop and preprocessed crop, the OCR text and the result.
+// Writes are best effort; the first failure is logged and the rest skipped so
+// a full disk doesn't fail the parse.
+type debugDump struct {
+	dir string
+
+	mu     sync.Mutex
+	failed bool
+}
+
+// newDebugDump creates an empty folder for imagePath under root, replacing
+// the dump of an earlier parse of the same file.
+func newDebugDump(root, imagePath string) (*debugDump, error) {
+	name := strings.TrimSuffix(filepath.Base(imagePath), filepath.Ext(imagePath))
+	dir := filepath.Join(root, name)
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("failed to clear debug folder: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create debug folder: %w", err)
+	}
+	return &debugDump{dir: dir}, nil
+}
+
+// saveRegion writes a region's crop, preprocessed crop and OCR text. A nil
+// dump is a no-op.
+func (d *debugDump) saveRegion(region string, crop, preprocessed image.Image, res ocrResult) {
+	if d == nil {
+		return
+	}
+	d.saveImage(region+".crop.png", crop)
+	d.saveImage(region+".preprocessed.png", preprocessed)
+
+	var text strings.Builder
+	for _, line := range res.Lines {
+		fmt.Fprintf(&text, "%5.1f%%  %s\n", line.Confidence, line.Text)
+	}
+	d.save(region+".txt", func(f *os.File) error {
+		_, err := f.WriteString(text.String())
+		return err
+	})
+}
+
+// saveCrop writes a region that isn't OCR'd, like the star row.
+func (d *debugDump) saveCrop(region string, crop image.Image) {
+	if d == nil {
+		return
+	}
+	d.saveImage(region+".crop.png", crop)
+}
+
+// saveResult writes the parsed data as result.json.
+func (d *debugDump) saveResult(result any) {
+	if d == nil {
+		return
+	}
+	d.save("result.json", func(f *os.File) error {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	})
+}
+
+func (d *debugDump) saveImage(name string, img image.Image) {
+	if img == nil || img.Bounds().Empty() {
+		return
+	}
+	d.save(name, func(f *os.File) error {
+		return encodePNG(f, img)
+	})
+}
+
+func (d *debugDump) save(name string, write func(*os.File) error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.failed {
+		return
+	}
+
+	f, err := os.Create(filepath.Join(d.dir, name))
+	if err == nil {
+		err = errors.Join(write(f), f.Close())
+	}
+	if err != nil {
+		log.Printf("warning: failed to write debug output %q, skipping the rest: %v", name, err)
+		d.failed = true
+	}
+}
@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cloneheroer/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseImageDebug(t *testing.T) {
+	debugDir := t.TempDir()
+	parser, err := NewParser(1920, 1080, WithDebugDir(debugDir))
+	require.NoError(t, err)
+	defer parser.Close()
+
+	imagePath := filepath.Join(_testImagePath, "scores", "clonehero-Tripping-Billies-20251209195440.png")
+	data, dir, err := parser.ParseImageDebug(imagePath)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(debugDir, "clonehero-Tripping-Billies-20251209195440"), dir)
+
+	for _, region := range []string{"top_left", "center", "players.0.name", "players.0.summary", "players.0.stats", "players.1.stats"} {
+		assert.FileExists(t, filepath.Join(dir, region+".crop.png"))
+		assert.FileExists(t, filepath.Join(dir, region+".preprocessed.png"))
+		assert.FileExists(t, filepath.Join(dir, region+".txt"))
+	}
+	assert.FileExists(t, filepath.Join(dir, "stars.crop.png"))
+
+	contents, err := os.ReadFile(filepath.Join(dir, "result.json"))
+	require.NoError(t, err)
+	var saved db.CreateScoreData
+	require.NoError(t, json.Unmarshal(contents, &saved))
+	assert.Equal(t, data.StarsAchieved, saved.StarsAchieved)
+	assert.Len(t, saved.Players, len(data.Players))
+}
+
+func TestParseImageDebug_ReplacesEarlierDump(t *testing.T) {
+	debugDir := t.TempDir()
+	parser, err := NewParser(1920, 1080, WithDebugDir(debugDir))
+	require.NoError(t, err)
+	defer parser.Close()
+
+	stale := filepath.Join(debugDir, "clonehero-Discography-20250930000459", "players.3.name.txt")
+	require.NoError(t, os.MkdirAll(filepath.Dir(stale), 0755))
+	require.NoError(t, os.WriteFile(stale, []byte("old"), 0644))
+
+	_, _, err = parser.ParseImageDebug(filepath.Join(_testImagePath, "scores", "clonehero-Discography-20250930000459.png"))
+	require.NoError(t, err)
+	assert.NoFileExists(t, stale)
+}
+
+func TestParseImage_DebugOption(t *testing.T) {
+	imagePath := filepath.Join(_testImagePath, "scores", "clonehero-Discography-20250930000459.png")
+
+	testCases := []struct {
+		name     string
+		debug    bool
+		wantDump bool
+	}{
+		{name: "off by default", debug: false, wantDump: false},
+		{name: "on for every image", debug: true, wantDump: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			debugDir := t.TempDir()
+			parser, err := NewParser(1920, 1080, WithDebug(tc.debug), WithDebugDir(debugDir))
+			require.NoError(t, err)
+			defer parser.Close()
+
+			_, err = parser.ParseImage(imagePath)
+			require.NoError(t, err)
+
+			entries, err := os.ReadDir(debugDir)
+			require.NoError(t, err)
+			if tc.wantDump {
+				assert.Len(t, entries, 1)
+			} else {
+				assert.Empty(t, entries)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package parser
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"cloneheroer/internal/db"
+	"cloneheroer/internal/levenshtein"
+
+	"github.com/stretchr/testify/require"
+)
+
+// update rewrites the golden files from parser output. The goldens are
+// hand-checked against the screenshots, so review the diff before committing.
+var update = flag.Bool("update", false, "rewrite testdata/scores/*.json from parser output")
+
+// TestGolden parses every screenshot in testdata/scores and compares the
+// result with its <name>.json golden file. It reports accuracy per field
+// rather than failing on OCR mistakes; run it with -v to see the report:
+//
+//	go test ./internal/parser -run TestGolden -v
+func TestGolden(t *testing.T) {
+	images, err := filepath.Glob(filepath.Join(_testImagePath, "scores", "*.png"))
+	require.NoError(t, err)
+	require.NotEmpty(t, images)
+
+	parser, close := newTestParser(t)
+	defer close()
+
+	acc := newAccuracy()
+	for _, imagePath := range images {
+		goldenPath := strings.TrimSuffix(imagePath, filepath.Ext(imagePath)) + ".json"
+		name := filepath.Base(goldenPath)
+
+		t.Run(strings.TrimSuffix(name, ".json"), func(t *testing.T) {
+			got, err := parser.ParseImage(imagePath)
+			require.NoError(t, err)
+			got.Confidence = nil
+
+			if *update {
+				writeGolden(t, goldenPath, got)
+				return
+			}
+
+			want := readGolden(t, goldenPath)
+			for _, diff := range acc.compare(want, got) {
+				t.Log(diff)
+			}
+		})
+	}
+
+	if !*update {
+		t.Logf("accuracy over %d screenshots:\n%s", len(images), acc.report())
+	}
+}
+
+func readGolden(t *testing.T, path string) *db.CreateScoreData {
+	t.Helper()
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err, "missing golden file, create it by hand or with -update")
+
+	var data db.CreateScoreData
+	require.NoError(t, json.Unmarshal(contents, &data))
+	return &data
+}
+
+func writeGolden(t *testing.T, path string, data *db.CreateScoreData) {
+	t.Helper()
+	contents, err := json.MarshalIndent(data, "", "  ")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, append(contents, '\n'), 0644))
+}
+
+// fieldStats tallies one field across all screenshots. dist is the edit
+// distance for text fields and the absolute error for numbers.
+type fieldStats struct {
+	numeric bool
+	total   int
+	exact   int
+	dist    float64
+}
+
+// accuracy collects per-field stats. Player fields are pooled across
+// players; a player the parser missed counts against every player field.
+type accuracy struct {
+	fields map[string]*fieldStats
+}
+
+func newAccuracy() *accuracy {
+	return &accuracy{fields: map[string]*fieldStats{}}
+}
+
+// compare records want against got and returns a line per mismatch.
+func (a *accuracy) compare(want, got *db.CreateScoreData) []string {
+	var diffs []string
+	text := func(label, field, want, got string) {
+		if !a.text(field, want, got) {
+			diffs = append(diffs, fmt.Sprintf("%s: got %q, want %q", label, got, want))
+		}
+	}
+	number := func(label, field string, want, got float64) {
+		if !a.number(field, want, got) {
+			diffs = append(diffs, fmt.Sprintf("%s: got %v, want %v", label, got, want))
+		}
+	}
+
+	text("artist", "artist", want.Artist, got.Artist)
+	text("song_name", "song_name", want.SongName, got.SongName)
+	text("charter", "charter", want.Charter, got.Charter)
+	number("total_score", "total_score", float64(want.TotalScore), float64(got.TotalScore))
+	number("stars_achieved", "stars_achieved", float64(want.StarsAchieved), float64(got.StarsAchieved))
+	text("gold_stars", "gold_stars", fmt.Sprint(want.GoldStars), fmt.Sprint(got.GoldStars))
+	if !want.CreatedAt.Equal(got.CreatedAt) {
+		diffs = append(diffs, fmt.Sprintf("created_at: got %v, want %v", got.CreatedAt, want.CreatedAt))
+	}
+	text("created_at_source", "created_at_source", want.CreatedAtSource, got.CreatedAtSource)
+	number("players", "players", float64(len(want.Players)), float64(len(got.Players)))
+
+	for i, w := range want.Players {
+		var g db.Player
+		if i < len(got.Players) {
+			g = got.Players[i]
+		}
+		// Stats pool players together, mismatches name the player
+		at := func(field string) string {
+			return fmt.Sprintf("players.%d.%s", i, field)
+		}
+		text(at("name"), "players.name", w.Name, g.Name)
+		text(at("instrument"), "players.instrument", w.Instrument, g.Instrument)
+		text(at("difficulty"), "players.difficulty", w.Difficulty, g.Difficulty)
+		number(at("score"), "players.score", float64(w.Score), float64(g.Score))
+		number(at("accuracy"), "players.accuracy", w.Accuracy, g.Accuracy)
+		number(at("total_notes"), "players.total_notes", float64(w.TotalNotes), float64(g.TotalNotes))
+		number(at("notes_hit"), "players.notes_hit", float64(w.NotesHit), float64(g.NotesHit))
+		number(at("notes_missed"), "players.notes_missed", float64(w.NotesMissed), float64(g.NotesMissed))
+		number(at("best_streak"), "players.best_streak", float64(w.BestStreak), float64(g.BestStreak))
+		number(at("avg_multiplier"), "players.avg_multiplier", w.AvgMultiplier, g.AvgMultiplier)
+		number(at("overhits"), "players.overhits", float64(w.Overhits), float64(g.Overhits))
+		number(at("star_power_hit"), "players.star_power_hit", float64(w.StarPowerHit), float64(g.StarPowerHit))
+		number(at("star_power_total"), "players.star_power_total", float64(w.StarPowerTotal), float64(g.StarPowerTotal))
+		text(at("full_combo"), "players.full_combo", fmt.Sprint(w.FullCombo), fmt.Sprint(g.FullCombo))
+	}
+	return diffs
+}
+
+func (a *accuracy) text(field, want, got string) bool {
+	s := a.field(field, false)
+	d := levenshtein.Distance(want, got)
+	s.dist += float64(d)
+	if d == 0 {
+		s.exact++
+	}
+	return d == 0
+}
+
+func (a *accuracy) number(field string, want, got float64) bool {
+	s := a.field(field, true)
+	d := math.Abs(want - got)
+	s.dist += d
+	if d < 1e-9 {
+		s.exact++
+	}
+	return d < 1e-9
+}
+
+func (a *accuracy) field(name string, numeric bool) *fieldStats {
+	s, ok := a.fields[name]
+	if !ok {
+		s = &fieldStats{numeric: numeric}
+		a.fields[name] = s
+	}
+	s.total++
+	return s
+}
+
+// report formats a table of exact-match rate and mean error per field.
+func (a *accuracy) report() string {
+	names := make([]string, 0, len(a.fields))
+	for name := range a.fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-26s %-12s %8s  %s\n", "field", "exact", "", "mean error")
+	for _, name := range names {
+		s := a.fields[name]
+		unit := "edits"
+		if s.numeric {
+			unit = "abs"
+		}
+		fmt.Fprintf(&b, "%-26s %5d/%-6d %7.1f%%  %.3f %s\n",
+			name, s.exact, s.total, 100*float64(s.exact)/float64(s.total), s.dist/float64(s.total), unit)
+	}
+	return b.String()
+}
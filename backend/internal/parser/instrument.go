@@ -0,0 +1,347 @@
+package parser
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"cloneheroer/internal/db"
+
+	"golang.org/x/image/draw"
+)
+
+// instrumentIconOrder lists the icons in img/instrum-icons.png from top to bottom.
+var instrumentIconOrder = []string{
+	db.InstrumentGuitar,
+	db.InstrumentBass,
+	db.InstrumentRhythm,
+	db.InstrumentCoop,
+	db.InstrumentKeys,
+	db.InstrumentProDrums,
+	db.InstrumentDrums,
+}
+
+const (
+	// iconReferenceHeight is the screen height the icon sheet matches 1:1.
+	iconReferenceHeight = 1080
+	// iconWorkScale shrinks icons and screenshots before matching to keep it cheap.
+	iconWorkScale = 0.5
+	// iconMatchThreshold is the minimum correlation accepted as an icon.
+	iconMatchThreshold = 0.6
+	// maxPlayers is the most player columns Clone Hero shows on the results screen.
+	maxPlayers = 4
+)
+
+// instrumentTemplate is a reference icon prepared for masked correlation.
+type instrumentTemplate struct {
+	instrument string
+	w, h       int
+	pix        []float64 // zero-mean luminance, 0 outside the mask
+	mask       []bool
+	count      int
+	norm       float64
+}
+
+// iconMatch is an instrument icon located in a screenshot.
+type iconMatch struct {
+	instrument string
+	x, y       int
+	score      float64
+}
+
+// findInstrumentIconsPath locates the reference icon sheet.
+// INSTRUMENT_ICONS_PATH wins; otherwise img/instrum-icons.png is searched for
+// from the working directory upwards so both the repo root and backend/ work.
+func findInstrumentIconsPath() string {
+	if path := os.Getenv("INSTRUMENT_ICONS_PATH"); path != "" {
+		return path
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		candidate := filepath.Join(dir, "img", "instrum-icons.png")
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// loadInstrumentTemplates reads the icon sheet and splits it into one template
+// per instrument. Icons are stacked vertically on a chroma-green background.
+func loadInstrumentTemplates(path string) ([]instrumentTemplate, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	sheet, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode icon sheet: %w", err)
+	}
+
+	icons := splitIconSheet(sheet)
+	if len(icons) != len(instrumentIconOrder) {
+		return nil, fmt.Errorf("expected %d icons in %s, found %d", len(instrumentIconOrder), path, len(icons))
+	}
+
+	templates := make([]instrumentTemplate, 0, len(icons))
+	for i, rect := range icons {
+		templates = append(templates, newInstrumentTemplate(instrumentIconOrder[i], sheet, rect))
+	}
+	return templates, nil
+}
+
+// splitIconSheet returns the bounding box of each icon, top to bottom.
+func splitIconSheet(sheet image.Image) []image.Rectangle {
+	bounds := sheet.Bounds()
+	var rects []image.Rectangle
+	start := -1
+	minX, maxX := bounds.Max.X, bounds.Min.X
+
+	for y := bounds.Min.Y; y <= bounds.Max.Y; y++ {
+		rowHasIcon := false
+		if y < bounds.Max.Y {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				if !isChromaGreen(sheet.At(x, y)) {
+					rowHasIcon = true
+					minX = min(minX, x)
+					maxX = max(maxX, x)
+				}
+			}
+		}
+
+		if rowHasIcon && start < 0 {
+			start = y
+		}
+		if !rowHasIcon && start >= 0 {
+			rects = append(rects, image.Rect(minX, start, maxX+1, y))
+			start = -1
+			minX, maxX = bounds.Max.X, bounds.Min.X
+		}
+	}
+	return rects
+}
+
+// newInstrumentTemplate scales an icon down to the working scale and records
+// which pixels belong to the icon rather than the sheet background.
+func newInstrumentTemplate(instrument string, sheet image.Image, rect image.Rectangle) instrumentTemplate {
+	w := int(math.Round(float64(rect.Dx()) * iconWorkScale))
+	h := int(math.Round(float64(rect.Dy()) * iconWorkScale))
+	scaled := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.BiLinear.Scale(scaled, scaled.Bounds(), sheet, rect, draw.Src, nil)
+
+	t := instrumentTemplate{
+		instrument: instrument,
+		w:          w,
+		h:          h,
+		pix:        make([]float64, w*h),
+		mask:       make([]bool, w*h),
+	}
+
+	var sum float64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := scaled.At(x, y)
+			if isChromaGreen(c) {
+				continue
+			}
+			i := y*w + x
+			t.mask[i] = true
+			t.pix[i] = luminance(c)
+			sum += t.pix[i]
+			t.count++
+		}
+	}
+
+	mean := sum / float64(max(t.count, 1))
+	var sq float64
+	for i := range t.pix {
+		if t.mask[i] {
+			t.pix[i] -= mean
+			sq += t.pix[i] * t.pix[i]
+		}
+	}
+	t.norm = math.Sqrt(sq)
+	return t
+}
+
+// detectInstruments finds the instrument icons in the players' icon row and
+// returns them left to right, one per player column that shows an icon.
+func (p *Parser) detectInstruments(img image.Image) []string {
+	if len(p.instruments) == 0 {
+		return nil
+	}
+
+	// Icon row: between the player name header and the per-player stars
+	panel := p.layoutFor(img).Panel
+	band := Region{Left: 0, Top: panel.IconTop, Right: 100, Bottom: panel.IconBottom}.rect(img.Bounds())
+
+	matches := p.matchInstrumentIcons(img, band)
+	instruments := make([]string, 0, len(matches))
+	for _, m := range matches {
+		instruments = append(instruments, m.instrument)
+	}
+	return instruments
+}
+
+// matchInstrumentIcons template-matches every reference icon inside area and
+// returns the non-overlapping matches sorted left to right.
+func (p *Parser) matchInstrumentIcons(img image.Image, area image.Rectangle) []iconMatch {
+	area = area.Intersect(img.Bounds())
+	if area.Empty() {
+		return nil
+	}
+
+	// Bring the area to the scale the templates were prepared at
+	factor := iconReferenceHeight / float64(img.Bounds().Dy())
+	w := int(math.Round(float64(area.Dx()) * factor * iconWorkScale))
+	h := int(math.Round(float64(area.Dy()) * factor * iconWorkScale))
+	if w == 0 || h == 0 {
+		return nil
+	}
+	gray := image.NewGray(image.Rect(0, 0, w, h))
+	draw.BiLinear.Scale(gray, gray.Bounds(), img, area, draw.Src, nil)
+
+	tw, th := p.instruments[0].w, p.instruments[0].h
+	if w < tw || h < th {
+		return nil
+	}
+
+	// Coarse pass: best correlation of any icon at every other position
+	var candidates []iconMatch
+	for y := 0; y+th <= h; y += 2 {
+		for x := 0; x+tw <= w; x += 2 {
+			best := iconMatch{x: x, y: y}
+			for i := range p.instruments {
+				if s := p.instruments[i].correlate(gray, x, y); s > best.score {
+					best.score = s
+					best.instrument = p.instruments[i].instrument
+				}
+			}
+			if best.score >= iconMatchThreshold*0.8 {
+				candidates = append(candidates, best)
+			}
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+
+	// Keep the strongest candidate per icon footprint, then refine it
+	var matches []iconMatch
+	for _, c := range candidates {
+		overlaps := false
+		for _, m := range matches {
+			if abs(c.x-m.x) < tw && abs(c.y-m.y) < th {
+				overlaps = true
+				break
+			}
+		}
+		if overlaps {
+			continue
+		}
+
+		refined := p.refineIconMatch(gray, c)
+		matches = append(matches, c)
+		if refined.score >= iconMatchThreshold {
+			matches[len(matches)-1] = refined
+		} else {
+			matches[len(matches)-1].score = 0
+		}
+		if len(matches) == maxPlayers*2 {
+			break
+		}
+	}
+
+	var accepted []iconMatch
+	for _, m := range matches {
+		if m.score >= iconMatchThreshold {
+			accepted = append(accepted, m)
+		}
+	}
+	sort.Slice(accepted, func(i, j int) bool { return accepted[i].x < accepted[j].x })
+	if len(accepted) > maxPlayers {
+		accepted = accepted[:maxPlayers]
+	}
+	return accepted
+}
+
+// refineIconMatch searches the neighbourhood of a coarse match pixel by pixel
+// and picks the best instrument there.
+func (p *Parser) refineIconMatch(gray *image.Gray, coarse iconMatch) iconMatch {
+	best := coarse
+	best.score = 0
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			for i := range p.instruments {
+				t := &p.instruments[i]
+				if s := t.correlate(gray, coarse.x+dx, coarse.y+dy); s > best.score {
+					best = iconMatch{instrument: t.instrument, x: coarse.x + dx, y: coarse.y + dy, score: s}
+				}
+			}
+		}
+	}
+	return best
+}
+
+// correlate returns the masked normalized cross-correlation of the template
+// placed at (ox, oy) in gray, in the range [-1, 1].
+func (t *instrumentTemplate) correlate(gray *image.Gray, ox, oy int) float64 {
+	b := gray.Bounds()
+	if ox < b.Min.X || oy < b.Min.Y || ox+t.w > b.Max.X || oy+t.h > b.Max.Y || t.count == 0 || t.norm == 0 {
+		return 0
+	}
+
+	var sum, sumSq, cross float64
+	for y := 0; y < t.h; y++ {
+		row := gray.Pix[(oy+y-b.Min.Y)*gray.Stride+(ox-b.Min.X):]
+		for x := 0; x < t.w; x++ {
+			i := y*t.w + x
+			if !t.mask[i] {
+				continue
+			}
+			v := float64(row[x])
+			sum += v
+			sumSq += v * v
+			cross += t.pix[i] * v
+		}
+	}
+
+	n := float64(t.count)
+	variance := sumSq - sum*sum/n
+	if variance <= 1e-6 {
+		return 0
+	}
+	return cross / (t.norm * math.Sqrt(variance))
+}
+
+// isChromaGreen reports whether c is the icon sheet's green background.
+func isChromaGreen(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	r, g, b = r>>8, g>>8, b>>8
+	return g > r+40 && g > b+40
+}
+
+func luminance(c color.Color) float64 {
+	r, g, b, _ := c.RGBA()
+	return (0.299*float64(r>>8) + 0.587*float64(g>>8) + 0.114*float64(b>>8))
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
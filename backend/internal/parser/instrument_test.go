@@ -0,0 +1,178 @@
+package parser
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cloneheroer/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/draw"
+)
+
+var _instrumentIconsPath = filepath.Join("..", "..", "..", "img", "instrum-icons.png")
+
+func TestLoadInstrumentTemplates(t *testing.T) {
+	templates, err := loadInstrumentTemplates(_instrumentIconsPath)
+	require.NoError(t, err)
+	require.Len(t, templates, len(instrumentIconOrder))
+
+	for i, tpl := range templates {
+		assert.Equal(t, instrumentIconOrder[i], tpl.instrument)
+		assert.Greater(t, tpl.count, 0, "template %s should have icon pixels", tpl.instrument)
+		assert.Greater(t, tpl.norm, 0.0, "template %s should have contrast", tpl.instrument)
+	}
+}
+
+func TestLoadInstrumentTemplates_MissingFile(t *testing.T) {
+	_, err := loadInstrumentTemplates("nonexistent.png")
+	assert.Error(t, err)
+}
+
+func TestDetectInstruments_SyntheticScreenshots(t *testing.T) {
+	templates, err := loadInstrumentTemplates(_instrumentIconsPath)
+	require.NoError(t, err)
+	parser := newLayoutOnlyParser(t)
+	parser.instruments = templates
+
+	testCases := []struct {
+		name        string
+		width       int
+		height      int
+		instruments []string
+	}{
+		{
+			name:        "single player",
+			width:       1920,
+			height:      1080,
+			instruments: []string{db.InstrumentProDrums},
+		},
+		{
+			name:        "two players",
+			width:       1920,
+			height:      1080,
+			instruments: []string{db.InstrumentDrums, db.InstrumentBass},
+		},
+		{
+			name:        "three players",
+			width:       1920,
+			height:      1080,
+			instruments: []string{db.InstrumentGuitar, db.InstrumentRhythm, db.InstrumentKeys},
+		},
+		{
+			name:        "four players at 720p",
+			width:       1280,
+			height:      720,
+			instruments: []string{db.InstrumentCoop, db.InstrumentGuitar, db.InstrumentBass, db.InstrumentProDrums},
+		},
+		{
+			name:        "ultrawide",
+			width:       1920,
+			height:      810,
+			instruments: []string{db.InstrumentKeys, db.InstrumentDrums},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			img := syntheticResultsScreen(t, tc.width, tc.height, tc.instruments)
+			assert.Equal(t, tc.instruments, parser.detectInstruments(img))
+		})
+	}
+}
+
+func TestDetectInstruments_NoIcons(t *testing.T) {
+	templates, err := loadInstrumentTemplates(_instrumentIconsPath)
+	require.NoError(t, err)
+	parser := newLayoutOnlyParser(t)
+	parser.instruments = templates
+
+	img := syntheticResultsScreen(t, 1920, 1080, nil)
+	assert.Empty(t, parser.detectInstruments(img))
+}
+
+func TestDetectInstruments_NoTemplates(t *testing.T) {
+	parser := &Parser{}
+	img := syntheticResultsScreen(t, 1920, 1080, []string{db.InstrumentGuitar})
+	assert.Nil(t, parser.detectInstruments(img))
+}
+
+func TestAssignInstruments(t *testing.T) {
+	players := []db.Player{
+		{Name: "_gem_"},
+		{Name: "A_Hole_Pro", Instrument: db.InstrumentNoPart},
+		{Name: "zac"},
+	}
+
+	assignInstruments(players, []string{db.InstrumentProDrums, db.InstrumentGuitar})
+
+	assert.Equal(t, db.InstrumentProDrums, players[0].Instrument)
+	assert.Equal(t, db.InstrumentNoPart, players[1].Instrument)
+	assert.Equal(t, db.InstrumentGuitar, players[2].Instrument)
+}
+
+// syntheticResultsScreen draws a results-like screen with one player column per
+// instrument and the matching icon from the sheet composited into each column.
+func syntheticResultsScreen(t *testing.T, width, height int, instruments []string) image.Image {
+	t.Helper()
+
+	file, err := os.Open(_instrumentIconsPath)
+	require.NoError(t, err)
+	defer file.Close()
+	sheet, _, err := image.Decode(file)
+	require.NoError(t, err)
+	icons := splitIconSheet(sheet)
+	require.Len(t, icons, len(instrumentIconOrder))
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	// Blue-to-purple background, like the default Clone Hero theme
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{uint8(30 + 40*x/width), 60, uint8(90 + 60*y/height), 255})
+		}
+	}
+
+	scale := float64(height) / iconReferenceHeight
+	columnWidth := int(448 * scale)
+	pitch := int(465 * scale)
+	centerY := int(335 * scale)
+	for i, instrument := range instruments {
+		centerX := width/2 + int((float64(i)-float64(len(instruments)-1)/2)*float64(pitch))
+		panel := image.Rect(centerX-columnWidth/2, int(218*scale), centerX+columnWidth/2, int(897*scale))
+		header := image.Rect(panel.Min.X, panel.Min.Y, panel.Max.X, int(290*scale))
+		draw.Draw(img, panel, image.NewUniform(color.RGBA{20, 40, 70, 255}), image.Point{}, draw.Src)
+		draw.Draw(img, header, image.NewUniform(color.RGBA{26, 26, 26, 255}), image.Point{}, draw.Src)
+
+		idx := -1
+		for j, name := range instrumentIconOrder {
+			if name == instrument {
+				idx = j
+			}
+		}
+		require.GreaterOrEqual(t, idx, 0, "unknown instrument %q", instrument)
+		compositeIcon(img, sheet, icons[idx], image.Pt(centerX, centerY), scale)
+	}
+	return img
+}
+
+// compositeIcon draws an icon from the sheet centred on at, dropping the green background.
+func compositeIcon(dst *image.RGBA, sheet image.Image, src image.Rectangle, at image.Point, scale float64) {
+	w := int(float64(src.Dx()) * scale)
+	h := int(float64(src.Dy()) * scale)
+	scaled := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.BiLinear.Scale(scaled, scaled.Bounds(), sheet, src, draw.Src, nil)
+
+	origin := image.Pt(at.X-w/2, at.Y-h/2)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := scaled.At(x, y)
+			if !isChromaGreen(c) {
+				dst.Set(origin.X+x, origin.Y+y, c)
+			}
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package parser
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"strings"
+)
+
+// LayoutAuto selects the layout profile closest to each image's aspect ratio.
+const LayoutAuto = "auto"
+
+//go:embed layouts.json
+var defaultLayoutsJSON []byte
+
+// Region is a rectangle in percent of the image width (Left, Right) and
+// height (Top, Bottom).
+type Region struct {
+	Left   float64 `json:"left"`
+	Top    float64 `json:"top"`
+	Right  float64 `json:"right"`
+	Bottom float64 `json:"bottom"`
+}
+
+// PanelLayout describes the player panels. Width and Pitch are in percent of
+// image height because Clone Hero sizes the panels with the screen height and
+// centres them horizontally; the rest are percent of image height too.
+type PanelLayout struct {
+	Width             float64 `json:"width"`
+	Pitch             float64 `json:"pitch"`
+	Top               float64 `json:"top"`
+	HeaderProbeTop    float64 `json:"header_probe_top"`
+	HeaderProbeBottom float64 `json:"header_probe_bottom"`
+	NameBottom        float64 `json:"name_bottom"`
+	SummaryTop        float64 `json:"summary_top"`
+	SummaryBottom     float64 `json:"summary_bottom"`
+	IconTop           float64 `json:"icon_top"`
+	IconBottom        float64 `json:"icon_bottom"`
+	StatsTop          float64 `json:"stats_top"`
+	StatsBottom       float64 `json:"stats_bottom"`
+	Bottom            float64 `json:"bottom"`
+
+	// The digit-only areas are skipped when left out. ScoreTop and
+	// AccuracyBottom are percent of image height; AccuracyLeft and
+	// StatsValueLeft are percent of the panel's width.
+	ScoreTop       float64 `json:"score_top,omitempty"`
+	AccuracyLeft   float64 `json:"accuracy_left,omitempty"`
+	AccuracyBottom float64 `json:"accuracy_bottom,omitempty"`
+	StatsValueLeft float64 `json:"stats_value_left,omitempty"`
+}
+
+// StarRowLayout describes the row of star icons under the total score. All
+// values are percent of image height; the row is centred horizontally.
+type StarRowLayout struct {
+	Top    float64 `json:"top"`
+	Bottom float64 `json:"bottom"`
+	Size   float64 `json:"size"`
+	Pitch  float64 `json:"pitch"`
+	Count  int     `json:"count"`
+}
+
+// Layout is a named set of crop regions for one screen shape.
+type Layout struct {
+	Name        string        `json:"name"`
+	AspectRatio float64       `json:"aspect_ratio"`
+	TopLeft     Region        `json:"top_left"`
+	Center      Region        `json:"center"`
+	Players     Region        `json:"players"`
+	Stars       StarRowLayout `json:"stars"`
+	Panel       PanelLayout   `json:"panel"`
+}
+
+// loadLayouts reads layout profiles from path, or the built-in profiles when
+// path is empty.
+func loadLayouts(path string) ([]Layout, error) {
+	data := defaultLayoutsJSON
+	if path != "" {
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read layouts file: %w", err)
+		}
+	}
+
+	var layouts []Layout
+	if err := json.Unmarshal(data, &layouts); err != nil {
+		return nil, fmt.Errorf("failed to parse layouts: %w", err)
+	}
+	if len(layouts) == 0 {
+		return nil, fmt.Errorf("no layout profiles defined")
+	}
+	for _, l := range layouts {
+		if l.Name == "" {
+			return nil, fmt.Errorf("layout profile without a name")
+		}
+		if l.AspectRatio <= 0 {
+			return nil, fmt.Errorf("layout %q has no aspect_ratio", l.Name)
+		}
+	}
+	return layouts, nil
+}
+
+// findLayout returns the profile with the given name.
+func findLayout(layouts []Layout, name string) (Layout, bool) {
+	for _, l := range layouts {
+		if strings.EqualFold(l.Name, name) {
+			return l, true
+		}
+	}
+	return Layout{}, false
+}
+
+// closestLayout returns the profile whose aspect ratio is nearest to the image's.
+func closestLayout(layouts []Layout, width, height int) Layout {
+	if len(layouts) == 0 || width == 0 || height == 0 {
+		return Layout{}
+	}
+
+	aspect := float64(width) / float64(height)
+	best := layouts[0]
+	for _, l := range layouts[1:] {
+		if math.Abs(l.AspectRatio-aspect) < math.Abs(best.AspectRatio-aspect) {
+			best = l
+		}
+	}
+	return best
+}
+
+// layoutFor returns the configured layout, or the closest one by aspect ratio
+// when the parser is set to pick automatically.
+func (p *Parser) layoutFor(img image.Image) Layout {
+	if p.layoutName != "" && p.layoutName != LayoutAuto {
+		if l, ok := findLayout(p.layouts, p.layoutName); ok {
+			return l
+		}
+	}
+	bounds := img.Bounds()
+	return closestLayout(p.layouts, bounds.Dx(), bounds.Dy())
+}
+
+// rect converts the region to pixel coordinates within bounds.
+func (r Region) rect(bounds image.Rectangle) image.Rectangle {
+	width := bounds.Dx()
+	height := bounds.Dy()
+	return image.Rect(
+		bounds.Min.X+pctOf(width, r.Left),
+		bounds.Min.Y+pctOf(height, r.Top),
+		bounds.Min.X+pctOf(width, r.Right),
+		bounds.Min.Y+pctOf(height, r.Bottom),
+	)
+}
+
+// cropRegion crops the region out of img.
+func cropRegion(img image.Image, r Region) image.Image {
+	rect := r.rect(img.Bounds())
+	return cropImage(img, rect.Min.X, rect.Min.Y, rect.Max.X, rect.Max.Y)
+}
@@ -0,0 +1,154 @@
+package parser
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadLayouts_BuiltIn(t *testing.T) {
+	layouts, err := loadLayouts("")
+	require.NoError(t, err)
+
+	for _, name := range []string{"16:9", "16:10", "21:9", "32:9", "4:3"} {
+		l, ok := findLayout(layouts, name)
+		require.True(t, ok, "built-in layout %q should exist", name)
+		assert.Greater(t, l.AspectRatio, 0.0)
+		assert.Less(t, l.TopLeft.Left, l.TopLeft.Right)
+		assert.Less(t, l.Center.Left, l.Center.Right)
+		assert.Greater(t, l.Panel.Width, 0.0)
+		assert.Equal(t, 7, l.Stars.Count)
+	}
+}
+
+func TestLoadLayouts_File(t *testing.T) {
+	testCases := []struct {
+		name      string
+		contents  string
+		wantNames []string
+		wantError bool
+	}{
+		{
+			name:      "custom profile",
+			contents:  `[{"name": "steam-deck", "aspect_ratio": 1.6, "top_left": {"left": 5, "top": 0, "right": 45, "bottom": 22}}]`,
+			wantNames: []string{"steam-deck"},
+		},
+		{
+			name:      "invalid json",
+			contents:  `{not json`,
+			wantError: true,
+		},
+		{
+			name:      "empty list",
+			contents:  `[]`,
+			wantError: true,
+		},
+		{
+			name:      "missing name",
+			contents:  `[{"aspect_ratio": 1.78}]`,
+			wantError: true,
+		},
+		{
+			name:      "missing aspect ratio",
+			contents:  `[{"name": "broken"}]`,
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "layouts.json")
+			require.NoError(t, os.WriteFile(path, []byte(tc.contents), 0644))
+
+			layouts, err := loadLayouts(path)
+			if tc.wantError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			var names []string
+			for _, l := range layouts {
+				names = append(names, l.Name)
+			}
+			assert.Equal(t, tc.wantNames, names)
+		})
+	}
+}
+
+func TestLoadLayouts_MissingFile(t *testing.T) {
+	_, err := loadLayouts(filepath.Join(t.TempDir(), "nonexistent.json"))
+	assert.Error(t, err)
+}
+
+func TestClosestLayout(t *testing.T) {
+	layouts, err := loadLayouts("")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		width, height int
+		expected      string
+	}{
+		{1920, 1080, "16:9"},
+		{1280, 720, "16:9"},
+		{1920, 1200, "16:10"},
+		{1728, 1080, "16:10"},
+		{2560, 1080, "21:9"},
+		{3440, 1440, "21:9"},
+		{1920, 810, "21:9"},
+		{3840, 1080, "32:9"},
+		{1440, 1080, "4:3"},
+	}
+
+	for _, tc := range testCases {
+		l := closestLayout(layouts, tc.width, tc.height)
+		assert.Equal(t, tc.expected, l.Name, "%dx%d", tc.width, tc.height)
+	}
+}
+
+func TestLayoutFor(t *testing.T) {
+	parser := newLayoutOnlyParser(t)
+	img := createTestImage(1920, 1080)
+
+	assert.Equal(t, "16:9", parser.layoutFor(img).Name)
+
+	parser.layoutName = LayoutAuto
+	assert.Equal(t, "16:9", parser.layoutFor(img).Name)
+
+	parser.layoutName = "21:9"
+	assert.Equal(t, "21:9", parser.layoutFor(img).Name, "a configured profile wins over the image shape")
+}
+
+func TestRegionRect(t *testing.T) {
+	r := Region{Left: 7, Top: 0, Right: 40, Bottom: 20}
+
+	assert.Equal(t, image.Rect(134, 0, 768, 216), r.rect(image.Rect(0, 0, 1920, 1080)))
+	assert.Equal(t, image.Rect(110, 50, 160, 70), r.rect(image.Rect(100, 50, 250, 150)))
+}
+
+func TestNewParser_UnknownLayout(t *testing.T) {
+	parser, err := NewParser(1920, 1080, WithLayout("not-a-layout"))
+	assert.Error(t, err)
+	assert.Nil(t, parser)
+}
+
+func TestNewParser_InvalidLayoutsFile(t *testing.T) {
+	parser, err := NewParser(1920, 1080, WithLayoutsFile(filepath.Join(t.TempDir(), "nonexistent.json")))
+	assert.Error(t, err)
+	assert.Nil(t, parser)
+}
+
+// newLayoutOnlyParser returns a parser with the built-in layouts and
+// preprocessing but no OCR client, for tests of the image geometry code.
+func newLayoutOnlyParser(t *testing.T) *Parser {
+	t.Helper()
+
+	layouts, err := loadLayouts("")
+	require.NoError(t, err)
+	pipelines, err := loadPipelines("")
+	require.NoError(t, err)
+	return &Parser{maxWidth: 1920, maxHeight: 1080, layouts: layouts, pipelines: pipelines}
+}
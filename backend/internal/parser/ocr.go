@@ -0,0 +1,131 @@
+package parser
+
+import (
+	"fmt"
+	"image"
+	"strings"
+
+	"cloneheroer/internal/db"
+)
+
+// ocrLine is one recognized line of text with its Tesseract confidence (0-100)
+// and where it was found in the OCR'd image.
+type ocrLine struct {
+	Text       string
+	Confidence float64
+	Box        image.Rectangle
+}
+
+// ocrResult is the OCR output for one region.
+type ocrResult struct {
+	Text       string
+	Lines      []ocrLine
+	Confidence float64 // mean line confidence
+	Height     int     // height of the OCR'd image, 0 when unknown
+}
+
+// lineConfidence returns the confidence of the recognized line matching line,
+// falling back to the region's confidence when no line matches.
+func (r ocrResult) lineConfidence(line string) float64 {
+	want := normalizeSpace(line)
+	for _, l := range r.Lines {
+		if normalizeSpace(l.Text) == want {
+			return l.Confidence
+		}
+	}
+	for _, l := range r.Lines {
+		if want != "" && strings.Contains(normalizeSpace(l.Text), want) {
+			return l.Confidence
+		}
+	}
+	return r.Confidence
+}
+
+// newOCRResult builds a result from recognized lines.
+func newOCRResult(lines []ocrLine) ocrResult {
+	res := ocrResult{}
+	texts := make([]string, 0, len(lines))
+	var sum float64
+	for _, l := range lines {
+		l.Text = strings.TrimSpace(l.Text)
+		if l.Text == "" {
+			continue
+		}
+		res.Lines = append(res.Lines, l)
+		texts = append(texts, l.Text)
+		sum += l.Confidence
+	}
+	res.Text = strings.Join(texts, "\n")
+	if len(res.Lines) > 0 {
+		res.Confidence = sum / float64(len(res.Lines))
+	}
+	return res
+}
+
+// textResult wraps plain text with no confidence information.
+func textResult(text string) ocrResult {
+	res := ocrResult{Text: text}
+	for _, line := range filterEmpty(strings.Split(text, "\n")) {
+		res.Lines = append(res.Lines, ocrLine{Text: strings.TrimSpace(line)})
+	}
+	return res
+}
+
+// parseRun collects per-image state while one screenshot is parsed.
+type parseRun struct {
+	confidence db.OCRConfidence
+	debug      *debugDump // nil unless debug output was asked for
+}
+
+func newParseRun() *parseRun {
+	return &parseRun{
+		confidence: db.OCRConfidence{
+			Regions: map[string]float64{},
+			Fields:  map[string]float64{},
+		},
+	}
+}
+
+// recordRegion stores the confidence of an OCR'd region. A nil run is a no-op.
+func (r *parseRun) recordRegion(region string, res ocrResult) {
+	if r == nil || len(res.Lines) == 0 {
+		return
+	}
+	r.confidence.Regions[region] = res.Confidence
+}
+
+// saveRegion dumps a region for debugging. A nil run or one without debug
+// output is a no-op.
+func (r *parseRun) saveRegion(region string, crop, preprocessed image.Image, res ocrResult) {
+	if r == nil {
+		return
+	}
+	r.debug.saveRegion(region, crop, preprocessed, res)
+}
+
+// recordField stores the confidence of a parsed field. A nil run is a no-op.
+func (r *parseRun) recordField(field string, confidence float64) {
+	if r == nil {
+		return
+	}
+	r.confidence.Fields[field] = confidence
+}
+
+// forgetField drops a field whose value didn't come from OCR after all.
+func (r *parseRun) forgetField(field string) {
+	if r == nil {
+		return
+	}
+	delete(r.confidence.Fields, field)
+}
+
+// recordFields stores field confidences under a prefix such as "players.0".
+func (r *parseRun) recordFields(prefix string, fields map[string]float64) {
+	for name, c := range fields {
+		r.recordField(fmt.Sprintf("%s.%s", prefix, name), c)
+	}
+}
+
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
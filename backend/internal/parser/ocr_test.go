@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOCRResult(t *testing.T) {
+	res := newOCRResult([]ocrLine{
+		{Text: "Made Your Mark\n", Confidence: 90},
+		{Text: "  ", Confidence: 10},
+		{Text: "Wage War", Confidence: 70},
+	})
+
+	assert.Equal(t, "Made Your Mark\nWage War", res.Text)
+	assert.Len(t, res.Lines, 2)
+	assert.InDelta(t, 80, res.Confidence, 0.001)
+}
+
+func TestNewOCRResult_Empty(t *testing.T) {
+	res := newOCRResult(nil)
+
+	assert.Empty(t, res.Text)
+	assert.Zero(t, res.Confidence)
+}
+
+func TestOCRResult_LineConfidence(t *testing.T) {
+	res := newOCRResult([]ocrLine{
+		{Text: "1,234,567", Confidence: 95},
+		{Text: "Expert  99.5%", Confidence: 60},
+	})
+
+	testCases := []struct {
+		name     string
+		line     string
+		expected float64
+	}{
+		{name: "exact line", line: "1,234,567", expected: 95},
+		{name: "whitespace differences", line: " Expert 99.5% ", expected: 60},
+		{name: "part of a line", line: "99.5%", expected: 60},
+		{name: "unknown line falls back to region", line: "Bass", expected: 77.5},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.InDelta(t, tc.expected, res.lineConfidence(tc.line), 0.001)
+		})
+	}
+}
+
+func TestParseRun_Records(t *testing.T) {
+	run := newParseRun()
+	run.recordRegion("center", newOCRResult([]ocrLine{{Text: "123", Confidence: 88}}))
+	run.recordRegion("top_left", ocrResult{})
+	run.recordField("total_score", 88)
+	run.recordFields("players.1", map[string]float64{"score": 42})
+
+	assert.Equal(t, map[string]float64{"center": 88}, run.confidence.Regions)
+	assert.Equal(t, map[string]float64{"total_score": 88, "players.1.score": 42}, run.confidence.Fields)
+
+	lowest := run.confidence.Lowest()
+	require.NotNil(t, lowest)
+	assert.Equal(t, 42.0, *lowest)
+}
+
+func TestParseRun_NilIsNoop(t *testing.T) {
+	var run *parseRun
+
+	assert.NotPanics(t, func() {
+		run.recordRegion("center", textResult("123"))
+		run.recordField("total_score", 50)
+		run.recordFields("players.0", map[string]float64{"score": 50})
+	})
+}
+
+func TestParsePlayerColumn_Confidence(t *testing.T) {
+	name := newOCRResult([]ocrLine{{Text: "mxygem", Confidence: 91}})
+	summary := newOCRResult([]ocrLine{
+		{Text: "Expert", Confidence: 85},
+		{Text: "98.25%", Confidence: 80},
+		{Text: "123,456", Confidence: 45},
+	})
+	stats := newOCRResult([]ocrLine{
+		{Text: "Notes Missed 3", Confidence: 70},
+		{Text: "Best Streak 512", Confidence: 75},
+	})
+
+	player, confidence := parsePlayerColumn(panelOCR{name: name, summary: summary, stats: stats})
+
+	assert.Equal(t, int64(123456), player.Score)
+	assert.Equal(t, map[string]float64{
+		"name":         91,
+		"difficulty":   85,
+		"accuracy":     80,
+		"score":        45,
+		"notes_missed": 70,
+		"best_streak":  75,
+	}, confidence)
+}
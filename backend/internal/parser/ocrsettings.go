@@ -0,0 +1,99 @@
+package parser
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/otiai10/gosseract/v2"
+)
+
+// DefaultOCRLanguage is used by regions that don't set a language.
+const DefaultOCRLanguage = "eng"
+
+//go:embed ocr.json
+var defaultOCRSettingsJSON []byte
+
+// OCRSettings configures Tesseract for one kind of region. Zero values keep
+// Tesseract's defaults.
+type OCRSettings struct {
+	// Language is the traineddata to use, like "eng" or "eng+deu" (default eng).
+	Language string `json:"language,omitempty"`
+	// PSM is the page segmentation mode, e.g. 6 for a block of text or 7 for a
+	// single line (default 6).
+	PSM int `json:"psm,omitempty"`
+	// Whitelist limits recognition to these characters.
+	Whitelist string `json:"whitelist,omitempty"`
+	// DPI tells Tesseract the crop's resolution, since PNGs in memory carry none.
+	DPI int `json:"dpi,omitempty"`
+}
+
+// language returns the configured language or the default.
+func (s OCRSettings) language() string {
+	if s.Language == "" {
+		return DefaultOCRLanguage
+	}
+	return s.Language
+}
+
+// pageSegMode returns the configured PSM or single block, Tesseract's API default.
+func (s OCRSettings) pageSegMode() gosseract.PageSegMode {
+	if s.PSM == 0 {
+		return gosseract.PSM_SINGLE_BLOCK
+	}
+	return gosseract.PageSegMode(s.PSM)
+}
+
+func (s OCRSettings) validate() error {
+	if s.PSM < 0 || s.PSM > 13 {
+		return fmt.Errorf("psm must be between 1 and 13, or 0 for the default")
+	}
+	if s.DPI != 0 && (s.DPI < 70 || s.DPI > 2400) {
+		return fmt.Errorf("dpi must be between 70 and 2400")
+	}
+	return nil
+}
+
+// WithOCRSettingsFile overrides the built-in per-region OCR settings with the
+// ones defined in a JSON file.
+func WithOCRSettingsFile(path string) Option {
+	return func(p *Parser) {
+		p.ocrSettingsFile = path
+	}
+}
+
+// loadOCRSettings reads per-region OCR settings from path on top of the
+// built-in ones. A region in the file replaces its default entirely, so
+// {"center": {}} turns the center whitelist off. Regions the parser doesn't
+// OCR are rejected, since a misspelt one would otherwise change nothing.
+func loadOCRSettings(path string) (map[string]OCRSettings, error) {
+	settings := map[string]OCRSettings{}
+	if err := json.Unmarshal(defaultOCRSettingsJSON, &settings); err != nil {
+		return nil, fmt.Errorf("failed to parse built-in OCR settings: %w", err)
+	}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read OCR settings file: %w", err)
+		}
+		var overrides map[string]OCRSettings
+		if err := json.Unmarshal(data, &overrides); err != nil {
+			return nil, fmt.Errorf("failed to parse OCR settings file: %w", err)
+		}
+		for name, s := range overrides {
+			if _, ok := settings[name]; !ok {
+				return nil, fmt.Errorf("unknown OCR settings region %q", name)
+			}
+			settings[name] = s
+		}
+	}
+
+	for name, s := range settings {
+		if err := s.validate(); err != nil {
+			return nil, fmt.Errorf("OCR settings %q: %w", name, err)
+		}
+	}
+	return settings, nil
+}
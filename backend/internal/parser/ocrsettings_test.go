@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/otiai10/gosseract/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOCRSettings_BuiltIn(t *testing.T) {
+	settings, err := loadOCRSettings("")
+	require.NoError(t, err)
+
+	for _, name := range []string{PipelineCenter, PipelinePlayerScore, PipelinePlayerAccuracy, PipelinePlayerStatsValues} {
+		assert.NotEmpty(t, settings[name].Whitelist, "built-in settings for %q should only read digits", name)
+	}
+	// extractCenterInfo falls back to a "Stars: 5" line when there are no icons
+	for _, c := range "Stars:5" {
+		assert.Contains(t, settings[PipelineCenter].Whitelist, string(c))
+	}
+	assert.Equal(t, gosseract.PSM_SINGLE_LINE, settings[PipelinePlayerName].pageSegMode())
+	assert.Equal(t, DefaultOCRLanguage, settings[PipelineTopLeft].language())
+}
+
+func TestLoadOCRSettings_File(t *testing.T) {
+	testCases := []struct {
+		name      string
+		contents  string
+		check     func(t *testing.T, settings map[string]OCRSettings)
+		wantError bool
+	}{
+		{
+			name:     "override one region",
+			contents: `{"player_name": {"language": "eng+jpn", "psm": 8}}`,
+			check: func(t *testing.T, settings map[string]OCRSettings) {
+				assert.Equal(t, OCRSettings{Language: "eng+jpn", PSM: 8}, settings[PipelinePlayerName])
+				assert.NotEmpty(t, settings[PipelineCenter].Whitelist, "regions not in the file keep their default")
+			},
+		},
+		{
+			name:     "empty settings use Tesseract's defaults",
+			contents: `{"center": {}}`,
+			check: func(t *testing.T, settings map[string]OCRSettings) {
+				assert.Empty(t, settings[PipelineCenter].Whitelist)
+				assert.Equal(t, gosseract.PSM_SINGLE_BLOCK, settings[PipelineCenter].pageSegMode())
+			},
+		},
+		{
+			name:      "psm out of range",
+			contents:  `{"center": {"psm": 14}}`,
+			wantError: true,
+		},
+		{
+			name:      "unknown region",
+			contents:  `{"centre": {"psm": 7}}`,
+			wantError: true,
+		},
+		{
+			name:      "dpi too low",
+			contents:  `{"center": {"dpi": 10}}`,
+			wantError: true,
+		},
+		{
+			name:      "invalid json",
+			contents:  `[`,
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "ocr.json")
+			require.NoError(t, os.WriteFile(path, []byte(tc.contents), 0644))
+
+			settings, err := loadOCRSettings(path)
+			if tc.wantError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			tc.check(t, settings)
+		})
+	}
+}
+
+func TestLoadOCRSettings_MissingFile(t *testing.T) {
+	_, err := loadOCRSettings(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestNewParser_InvalidOCRSettingsFile(t *testing.T) {
+	parser, err := NewParser(1920, 1080, WithOCRSettingsFile(filepath.Join(t.TempDir(), "nonexistent.json")))
+	assert.Error(t, err)
+	assert.Nil(t, parser)
+}
@@ -1,9 +1,13 @@
 package parser
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"image"
+	_ "image/jpeg"
 	"image/png"
+	"io"
 	"log"
 	"os"
 	"os/exec"
@@ -17,14 +21,75 @@ import (
 
 	"github.com/otiai10/gosseract/v2"
 	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	nonDigitRe    = regexp.MustCompile(`[^\d]`)
+	firstIntRe    = regexp.MustCompile(`(\d+)`)
+	firstNumberRe = regexp.MustCompile(`(\d+\.?\d*)`)
+	percentRe     = regexp.MustCompile(`(\d+\.?\d*)%`)
+	numericRe     = regexp.MustCompile(`^\d+([,\s]\d+)*$`)
+	// Clone Hero stamps screenshot names with yyyyMMddHHmmss
+	filenameStampRe = regexp.MustCompile(`(\d{14})`)
 )
 
 // Parser extracts score data from Clone Hero screenshot images.
+// It is safe for concurrent use; OCR runs on a pool of Tesseract clients.
 type Parser struct {
-	client    *gosseract.Client
-	closed    bool
-	maxWidth  int
-	maxHeight int
+	pool        *clientPool
+	poolSize    int
+	maxWidth    int
+	maxHeight   int
+	instruments []instrumentTemplate
+	layouts     []Layout
+	layoutName  string
+	layoutsFile string
+
+	pipelines      map[string]Pipeline
+	preprocessFile string
+
+	ocrSettings     map[string]OCRSettings
+	ocrSettingsFile string
+
+	debug    bool
+	debugDir string
+
+	timezone *time.Location
+}
+
+// Option configures optional Parser behaviour.
+type Option func(*Parser)
+
+// WithLayout forces a named layout profile instead of picking one per image.
+// An empty name or LayoutAuto keeps automatic selection.
+func WithLayout(name string) Option {
+	return func(p *Parser) {
+		p.layoutName = name
+	}
+}
+
+// WithLayoutsFile loads layout profiles from a JSON file instead of the built-in ones.
+func WithLayoutsFile(path string) Option {
+	return func(p *Parser) {
+		p.layoutsFile = path
+	}
+}
+
+// WithPoolSize sets how many Tesseract clients the parser keeps, which is how
+// many crops can be OCR'd at once. The default is 1.
+func WithPoolSize(size int) Option {
+	return func(p *Parser) {
+		p.poolSize = size
+	}
+}
+
+// WithPreprocessFile overrides the built-in preprocessing pipelines with the
+// ones defined in a JSON file.
+func WithPreprocessFile(path string) Option {
+	return func(p *Parser) {
+		p.preprocessFile = path
+	}
 }
 
 // findTessdataPrefix attempts to find the Tesseract data directory.
@@ -83,7 +148,38 @@ func findTessdataPrefix() string {
 }
 
 // NewParser creates a new parser instance.
-func NewParser(maxWidth, maxHeight int) (*Parser, error) {
+func NewParser(maxWidth, maxHeight int, opts ...Option) (*Parser, error) {
+	p := &Parser{
+		maxWidth:  maxWidth,
+		maxHeight: maxHeight,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	layouts, err := loadLayouts(p.layoutsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load layout profiles: %w", err)
+	}
+	if p.layoutName != "" && p.layoutName != LayoutAuto {
+		if _, ok := findLayout(layouts, p.layoutName); !ok {
+			return nil, fmt.Errorf("unknown layout profile %q", p.layoutName)
+		}
+	}
+	p.layouts = layouts
+
+	pipelines, err := loadPipelines(p.preprocessFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load preprocessing pipelines: %w", err)
+	}
+	p.pipelines = pipelines
+
+	ocrSettings, err := loadOCRSettings(p.ocrSettingsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OCR settings: %w", err)
+	}
+	p.ocrSettings = ocrSettings
+
 	// Set TESSDATA_PREFIX if not already set
 	if os.Getenv("TESSDATA_PREFIX") == "" {
 		prefix := findTessdataPrefix()
@@ -97,56 +193,85 @@ func NewParser(maxWidth, maxHeight int) (*Parser, error) {
 		}
 	}
 
-	client := gosseract.NewClient()
-	if err := client.SetLanguage("eng"); err != nil {
-		return nil, fmt.Errorf("failed to set OCR language (check TESSDATA_PREFIX): %w", err)
+	pool, err := newClientPool(p.poolSize)
+	if err != nil {
+		return nil, err
 	}
-	return &Parser{
-		client:    client,
-		maxWidth:  maxWidth,
-		maxHeight: maxHeight,
-	}, nil
+
+	// Instrument detection is optional; without the icon sheet players keep an empty instrument
+	var instruments []instrumentTemplate
+	if iconsPath := findInstrumentIconsPath(); iconsPath != "" {
+		instruments, err = loadInstrumentTemplates(iconsPath)
+		if err != nil {
+			log.Printf("warning: failed to load instrument icons from %q: %v", iconsPath, err)
+		}
+	} else {
+		log.Printf("warning: instrument icon sheet not found; set INSTRUMENT_ICONS_PATH to img/instrum-icons.png to enable instrument detection")
+	}
+
+	p.pool = pool
+	p.instruments = instruments
+	return p, nil
 }
 
-// Close releases resources.
+// Close releases resources, waiting for in-flight OCR to finish.
 func (p *Parser) Close() error {
-	if p.closed {
-		return nil // Already closed, no-op
-	}
-	if p.client != nil {
-		err := p.client.Close()
-		p.closed = true
-		return err
+	if p.pool == nil {
+		return nil
 	}
-	p.closed = true
-	return nil
+	return p.pool.close()
 }
 
 // ParseImage extracts score data from a screenshot image file.
 func (p *Parser) ParseImage(imagePath string) (*db.CreateScoreData, error) {
-	// Parse timestamp from filename
-	// Format: clonehero-Artist-20251212052231.png or 20251212052231.png
-	filename := filepath.Base(imagePath)
-	createdAt, err := parseTimestampFromFilename(filename)
+	data, _, err := p.parseImage(imagePath, p.debug)
+	return data, err
+}
+
+// ParseImageDebug parses one image like ParseImage and also writes every
+// region crop, preprocessed crop, the OCR text and the parsed JSON to a folder
+// named after the image under the debug directory. It returns that folder.
+func (p *Parser) ParseImageDebug(imagePath string) (*db.CreateScoreData, string, error) {
+	return p.parseImage(imagePath, true)
+}
+
+// parseImage parses imagePath, dumping debug output when debug is set. The
+// returned folder is empty when nothing was dumped.
+func (p *Parser) parseImage(imagePath string, debug bool) (*db.CreateScoreData, string, error) {
+	createdAt, createdAtSource, err := p.captureTime(imagePath)
 	if err != nil {
-		// If we can't parse timestamp, use file modification time
-		info, err := os.Stat(imagePath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get file info: %w", err)
-		}
-		createdAt = info.ModTime()
+		return nil, "", err
 	}
 
 	// Load and preprocess image
 	img, err := p.loadImage(imagePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load image: %w", err)
+		return nil, "", fmt.Errorf("failed to load image: %w", err)
 	}
 
 	// Extract text from different regions
-	songName, artist, charter := p.extractTopLeftInfo(img)
-	totalScore, stars := p.extractCenterInfo(img)
-	players := p.extractPlayers(img)
+	run := newParseRun()
+	if debug {
+		dump, err := newDebugDump(p.debugDirOrDefault(), imagePath)
+		if err != nil {
+			log.Printf("warning: debug output disabled for %q: %v", imagePath, err)
+		} else {
+			run.debug = dump
+		}
+	}
+	artist, songName, charter := p.extractTopLeftInfo(run, img)
+	totalScore, stars := p.extractCenterInfo(run, img)
+	players := p.extractPlayers(run, img)
+
+	// The star icons are more reliable than any star text OCR picked up
+	goldStars := false
+	if run.debug != nil {
+		run.debug.saveCrop("stars", cropStarRow(img, p.layoutFor(img).Stars))
+	}
+	if counted, gold, ok := p.countStars(img); ok {
+		stars, goldStars = counted, gold
+		run.forgetField("stars_achieved")
+	}
 
 	// Check if OCR failed to extract meaningful data
 	hasData := false
@@ -193,54 +318,71 @@ func (p *Parser) ParseImage(imagePath string) (*db.CreateScoreData, error) {
 		log.Printf("%s%s════════════════════════════════════════════════════════════════════════════════%s", red, bold, reset)
 	}
 
-	return &db.CreateScoreData{
-		Artist:        artist,
-		SongName:      songName,
-		Charter:       charter,
-		TotalScore:    totalScore,
-		StarsAchieved: stars,
-		Players:       players,
-		CreatedAt:     createdAt,
-	}, nil
+	data := &db.CreateScoreData{
+		Artist:          artist,
+		SongName:        songName,
+		Charter:         charter,
+		TotalScore:      totalScore,
+		StarsAchieved:   stars,
+		GoldStars:       goldStars,
+		Players:         players,
+		CreatedAt:       createdAt,
+		CreatedAtSource: createdAtSource,
+		Confidence:      &run.confidence,
+	}
+	if run.debug == nil {
+		return data, "", nil
+	}
+	run.debug.saveResult(data)
+	log.Printf("wrote debug output for %q to %q", imagePath, run.debug.dir)
+	return data, run.debug.dir, nil
+}
+
+// debugDirOrDefault returns the configured debug directory or DefaultDebugDir.
+func (p *Parser) debugDirOrDefault() string {
+	if p.debugDir == "" {
+		return DefaultDebugDir
+	}
+	return p.debugDir
 }
 
 // parseTimestampFromFilename extracts timestamp from filename.
 // Supports formats: "20251212052231" or "clonehero-Artist-20251212052231.png"
-func parseTimestampFromFilename(filename string) (time.Time, error) {
+// Clone Hero stamps local time, so it's read in loc.
+func parseTimestampFromFilename(filename string, loc *time.Location) (time.Time, error) {
 	// Remove extension
 	filename = strings.TrimSuffix(filename, filepath.Ext(filename))
 
 	// Try to find 14-digit timestamp (yyyyMMddHHmmss)
-	re := regexp.MustCompile(`(\d{14})`)
-	matches := re.FindStringSubmatch(filename)
+	matches := filenameStampRe.FindStringSubmatch(filename)
 	if len(matches) < 2 {
 		return time.Time{}, fmt.Errorf("no timestamp found in filename")
 	}
 
 	timestamp := matches[1]
 	layout := "20060102150405"
-	return time.Parse(layout, timestamp)
+	return time.ParseInLocation(layout, timestamp, loc)
 }
 
-// loadImage loads a PNG image file and returns it as an image.Image.
+// loadImage loads a PNG, JPEG or WebP image file and returns it as an image.Image.
+// The decoder is picked by sniffing the file contents, not by extension.
 // It resizes the image if it exceeds the configured maximum dimensions.
 func (p *Parser) loadImage(path string) (image.Image, error) {
-	// Only accept PNG files
-	ext := strings.ToLower(filepath.Ext(path))
-	if ext != ".png" {
-		return nil, fmt.Errorf("only PNG files are supported, got: %s", ext)
-	}
-
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
 
-	// Decode PNG
-	img, err := png.Decode(file)
+	img, format, err := image.Decode(file)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode PNG: %w", err)
+		if errors.Is(err, image.ErrFormat) {
+			return nil, fmt.Errorf("unsupported image format (expected PNG, JPEG or WebP): %w", err)
+		}
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if ext := strings.ToLower(filepath.Ext(path)); !extensionMatchesFormat(ext, format) {
+		log.Printf("warning: %s has extension %q but contains %s data", filepath.Base(path), ext, format)
 	}
 
 	// Resize if too large (OCR works better on smaller images)
@@ -266,17 +408,8 @@ func (p *Parser) loadImage(path string) (image.Image, error) {
 }
 
 // extractTopLeftInfo extracts artist, song name, and charter from top left of image.
-func (p *Parser) extractTopLeftInfo(img image.Image) (artist, songName string, charter string) {
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-
-	left := width * 7 / 100
-	top := 0
-	right := width * 40 / 100
-	bottom := height * 20 / 100
-
-	region := cropImage(img, left, top, right, bottom)
+func (p *Parser) extractTopLeftInfo(run *parseRun, img image.Image) (artist, songName string, charter string) {
+	region := cropRegion(img, p.layoutFor(img).TopLeft)
 
 	// Debug: log region size
 	regionBounds := region.Bounds()
@@ -285,7 +418,8 @@ func (p *Parser) extractTopLeftInfo(img image.Image) (artist, songName string, c
 		return "", "", ""
 	}
 
-	text := p.extractText(region)
+	res := p.ocrRegion(run, "top_left", PipelineTopLeft, region)
+	text := res.Text
 	if text == "" {
 		log.Printf("warning: OCR returned empty text for top-left region (%dx%d)", regionBounds.Dx(), regionBounds.Dy())
 	}
@@ -298,12 +432,15 @@ func (p *Parser) extractTopLeftInfo(img image.Image) (artist, songName string, c
 
 	if len(lines) > 0 {
 		songName = strings.TrimSpace(lines[0])
+		run.recordField("song_name", res.lineConfidence(lines[0]))
 	}
 	if len(lines) > 1 {
 		artist = strings.TrimSpace(lines[1])
+		run.recordField("artist", res.lineConfidence(lines[1]))
 	}
 	if len(lines) > 2 {
 		charter = strings.TrimSpace(lines[2])
+		run.recordField("charter", res.lineConfidence(lines[2]))
 	}
 
 	if artist == "" || songName == "" || charter == "" {
@@ -314,19 +451,10 @@ func (p *Parser) extractTopLeftInfo(img image.Image) (artist, songName string, c
 }
 
 // extractCenterInfo extracts total score and stars from center top of image.
-func (p *Parser) extractCenterInfo(img image.Image) (totalScore int64, stars int) {
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-
-	// Center region: middle 40% of width, first 25% of height
-	left := width * 30 / 100
-	top := 0
-	right := width * 70 / 100
-	bottom := height * 25 / 100
-
-	region := cropImage(img, left, top, right, bottom)
-	text := p.extractText(region)
+func (p *Parser) extractCenterInfo(run *parseRun, img image.Image) (totalScore int64, stars int) {
+	region := cropRegion(img, p.layoutFor(img).Center)
+	res := p.ocrRegion(run, "center", PipelineCenter, region)
+	text := res.Text
 
 	// Look for large numbers (total score) and star indicators
 	lines := strings.Split(strings.TrimSpace(text), "\n")
@@ -335,24 +463,26 @@ func (p *Parser) extractCenterInfo(img image.Image) (totalScore int64, stars int
 	// First large number is likely total score
 	for _, line := range lines {
 		// Remove non-digit characters except commas
-		cleaned := regexp.MustCompile(`[^\d]`).ReplaceAllString(line, "")
+		cleaned := nonDigitRe.ReplaceAllString(line, "")
 		if cleaned != "" {
 			if val, err := strconv.ParseInt(cleaned, 10, 64); err == nil {
 				totalScore = val
+				run.recordField("total_score", res.lineConfidence(line))
 				break
 			}
 		}
 	}
 
-	// Look for star count (usually "★" or "Stars: X" or just a number)
+	// Look for star count (usually "★" or "Stars: X" or just a number). This
+	// only sticks when countStars finds no star icons.
 	for _, line := range lines {
 		lower := strings.ToLower(line)
 		if strings.Contains(lower, "star") {
-			re := regexp.MustCompile(`(\d+)`)
-			matches := re.FindStringSubmatch(line)
+			matches := firstIntRe.FindStringSubmatch(line)
 			if len(matches) > 1 {
 				if val, err := strconv.Atoi(matches[1]); err == nil {
 					stars = val
+					run.recordField("stars_achieved", res.lineConfidence(line))
 					break
 				}
 			}
@@ -361,6 +491,7 @@ func (p *Parser) extractCenterInfo(img image.Image) (totalScore int64, stars int
 		if len(strings.TrimSpace(line)) == 1 {
 			if val, err := strconv.Atoi(strings.TrimSpace(line)); err == nil && val >= 0 && val <= 7 {
 				stars = val
+				run.recordField("stars_achieved", res.lineConfidence(line))
 				break
 			}
 		}
@@ -370,22 +501,30 @@ func (p *Parser) extractCenterInfo(img image.Image) (totalScore int64, stars int
 }
 
 // extractPlayers extracts player data from the main area of the image.
-// TODO: Instrument detection currently relies on OCR text. For better accuracy,
-// implement template matching using instrum-icons.png to detect instrument icons.
-// This would require loading the reference icons and using image comparison/template matching.
-func (p *Parser) extractPlayers(img image.Image) []db.Player {
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
+// Each player panel is located and OCR'd on its own so stats stay with their
+// player; players come back in screen order.
+func (p *Parser) extractPlayers(run *parseRun, img image.Image) []db.Player {
+	panel := p.layoutFor(img).Panel
+	columns := findPlayerColumns(img, panel)
+	if len(columns) == 0 {
+		log.Printf("warning: no player columns found, falling back to full-width player parsing")
+		return p.extractPlayersFromText(run, img)
+	}
 
-	// Main player area: full width, from 25% to 90% of height
-	left := 0
-	top := height * 25 / 100
-	right := width
-	bottom := height * 90 / 100
+	players := make([]db.Player, 0, len(columns))
+	for i, column := range columns {
+		players = append(players, p.extractPlayerColumn(run, img, column, panel, i))
+	}
+	return players
+}
 
-	region := cropImage(img, left, top, right, bottom)
-	text := p.extractText(region)
+// extractPlayersFromText parses players out of one OCR pass over the whole
+// player area. It's used when no player panels can be found.
+// Instruments come from template matching the icon row against instrum-icons.png.
+func (p *Parser) extractPlayersFromText(run *parseRun, img image.Image) []db.Player {
+	region := cropRegion(img, p.layoutFor(img).Players)
+	res := p.ocrRegion(run, "players", PipelinePlayers, region)
+	text := res.Text
 
 	// Parse player data from text
 	// This is a simplified parser - may need refinement based on actual screenshot format
@@ -395,6 +534,7 @@ func (p *Parser) extractPlayers(img image.Image) []db.Player {
 
 	// Group lines into player blocks (heuristic: look for patterns)
 	currentPlayer := db.Player{}
+	confidence := map[string]float64{}
 	for i, line := range lines {
 		line = strings.TrimSpace(line)
 		if line == "" {
@@ -404,6 +544,13 @@ func (p *Parser) extractPlayers(img image.Image) []db.Player {
 		// Try to identify player name (usually first non-numeric line in a block)
 		if currentPlayer.Name == "" && !isNumeric(line) {
 			currentPlayer.Name = line
+			confidence["name"] = res.lineConfidence(line)
+			continue
+		}
+
+		// Players without a chart for their instrument show "No Part" instead of an icon
+		if strings.Contains(strings.ToLower(line), "no part") {
+			currentPlayer.Instrument = db.InstrumentNoPart
 			continue
 		}
 
@@ -414,70 +561,102 @@ func (p *Parser) extractPlayers(img image.Image) []db.Player {
 			strings.Contains(strings.ToLower(line), "expert") {
 			d := normalizeDifficulty(line)
 			currentPlayer.Difficulty = d
+			confidence["difficulty"] = res.lineConfidence(line)
 			continue
 		}
 
 		// Look for score (large number)
 		if isNumeric(line) {
-			cleaned := regexp.MustCompile(`[^\d]`).ReplaceAllString(line, "")
+			cleaned := nonDigitRe.ReplaceAllString(line, "")
 			if len(cleaned) > 3 { // Score is usually a large number
 				if val, err := strconv.ParseInt(cleaned, 10, 64); err == nil {
 					currentPlayer.Score = val
+					confidence["score"] = res.lineConfidence(line)
 				}
 			}
 		}
 
 		// Look for accuracy (percentage)
 		if strings.Contains(line, "%") {
-			re := regexp.MustCompile(`(\d+\.?\d*)%`)
-			matches := re.FindStringSubmatch(line)
+			matches := percentRe.FindStringSubmatch(line)
 			if len(matches) > 1 {
 				if val, err := strconv.ParseFloat(matches[1], 64); err == nil {
 					currentPlayer.Accuracy = val
+					confidence["accuracy"] = res.lineConfidence(line)
+				}
+			}
+		}
+
+		// Look for note counts
+		if strings.Contains(strings.ToLower(line), "total notes") {
+			cleaned := nonDigitRe.ReplaceAllString(line, "")
+			if val, err := strconv.Atoi(cleaned); err == nil {
+				currentPlayer.TotalNotes = val
+				confidence["total_notes"] = res.lineConfidence(line)
+			}
+		}
+		if strings.Contains(strings.ToLower(line), "notes hit") {
+			cleaned := nonDigitRe.ReplaceAllString(line, "")
+			if val, err := strconv.Atoi(cleaned); err == nil {
+				currentPlayer.NotesHit = val
+				confidence["notes_hit"] = res.lineConfidence(line)
+			}
+		}
+
+		// Look for star power phrases and the full combo banner
+		if isStarPowerText(strings.ToLower(line)) {
+			if hit, total, ok := parseStarPower(line); ok {
+				currentPlayer.StarPowerHit, currentPlayer.StarPowerTotal = hit, total
+				confidence["star_power_hit"] = res.lineConfidence(line)
+				if total > 0 {
+					confidence["star_power_total"] = res.lineConfidence(line)
 				}
 			}
 		}
+		if isFullComboText(strings.ToLower(line)) {
+			currentPlayer.FullCombo = true
+		}
 
 		// Look for misses
 		if strings.Contains(strings.ToLower(line), "notes missed") {
-			re := regexp.MustCompile(`(\d+)`)
-			matches := re.FindStringSubmatch(line)
+			matches := firstIntRe.FindStringSubmatch(line)
 			if len(matches) > 1 {
 				if val, err := strconv.Atoi(matches[1]); err == nil {
 					currentPlayer.NotesMissed = val
+					confidence["notes_missed"] = res.lineConfidence(line)
 				}
 			}
 		}
 
 		// Look for combo/best streak
 		if strings.Contains(strings.ToLower(line), "best streak") {
-			re := regexp.MustCompile(`(\d+)`)
-			matches := re.FindStringSubmatch(line)
+			matches := firstIntRe.FindStringSubmatch(line)
 			if len(matches) > 1 {
 				if val, err := strconv.Atoi(matches[1]); err == nil {
 					currentPlayer.BestStreak = val
+					confidence["best_streak"] = res.lineConfidence(line)
 				}
 			}
 		}
 
 		// Look for overhits
 		if strings.Contains(strings.ToLower(line), "overhits") {
-			re := regexp.MustCompile(`(\d+)`)
-			matches := re.FindStringSubmatch(line)
+			matches := firstIntRe.FindStringSubmatch(line)
 			if len(matches) > 1 {
 				if val, err := strconv.Atoi(matches[1]); err == nil {
 					currentPlayer.Overhits = val
+					confidence["overhits"] = res.lineConfidence(line)
 				}
 			}
 		}
 
 		// Look for avg multiplier
 		if strings.Contains(strings.ToLower(line), "avg multiplier") {
-			re := regexp.MustCompile(`(\d+\.?\d*)`)
-			matches := re.FindStringSubmatch(line)
+			matches := firstNumberRe.FindStringSubmatch(line)
 			if len(matches) > 1 {
 				if val, err := strconv.ParseFloat(matches[1], 64); err == nil {
 					currentPlayer.AvgMultiplier = val
+					confidence["avg_multiplier"] = res.lineConfidence(line)
 				}
 			}
 		}
@@ -485,73 +664,104 @@ func (p *Parser) extractPlayers(img image.Image) []db.Player {
 		// If we've collected enough info or hit a separator, save player
 		if currentPlayer.Name != "" && (i == len(lines)-1 || isPlayerSeparator(line)) {
 			if currentPlayer.Name != "" {
+				completeNoteCounts(&currentPlayer, confidence)
+				run.recordFields(fmt.Sprintf("players.%d", len(players)), confidence)
 				players = append(players, currentPlayer)
 			}
 			currentPlayer = db.Player{}
+			confidence = map[string]float64{}
 		}
 	}
 
 	// Add last player if exists
 	if currentPlayer.Name != "" {
+		completeNoteCounts(&currentPlayer, confidence)
+		run.recordFields(fmt.Sprintf("players.%d", len(players)), confidence)
 		players = append(players, currentPlayer)
 	}
 
+	assignInstruments(players, p.detectInstruments(img))
+
 	return players
 }
 
+// assignInstruments hands the detected icons to players in screen order.
+// Players whose column reads "No Part" have no icon and don't consume one.
+func assignInstruments(players []db.Player, instruments []string) {
+	next := 0
+	for i := range players {
+		if players[i].Instrument == db.InstrumentNoPart {
+			continue
+		}
+		if next < len(instruments) {
+			players[i].Instrument = instruments[next]
+			next++
+		}
+	}
+}
+
+// ocrRegion preprocesses a cropped region with the named pipeline, OCRs it
+// with the pipeline's OCR settings and records the result under region.
+func (p *Parser) ocrRegion(run *parseRun, region, pipeline string, crop image.Image) ocrResult {
+	prepared := p.preprocess(pipeline, crop)
+	res := p.recognize(prepared, p.ocrSettings[pipeline])
+	run.recordRegion(region, res)
+	run.saveRegion(region, crop, prepared, res)
+	return res
+}
+
 // extractText performs OCR on an image region.
 func (p *Parser) extractText(img image.Image) string {
+	return p.recognize(img, OCRSettings{}).Text
+}
+
+// recognize performs OCR on an image region with the given settings and
+// returns its text lines along with Tesseract's confidence for each.
+func (p *Parser) recognize(img image.Image, settings OCRSettings) ocrResult {
 	// Check if image is valid
 	bounds := img.Bounds()
 	if bounds.Dx() == 0 || bounds.Dy() == 0 {
-		log.Printf("warning: extractText called with empty image")
-		return ""
-	}
-
-	// Save image to temp file for OCR
-	tmpFile, err := os.CreateTemp("", "clonehero-ocr-*.png")
-	if err != nil {
-		log.Printf("failed to create temp file for OCR: %v", err)
-		return ""
+		log.Printf("warning: recognize called with empty image")
+		return ocrResult{}
 	}
-	tmpPath := tmpFile.Name()
-	defer os.Remove(tmpPath)
 
-	// Encode image to PNG
-	if err := encodePNG(tmpFile, img); err != nil {
+	// Tesseract decodes the PNG from memory; no temp file needed
+	var buf bytes.Buffer
+	if err := encodePNG(&buf, img); err != nil {
 		log.Printf("failed to encode image to PNG: %v", err)
-		tmpFile.Close()
-		return ""
+		return ocrResult{}
 	}
 
-	// Close and flush the file before Tesseract reads it
-	if err := tmpFile.Close(); err != nil {
-		log.Printf("failed to close temp file: %v", err)
-		return ""
+	client, ok := p.pool.acquire()
+	if !ok {
+		log.Printf("warning: OCR requested after the parser was closed")
+		return ocrResult{}
 	}
+	defer p.pool.release(client)
 
-	// Verify file exists and has content
-	fileInfo, err := os.Stat(tmpPath)
-	if err != nil {
-		log.Printf("failed to stat temp file: %v", err)
-		return ""
-	}
-	if fileInfo.Size() == 0 {
-		log.Printf("warning: encoded PNG file is empty")
-		return ""
+	if err := client.configure(settings); err != nil {
+		log.Printf("failed to configure OCR: %v", err)
+		return ocrResult{}
 	}
 
-	// Perform OCR - SetImage needs the file path
-	if err := p.client.SetImage(tmpPath); err != nil {
+	if err := client.SetImageFromBytes(buf.Bytes()); err != nil {
 		log.Printf("failed to set image for OCR: %v", err)
-		return ""
+		return ocrResult{}
 	}
 
-	text, err := p.client.Text()
+	// Line boxes carry both the text and its confidence in a single recognition pass
+	boxes, err := client.GetBoundingBoxes(gosseract.RIL_TEXTLINE)
 	if err != nil {
 		log.Printf("failed to extract text via OCR: %v", err)
-		return ""
+		return ocrResult{}
 	}
+	lines := make([]ocrLine, 0, len(boxes))
+	for _, box := range boxes {
+		lines = append(lines, ocrLine{Text: box.Word, Confidence: box.Confidence, Box: box.Box})
+	}
+	res := newOCRResult(lines)
+	res.Height = bounds.Dy()
+	text := res.Text
 
 	// Debug: log extracted text (first 100 chars to avoid spam)
 	if text != "" {
@@ -559,12 +769,12 @@ func (p *Parser) extractText(img image.Image) string {
 		if len(preview) > 100 {
 			preview = preview[:100] + "..."
 		}
-		log.Printf("OCR extracted text (%d bytes): %q", len(text), preview)
+		log.Printf("OCR extracted text (%d bytes, %.0f%% confidence): %q", len(text), res.Confidence, preview)
 	} else {
 		log.Printf("warning: OCR returned empty text for image %dx%d", bounds.Dx(), bounds.Dy())
 	}
 
-	return text
+	return res
 }
 
 // Helper functions
@@ -585,22 +795,10 @@ func cropImage(img image.Image, left, top, right, bottom int) image.Image {
 
 	cropped := image.NewRGBA(image.Rect(0, 0, right-left, bottom-top))
 	draw.Draw(cropped, cropped.Bounds(), img, image.Pt(left, top), draw.Src)
-	// if err := saveImage(cropped, strconv.FormatInt(time.Now().UnixNano(), 10)+".png"); err != nil {
-	// 	log.Printf("failed to save cropped image: %v", err)
-	// }
 
 	return cropped
 }
 
-func saveImage(img image.Image, path string) error {
-	file, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-	return png.Encode(file, img)
-}
-
 func filterEmpty(lines []string) []string {
 	var result []string
 	for _, line := range lines {
@@ -612,8 +810,7 @@ func filterEmpty(lines []string) []string {
 }
 
 func isNumeric(s string) bool {
-	re := regexp.MustCompile(`^\d+([,\s]\d+)*$`)
-	return re.MatchString(strings.TrimSpace(s))
+	return numericRe.MatchString(strings.TrimSpace(s))
 }
 
 func normalizeDifficulty(s string) string {
@@ -638,6 +835,19 @@ func isPlayerSeparator(s string) bool {
 	return strings.TrimSpace(s) == "" || strings.HasPrefix(strings.TrimSpace(s), "---")
 }
 
-func encodePNG(w *os.File, img image.Image) error {
-	return png.Encode(w, img)
+// extensionMatchesFormat reports whether a file extension agrees with the
+// format name returned by image.Decode.
+func extensionMatchesFormat(ext, format string) bool {
+	switch format {
+	case "jpeg":
+		return ext == ".jpg" || ext == ".jpeg"
+	default:
+		return ext == "."+format
+	}
+}
+
+func encodePNG(w io.Writer, img image.Image) error {
+	// Crops are encoded once and decoded once, so favour speed over size
+	encoder := png.Encoder{CompressionLevel: png.BestSpeed}
+	return encoder.Encode(w, img)
 }
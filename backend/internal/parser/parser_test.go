@@ -73,7 +73,7 @@ func TestParseTimestampFromFilename(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			got, err := parseTimestampFromFilename(tc.filename)
+			got, err := parseTimestampFromFilename(tc.filename, time.UTC)
 			if tc.wantError {
 				assert.Error(t, err)
 			} else {
@@ -105,9 +105,9 @@ func TestLoadImage(t *testing.T) {
 			wantError: true,
 		},
 		{
-			name:      "non-PNG file (JPEG)",
+			name:      "JPEG image",
 			imagePath: "../../../testdata/images/test-image.jpg",
-			wantError: true,
+			wantError: false,
 		},
 	}
 
@@ -129,6 +129,77 @@ func TestLoadImage(t *testing.T) {
 	}
 }
 
+func TestLoadImage_SniffsContent(t *testing.T) {
+	parser, err := NewParser(1920, 1080)
+	require.NoError(t, err)
+	defer parser.Close()
+
+	testCases := []struct {
+		name      string
+		source    string
+		filename  string
+		wantError bool
+	}{
+		{
+			name:     "JPEG with a .png extension",
+			source:   "../../../testdata/images/test-image.jpg",
+			filename: "mislabelled.png",
+		},
+		{
+			name:     "PNG with a .jpg extension",
+			source:   "../../../testdata/images/iamabanana.png",
+			filename: "mislabelled.jpg",
+		},
+		{
+			name:     "PNG with a .webp extension",
+			source:   "../../../testdata/images/iamabanana.png",
+			filename: "mislabelled.webp",
+		},
+		{
+			name:      "text file with a .jpeg extension",
+			filename:  "not-an-image.jpeg",
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data := []byte("not an image")
+			if tc.source != "" {
+				data, err = os.ReadFile(tc.source)
+				require.NoError(t, err)
+			}
+			path := filepath.Join(t.TempDir(), tc.filename)
+			require.NoError(t, os.WriteFile(path, data, 0644))
+
+			img, err := parser.loadImage(path)
+			if tc.wantError {
+				assert.Error(t, err)
+				assert.Nil(t, img)
+				return
+			}
+			require.NoError(t, err)
+			assert.Greater(t, img.Bounds().Dx(), 0)
+			assert.Greater(t, img.Bounds().Dy(), 0)
+		})
+	}
+}
+
+func TestLoadImage_JPEGMatchesPNGDimensions(t *testing.T) {
+	parser, err := NewParser(1920, 1080)
+	require.NoError(t, err)
+	defer parser.Close()
+
+	img, err := parser.loadImage("../../../testdata/images/test-image.jpg")
+	require.NoError(t, err)
+
+	ref, err := parser.loadImage("../../../testdata/images/test-mixed.png")
+	require.NoError(t, err)
+
+	// test-image.jpg is the JPEG encoding of test-mixed.png
+	assert.Equal(t, ref.Bounds(), img.Bounds())
+}
+
 func TestFilterEmpty(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -454,21 +525,25 @@ func TestParseImage_TimestampFormats(t *testing.T) {
 		name     string
 		filename string
 		expected time.Time
+		source   string
 	}{
 		{
 			name:     "timestamp at end",
 			filename: "scores/clonehero-Made-Your-Mark-20251019165816.png",
-			expected: time.Date(2025, 10, 19, 16, 58, 16, 0, time.UTC),
+			expected: time.Date(2025, 10, 19, 16, 58, 16, 0, time.Local),
+			source:   db.TimeSourceFilename,
 		},
 		{
 			name:     "timestamp at start",
 			filename: "images/20251212052231-iamabanana.png",
-			expected: time.Date(2025, 12, 12, 5, 22, 31, 0, time.UTC),
+			expected: time.Date(2025, 12, 12, 5, 22, 31, 0, time.Local),
+			source:   db.TimeSourceFilename,
 		},
 		{
 			name:     "no timestamp in filename falls back to file modification time",
 			filename: "images/iamabanana.png",
 			expected: time.Date(2025, 12, 13, 1, 21, 42, 384011703, time.Local),
+			source:   db.TimeSourceModTime,
 		},
 	}
 
@@ -483,6 +558,7 @@ func TestParseImage_TimestampFormats(t *testing.T) {
 			require.NoError(t, err)
 			require.NotNil(t, scoreData)
 			assert.Equal(t, tc.expected, scoreData.CreatedAt)
+			assert.Equal(t, tc.source, scoreData.CreatedAtSource)
 		})
 	}
 }
@@ -586,9 +662,8 @@ func TestParseImage_TestImages(t *testing.T) {
 			name:     "test-image-jpeg",
 			filepath: "../../../testdata/images/test-image.jpg",
 			validate: func(t *testing.T, data *db.CreateScoreData) {
-				// JPEG images are not supported; expect an error/failure
-				fmt.Printf("data: %v", data)
-				assert.Nil(t, data, "JPEG images should not be parsed successfully (only PNG supported)")
+				// JPEG images are decoded like PNGs
+				assert.NotNil(t, data, "JPEG images should be parsed")
 			},
 		},
 	}
@@ -701,7 +776,7 @@ func TestExtractTopLeftInfo(t *testing.T) {
 			defer close()
 
 			img := testImage(t, parser, tC.filepath)
-			artist, songName, charter := parser.extractTopLeftInfo(img)
+			artist, songName, charter := parser.extractTopLeftInfo(nil, img)
 
 			assert.Equal(t, tC.expectedArtist, artist)
 			assert.Equal(t, tC.expectedSongName, songName)
@@ -716,7 +791,7 @@ func TestExtractTopLeftInfo_TestImage(t *testing.T) {
 	imagePath := filepath.Join(_testImagePath, "images", "test-top-left.png")
 	img := testImage(t, parser, imagePath)
 
-	artist, songName, charter := parser.extractTopLeftInfo(img)
+	artist, songName, charter := parser.extractTopLeftInfo(nil, img)
 
 	assert.Equal(t, artist, "The Beatles")
 	assert.Equal(t, songName, "Hey Jude")
@@ -734,7 +809,7 @@ func TestExtractCenterInfo_TestImage(t *testing.T) {
 	img, err := parser.loadImage(imagePath)
 	require.NoError(t, err)
 
-	totalScore, stars := parser.extractCenterInfo(img)
+	totalScore, stars := parser.extractCenterInfo(nil, img)
 
 	// Verify extraction from center region
 	// OCR may not always extract perfectly, but we verify the function runs
@@ -754,7 +829,7 @@ func TestExtractPlayers_TestImage(t *testing.T) {
 	img, err := parser.loadImage(imagePath)
 	require.NoError(t, err)
 
-	players := parser.extractPlayers(img)
+	players := parser.extractPlayers(nil, img)
 
 	// Verify extraction from player region
 	assert.Greater(t, len(players), 0, "Should extract at least one player from player region")
@@ -824,8 +899,10 @@ func createTestImage(width, height int) image.Image {
 	return img
 }
 
+// newTestParser reads capture times as UTC, the zone the goldens in
+// testdata/scores were written in, whatever the host's zone.
 func newTestParser(t *testing.T) (*Parser, func() error) {
-	parser, err := NewParser(1920, 1080)
+	parser, err := NewParser(1920, 1080, WithTimezone(time.UTC))
 	require.NoError(t, err, "Tesseract OCR is not installed or not available. This is required for the parser service. Install with: sudo apt-get install tesseract-ocr (Ubuntu/Debian) or brew install tesseract (macOS)")
 
 	return parser, parser.Close
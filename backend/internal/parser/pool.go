@@ -0,0 +1,108 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/otiai10/gosseract/v2"
+)
+
+// clientPool hands out Tesseract clients. A gosseract.Client holds the image
+// being recognized, so each goroutine needs its own while it OCRs a crop.
+type clientPool struct {
+	clients chan *ocrClient
+	size    int
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// ocrClient is a pooled client and the settings it was last configured with.
+type ocrClient struct {
+	*gosseract.Client
+	settings *OCRSettings
+}
+
+// configure applies s unless the client already uses it. Changing settings
+// makes Tesseract re-initialize on the next recognition, which is cheap
+// while the language stays the same.
+func (c *ocrClient) configure(s OCRSettings) error {
+	if c.settings != nil && *c.settings == s {
+		return nil
+	}
+	if c.settings == nil || c.settings.language() != s.language() {
+		if err := c.SetLanguage(s.language()); err != nil {
+			return fmt.Errorf("failed to set OCR language %q: %w", s.language(), err)
+		}
+	}
+	if err := c.SetPageSegMode(s.pageSegMode()); err != nil {
+		return fmt.Errorf("failed to set page segmentation mode: %w", err)
+	}
+	if err := c.SetWhitelist(s.Whitelist); err != nil {
+		return fmt.Errorf("failed to set character whitelist: %w", err)
+	}
+	// 0 is Tesseract's "not set"
+	if err := c.SetVariable("user_defined_dpi", strconv.Itoa(s.DPI)); err != nil {
+		return fmt.Errorf("failed to set DPI: %w", err)
+	}
+	c.settings = &s
+	return nil
+}
+
+// newClientPool creates size clients set up for English.
+func newClientPool(size int) (*clientPool, error) {
+	if size < 1 {
+		size = 1
+	}
+
+	pool := &clientPool{
+		clients: make(chan *ocrClient, size),
+		size:    size,
+	}
+	for i := 0; i < size; i++ {
+		client := gosseract.NewClient()
+		if err := client.SetLanguage("eng"); err != nil {
+			client.Close()
+			for len(pool.clients) > 0 {
+				(<-pool.clients).Close()
+			}
+			return nil, fmt.Errorf("failed to set OCR language (check TESSDATA_PREFIX): %w", err)
+		}
+		pool.clients <- &ocrClient{Client: client}
+	}
+	return pool, nil
+}
+
+// acquire waits for a free client. ok is false once the pool is closed.
+func (cp *clientPool) acquire() (client *ocrClient, ok bool) {
+	client, ok = <-cp.clients
+	return client, ok
+}
+
+// release returns a client taken with acquire.
+func (cp *clientPool) release(client *ocrClient) {
+	cp.clients <- client
+}
+
+// close waits for every client to be released and closes them. Calling it
+// more than once is a no-op.
+func (cp *clientPool) close() error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if cp.closed {
+		return nil
+	}
+	cp.closed = true
+
+	var errs []error
+	for i := 0; i < cp.size; i++ {
+		client := <-cp.clients
+		if err := client.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	close(cp.clients)
+	return errors.Join(errs...)
+}
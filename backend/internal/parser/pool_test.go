@@ -0,0 +1,166 @@
+package parser
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"cloneheroer/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewParser_PoolSize(t *testing.T) {
+	testCases := []struct {
+		name     string
+		size     int
+		expected int
+	}{
+		{name: "default", size: 0, expected: 1},
+		{name: "negative", size: -2, expected: 1},
+		{name: "several clients", size: 3, expected: 3},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parser, err := NewParser(1920, 1080, WithPoolSize(tc.size))
+			require.NoError(t, err)
+			defer parser.Close()
+
+			assert.Equal(t, tc.expected, parser.pool.size)
+			assert.Len(t, parser.pool.clients, tc.expected)
+		})
+	}
+}
+
+func TestClientPool_CloseWaitsForInFlight(t *testing.T) {
+	pool, err := newClientPool(2)
+	require.NoError(t, err)
+
+	client, ok := pool.acquire()
+	require.True(t, ok)
+
+	closed := make(chan error)
+	go func() { closed <- pool.close() }()
+
+	select {
+	case <-closed:
+		t.Fatal("close returned while a client was still in use")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	pool.release(client)
+	assert.NoError(t, <-closed)
+
+	_, ok = pool.acquire()
+	assert.False(t, ok, "a closed pool hands out no clients")
+	assert.NoError(t, pool.close(), "closing twice is a no-op")
+}
+
+func TestRecognize_AfterClose(t *testing.T) {
+	parser, err := NewParser(1920, 1080)
+	require.NoError(t, err)
+	require.NoError(t, parser.Close())
+
+	done := make(chan ocrResult)
+	go func() { done <- parser.recognize(createTestImage(20, 20), OCRSettings{}) }()
+
+	select {
+	case res := <-done:
+		assert.Empty(t, res.Text)
+	case <-time.After(time.Second):
+		t.Fatal("recognize blocked on a closed parser")
+	}
+}
+
+// ParseImage must give the same answers whether it's called serially or from
+// many goroutines sharing one parser.
+func TestParseImage_Concurrent(t *testing.T) {
+	parser, err := NewParser(1920, 1080, WithPoolSize(4))
+	require.NoError(t, err)
+	defer parser.Close()
+
+	files := scoreScreenshots(t)
+	serial := make(map[string]*db.CreateScoreData, len(files))
+	for _, file := range files {
+		data, err := parser.ParseImage(file)
+		require.NoError(t, err)
+		serial[file] = data
+	}
+
+	var wg sync.WaitGroup
+	results := make([]*db.CreateScoreData, len(files))
+	errs := make([]error, len(results))
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = parser.ParseImage(files[i%len(files)])
+		}()
+	}
+	wg.Wait()
+
+	for i, data := range results {
+		file := files[i%len(files)]
+		require.NoError(t, errs[i], file)
+		assert.Equal(t, serial[file], data, file)
+	}
+}
+
+func BenchmarkParseImage(b *testing.B) {
+	files := scoreScreenshots(b)
+
+	for _, size := range []int{1, 2, 4} {
+		b.Run(fmt.Sprintf("clients=%d", size), func(b *testing.B) {
+			parser, err := NewParser(1920, 1080, WithPoolSize(size))
+			require.NoError(b, err)
+			defer parser.Close()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// One goroutine per client, each working through its share of the folder
+				var wg sync.WaitGroup
+				for w := 0; w < size; w++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						for j := w; j < len(files); j += size {
+							if _, err := parser.ParseImage(files[j]); err != nil {
+								b.Error(err)
+							}
+						}
+					}()
+				}
+				wg.Wait()
+			}
+			b.ReportMetric(float64(b.N*len(files))/b.Elapsed().Seconds(), "images/s")
+		})
+	}
+}
+
+func BenchmarkRecognize(b *testing.B) {
+	parser, err := NewParser(1920, 1080)
+	require.NoError(b, err)
+	defer parser.Close()
+
+	img, err := parser.loadImage(filepath.Join(_testImagePath, "scores", "clonehero-Tripping-Billies-20251209195440.png"))
+	require.NoError(b, err)
+	region := cropRegion(img, parser.layoutFor(img).TopLeft)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		parser.recognize(region, parser.ocrSettings[PipelineTopLeft])
+	}
+}
+
+func scoreScreenshots(tb testing.TB) []string {
+	tb.Helper()
+
+	files, err := filepath.Glob(filepath.Join(_testImagePath, "scores", "*.png"))
+	require.NoError(tb, err)
+	require.NotEmpty(tb, files)
+	return files
+}
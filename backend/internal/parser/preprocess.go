@@ -0,0 +1,286 @@
+package parser
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"sort"
+
+	"golang.org/x/image/draw"
+)
+
+// Preprocessing stage operations.
+const (
+	OpGrayscale = "grayscale"
+	OpContrast  = "contrast"
+	OpThreshold = "threshold"
+	OpInvert    = "invert"
+	OpUpscale   = "upscale"
+)
+
+// Pipeline names, one per kind of OCR'd region.
+const (
+	PipelineTopLeft       = "top_left"
+	PipelineCenter        = "center"
+	PipelinePlayers       = "players"
+	PipelinePlayerName    = "player_name"
+	PipelinePlayerSummary = "player_summary"
+	PipelinePlayerStats   = "player_stats"
+
+	// Digit-only parts of a player panel, OCR'd with a character whitelist
+	PipelinePlayerScore       = "player_score"
+	PipelinePlayerAccuracy    = "player_accuracy"
+	PipelinePlayerStatsValues = "player_stats_values"
+)
+
+//go:embed preprocess.json
+var defaultPreprocessJSON []byte
+
+// PreprocessStage is one step of a preprocessing pipeline. Only the fields of
+// the stage's Op are used; zero values pick the defaults noted below.
+type PreprocessStage struct {
+	Op string `json:"op"`
+	// Factor is the integer upscale factor (upscale, default 2).
+	Factor int `json:"factor,omitempty"`
+	// Low and High are the percentiles stretched to black and white (contrast, default 1 and 99).
+	Low  float64 `json:"low,omitempty"`
+	High float64 `json:"high,omitempty"`
+	// Window is the side of the neighbourhood in pixels (threshold, default 41).
+	Window int `json:"window,omitempty"`
+	// Offset is how much brighter than its neighbourhood a pixel must be to
+	// count as text (threshold, default 10).
+	Offset float64 `json:"offset,omitempty"`
+}
+
+// Pipeline is an ordered list of stages applied to a crop before OCR.
+type Pipeline []PreprocessStage
+
+// loadPipelines reads preprocessing pipelines from path on top of the
+// built-in ones. Pipelines missing from the file keep their default; an empty
+// list turns preprocessing off for that region.
+func loadPipelines(path string) (map[string]Pipeline, error) {
+	pipelines := map[string]Pipeline{}
+	if err := json.Unmarshal(defaultPreprocessJSON, &pipelines); err != nil {
+		return nil, fmt.Errorf("failed to parse built-in preprocessing: %w", err)
+	}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read preprocessing file: %w", err)
+		}
+		var overrides map[string]Pipeline
+		if err := json.Unmarshal(data, &overrides); err != nil {
+			return nil, fmt.Errorf("failed to parse preprocessing file: %w", err)
+		}
+		for name, pipeline := range overrides {
+			pipelines[name] = pipeline
+		}
+	}
+
+	for name, pipeline := range pipelines {
+		if err := pipeline.validate(); err != nil {
+			return nil, fmt.Errorf("pipeline %q: %w", name, err)
+		}
+	}
+	return pipelines, nil
+}
+
+// validate checks every stage names a known operation with sane parameters.
+func (pl Pipeline) validate() error {
+	for i, stage := range pl {
+		switch stage.Op {
+		case OpGrayscale, OpInvert:
+		case OpUpscale:
+			if stage.Factor < 0 || stage.Factor > 8 {
+				return fmt.Errorf("stage %d: upscale factor must be between 1 and 8", i)
+			}
+		case OpContrast:
+			if stage.Low < 0 || stage.High > 100 || (stage.High != 0 && stage.Low >= stage.High) {
+				return fmt.Errorf("stage %d: contrast needs 0 <= low < high <= 100", i)
+			}
+		case OpThreshold:
+			if stage.Window < 0 {
+				return fmt.Errorf("stage %d: threshold window must be positive", i)
+			}
+		default:
+			return fmt.Errorf("stage %d: unknown op %q", i, stage.Op)
+		}
+	}
+	return nil
+}
+
+// Apply runs the pipeline over img and returns the processed image.
+func (pl Pipeline) Apply(img image.Image) image.Image {
+	for _, stage := range pl {
+		switch stage.Op {
+		case OpGrayscale:
+			img = toGray(img)
+		case OpContrast:
+			low, high := stage.Low, stage.High
+			if high == 0 {
+				low, high = 1, 99
+			}
+			img = stretchContrast(toGray(img), low, high)
+		case OpThreshold:
+			window := stage.Window
+			if window == 0 {
+				window = 41
+			}
+			offset := stage.Offset
+			if offset == 0 {
+				offset = 10
+			}
+			img = adaptiveThreshold(toGray(img), window, offset)
+		case OpInvert:
+			img = invert(img)
+		case OpUpscale:
+			factor := stage.Factor
+			if factor == 0 {
+				factor = 2
+			}
+			img = upscale(img, factor)
+		}
+	}
+	return img
+}
+
+// preprocess applies the named pipeline to a crop. Unknown names leave the
+// crop untouched.
+func (p *Parser) preprocess(name string, img image.Image) image.Image {
+	pipeline, ok := p.pipelines[name]
+	if !ok || len(pipeline) == 0 {
+		return img
+	}
+	bounds := img.Bounds()
+	if bounds.Dx() == 0 || bounds.Dy() == 0 {
+		return img
+	}
+	return pipeline.Apply(img)
+}
+
+// toGray converts img to an 8-bit grayscale image with its origin at (0, 0).
+func toGray(img image.Image) *image.Gray {
+	if gray, ok := img.(*image.Gray); ok && gray.Bounds().Min == (image.Point{}) {
+		return gray
+	}
+	bounds := img.Bounds()
+	gray := image.NewGray(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			gray.Pix[y*gray.Stride+x] = uint8(luminance(img.At(bounds.Min.X+x, bounds.Min.Y+y)) + 0.5)
+		}
+	}
+	return gray
+}
+
+// stretchContrast maps the low and high luminance percentiles to black and
+// white, clipping everything outside them.
+func stretchContrast(gray *image.Gray, lowPct, highPct float64) *image.Gray {
+	values := make([]int, len(gray.Pix))
+	for i, v := range gray.Pix {
+		values[i] = int(v)
+	}
+	sort.Ints(values)
+	if len(values) == 0 {
+		return gray
+	}
+	percentile := func(pct float64) int {
+		i := int(pct / 100 * float64(len(values)-1))
+		return values[i]
+	}
+	low, high := percentile(lowPct), percentile(highPct)
+	if high <= low {
+		return gray
+	}
+
+	out := image.NewGray(gray.Bounds())
+	scale := 255 / float64(high-low)
+	for i, v := range gray.Pix {
+		switch {
+		case int(v) <= low:
+			out.Pix[i] = 0
+		case int(v) >= high:
+			out.Pix[i] = 255
+		default:
+			out.Pix[i] = uint8(float64(int(v)-low)*scale + 0.5)
+		}
+	}
+	return out
+}
+
+// adaptiveThreshold turns pixels brighter than the mean of their window by
+// more than offset white and everything else black. Clone Hero draws light
+// text, so this keeps the glyphs whatever the background behind them.
+func adaptiveThreshold(gray *image.Gray, window int, offset float64) *image.Gray {
+	bounds := gray.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	// Summed-area table so each window mean costs four lookups
+	integral := make([]int64, (w+1)*(h+1))
+	for y := 0; y < h; y++ {
+		var rowSum int64
+		for x := 0; x < w; x++ {
+			rowSum += int64(gray.Pix[y*gray.Stride+x])
+			integral[(y+1)*(w+1)+x+1] = integral[y*(w+1)+x+1] + rowSum
+		}
+	}
+
+	half := window / 2
+	out := image.NewGray(bounds)
+	for y := 0; y < h; y++ {
+		y0, y1 := max(y-half, 0), min(y+half+1, h)
+		for x := 0; x < w; x++ {
+			x0, x1 := max(x-half, 0), min(x+half+1, w)
+			sum := integral[y1*(w+1)+x1] - integral[y0*(w+1)+x1] - integral[y1*(w+1)+x0] + integral[y0*(w+1)+x0]
+			mean := float64(sum) / float64((x1-x0)*(y1-y0))
+			if float64(gray.Pix[y*gray.Stride+x]) > mean+offset {
+				out.Pix[y*out.Stride+x] = 255
+			}
+		}
+	}
+	return out
+}
+
+// invert flips light and dark so text ends up dark on light, which is what
+// Tesseract is trained on.
+func invert(img image.Image) image.Image {
+	if gray, ok := img.(*image.Gray); ok {
+		out := image.NewGray(gray.Bounds())
+		for i, v := range gray.Pix {
+			out.Pix[i] = 255 - v
+		}
+		return out
+	}
+
+	bounds := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			out.Set(x, y, color.RGBA{R: 255 - uint8(r>>8), G: 255 - uint8(g>>8), B: 255 - uint8(b>>8), A: uint8(a >> 8)})
+		}
+	}
+	return out
+}
+
+// upscale enlarges img by an integer factor. Tesseract reads small UI text
+// much better once glyphs are 30px or more tall.
+func upscale(img image.Image, factor int) image.Image {
+	if factor <= 1 {
+		return img
+	}
+	bounds := img.Bounds()
+	rect := image.Rect(0, 0, bounds.Dx()*factor, bounds.Dy()*factor)
+	if _, ok := img.(*image.Gray); ok {
+		out := image.NewGray(rect)
+		draw.CatmullRom.Scale(out, rect, img, bounds, draw.Src, nil)
+		return out
+	}
+	out := image.NewRGBA(rect)
+	draw.CatmullRom.Scale(out, rect, img, bounds, draw.Src, nil)
+	return out
+}
@@ -0,0 +1,207 @@
+package parser
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPipelines_BuiltIn(t *testing.T) {
+	pipelines, err := loadPipelines("")
+	require.NoError(t, err)
+
+	for _, name := range []string{PipelineTopLeft, PipelineCenter, PipelinePlayers, PipelinePlayerName, PipelinePlayerSummary, PipelinePlayerStats, PipelinePlayerScore, PipelinePlayerAccuracy, PipelinePlayerStatsValues} {
+		assert.NotEmpty(t, pipelines[name], "built-in pipeline %q should exist", name)
+	}
+}
+
+func TestLoadPipelines_File(t *testing.T) {
+	testCases := []struct {
+		name      string
+		contents  string
+		check     func(t *testing.T, pipelines map[string]Pipeline)
+		wantError bool
+	}{
+		{
+			name:     "override one region",
+			contents: `{"center": [{"op": "grayscale"}, {"op": "upscale", "factor": 3}]}`,
+			check: func(t *testing.T, pipelines map[string]Pipeline) {
+				assert.Equal(t, Pipeline{{Op: OpGrayscale}, {Op: OpUpscale, Factor: 3}}, pipelines[PipelineCenter])
+				assert.NotEmpty(t, pipelines[PipelineTopLeft], "regions not in the file keep their default")
+			},
+		},
+		{
+			name:     "empty pipeline disables preprocessing",
+			contents: `{"top_left": []}`,
+			check: func(t *testing.T, pipelines map[string]Pipeline) {
+				assert.Empty(t, pipelines[PipelineTopLeft])
+			},
+		},
+		{
+			name:      "unknown op",
+			contents:  `{"center": [{"op": "sharpen"}]}`,
+			wantError: true,
+		},
+		{
+			name:      "upscale factor out of range",
+			contents:  `{"center": [{"op": "upscale", "factor": 20}]}`,
+			wantError: true,
+		},
+		{
+			name:      "contrast bounds reversed",
+			contents:  `{"center": [{"op": "contrast", "low": 90, "high": 10}]}`,
+			wantError: true,
+		},
+		{
+			name:      "invalid json",
+			contents:  `[`,
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "preprocess.json")
+			require.NoError(t, os.WriteFile(path, []byte(tc.contents), 0644))
+
+			pipelines, err := loadPipelines(path)
+			if tc.wantError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			tc.check(t, pipelines)
+		})
+	}
+}
+
+func TestLoadPipelines_MissingFile(t *testing.T) {
+	_, err := loadPipelines(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestNewParser_InvalidPreprocessFile(t *testing.T) {
+	parser, err := NewParser(1920, 1080, WithPreprocessFile(filepath.Join(t.TempDir(), "nonexistent.json")))
+	assert.Error(t, err)
+	assert.Nil(t, parser)
+}
+
+func TestPipeline_Stages(t *testing.T) {
+	// Light text-like stripe on a mid grey, offset from the origin like a crop
+	src := image.NewRGBA(image.Rect(10, 10, 50, 30))
+	for y := 10; y < 30; y++ {
+		for x := 10; x < 50; x++ {
+			c := color.RGBA{R: 90, G: 100, B: 110, A: 255}
+			if x >= 28 && x < 32 {
+				c = color.RGBA{R: 220, G: 220, B: 220, A: 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+
+	t.Run("grayscale", func(t *testing.T) {
+		out := Pipeline{{Op: OpGrayscale}}.Apply(src)
+		gray, ok := out.(*image.Gray)
+		require.True(t, ok)
+		assert.Equal(t, image.Rect(0, 0, 40, 20), gray.Bounds())
+		assert.InDelta(t, 98, gray.GrayAt(0, 0).Y, 1)
+	})
+
+	t.Run("contrast", func(t *testing.T) {
+		gray := Pipeline{{Op: OpContrast}}.Apply(src).(*image.Gray)
+		assert.Equal(t, uint8(0), gray.GrayAt(0, 0).Y)
+		assert.Equal(t, uint8(255), gray.GrayAt(20, 0).Y)
+	})
+
+	t.Run("threshold", func(t *testing.T) {
+		gray := Pipeline{{Op: OpThreshold, Window: 15, Offset: 10}}.Apply(src).(*image.Gray)
+		assert.Equal(t, uint8(0), gray.GrayAt(0, 10).Y, "background should be black")
+		assert.Equal(t, uint8(255), gray.GrayAt(20, 10).Y, "text should be white")
+	})
+
+	t.Run("invert", func(t *testing.T) {
+		out := Pipeline{{Op: OpInvert}}.Apply(src)
+		r, g, b, _ := out.At(0, 0).RGBA()
+		assert.Equal(t, []uint32{165, 155, 145}, []uint32{r >> 8, g >> 8, b >> 8})
+	})
+
+	t.Run("upscale", func(t *testing.T) {
+		out := Pipeline{{Op: OpUpscale, Factor: 3}}.Apply(src)
+		assert.Equal(t, image.Rect(0, 0, 120, 60), out.Bounds())
+	})
+
+	t.Run("chained", func(t *testing.T) {
+		out := Pipeline{
+			{Op: OpGrayscale},
+			{Op: OpUpscale},
+			{Op: OpContrast},
+			{Op: OpThreshold, Window: 21},
+			{Op: OpInvert},
+		}.Apply(src)
+		gray := out.(*image.Gray)
+		assert.Equal(t, image.Rect(0, 0, 80, 40), gray.Bounds())
+		assert.Equal(t, uint8(255), gray.GrayAt(0, 20).Y, "background should end up white")
+		assert.Equal(t, uint8(0), gray.GrayAt(40, 20).Y, "text should end up black")
+	})
+}
+
+func TestPreprocess_UnknownPipeline(t *testing.T) {
+	parser := newLayoutOnlyParser(t)
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+
+	assert.Same(t, img, parser.preprocess("nonexistent", img))
+}
+
+// The default pipelines should turn every real results screen into dark text
+// on a mostly white page, whatever the background behind the panels.
+func TestPreprocess_ScoreScreenshots(t *testing.T) {
+	parser := newLayoutOnlyParser(t)
+
+	files, err := filepath.Glob(filepath.Join(_testImagePath, "scores", "*.png"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			img, err := parser.loadImage(file)
+			require.NoError(t, err)
+			layout := parser.layoutFor(img)
+
+			regions := map[string]image.Image{
+				PipelineTopLeft: cropRegion(img, layout.TopLeft),
+				PipelineCenter:  cropRegion(img, layout.Center),
+			}
+			height := img.Bounds().Dy()
+			if columns := findPlayerColumns(img, layout.Panel); len(columns) > 0 {
+				c := columns[0]
+				regions[PipelinePlayerName] = cropImage(img, c.Min.X, pctOf(height, layout.Panel.Top), c.Max.X, pctOf(height, layout.Panel.NameBottom))
+				regions[PipelinePlayerSummary] = cropImage(img, c.Min.X, pctOf(height, layout.Panel.SummaryTop), c.Max.X, pctOf(height, layout.Panel.SummaryBottom))
+				regions[PipelinePlayerStats] = cropImage(img, c.Min.X, pctOf(height, layout.Panel.StatsTop), c.Max.X, pctOf(height, layout.Panel.StatsBottom))
+			}
+
+			for name, region := range regions {
+				out := toGray(parser.preprocess(name, region))
+				assert.Equal(t, region.Bounds().Dx()*2, out.Bounds().Dx(), "%s should be upscaled", name)
+
+				var black, white int
+				for _, v := range out.Pix {
+					switch v {
+					case 0:
+						black++
+					case 255:
+						white++
+					}
+				}
+				total := len(out.Pix)
+				assert.Equal(t, total, black+white, "%s should be binary", name)
+				assert.Greater(t, float64(white)/float64(total), 0.6, "%s should be mostly background", name)
+				assert.Greater(t, float64(black)/float64(total), 0.005, "%s should keep some text", name)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"image"
+	"image/color"
+)
+
+const (
+	// starMinFill is the share of a slot a lit star covers; a five-pointed
+	// star fills roughly half of its bounding box.
+	starMinFill = 0.3
+	// starMaxCornerFill is the most a slot's top corners may be lit. Star
+	// points never reach them, a bright background does.
+	starMaxCornerFill = 0.2
+)
+
+// starSlot is the measured state of one star position.
+type starSlot struct {
+	lit  bool
+	gold bool
+}
+
+// countStars counts the lit star icons under the total score. Clone Hero
+// always draws the full row and fills stars from the left, white or, for the
+// best runs, gold. ok is false when no lit star was found, which also covers
+// images that aren't results screens.
+func (p *Parser) countStars(img image.Image) (stars int, gold bool, ok bool) {
+	slots := measureStarSlots(img, p.layoutFor(img).Stars)
+
+	goldCount := 0
+	for _, slot := range slots {
+		if !slot.lit {
+			break
+		}
+		stars++
+		if slot.gold {
+			goldCount++
+		}
+	}
+	if stars == 0 {
+		return 0, false, false
+	}
+	return stars, goldCount == stars, true
+}
+
+// measureStarSlots classifies every slot of the star row left to right.
+func measureStarSlots(img image.Image, row StarRowLayout) []starSlot {
+	rects := starSlotRects(img.Bounds(), row)
+	slots := make([]starSlot, 0, len(rects))
+	for _, rect := range rects {
+		slots = append(slots, measureStarSlot(img, rect))
+	}
+	return slots
+}
+
+// cropStarRow crops the star row, from the first slot to the last.
+func cropStarRow(img image.Image, row StarRowLayout) image.Image {
+	var rect image.Rectangle
+	for _, slot := range starSlotRects(img.Bounds(), row) {
+		rect = rect.Union(slot)
+	}
+	return cropImage(img, rect.Min.X, rect.Min.Y, rect.Max.X, rect.Max.Y)
+}
+
+// starSlotRects returns where each slot of the star row sits in an image
+// with the given bounds, left to right.
+func starSlotRects(bounds image.Rectangle, row StarRowLayout) []image.Rectangle {
+	height := bounds.Dy()
+	size := float64(height) * row.Size / 100
+	pitch := float64(height) * row.Pitch / 100
+	if row.Count == 0 || size < 4 || pitch <= 0 {
+		return nil
+	}
+
+	top := bounds.Min.Y + pctOf(height, row.Top)
+	bottom := bounds.Min.Y + pctOf(height, row.Bottom)
+	centre := float64(bounds.Min.X) + float64(bounds.Dx())/2
+	firstLeft := centre - pitch*float64(row.Count-1)/2 - size/2
+
+	rects := make([]image.Rectangle, 0, row.Count)
+	for i := 0; i < row.Count; i++ {
+		left := int(firstLeft + float64(i)*pitch)
+		rects = append(rects, image.Rect(left, top, left+int(size), bottom).Intersect(bounds))
+	}
+	return rects
+}
+
+// measureStarSlot decides whether rect holds a lit star and whether it's gold.
+func measureStarSlot(img image.Image, rect image.Rectangle) starSlot {
+	if rect.Empty() {
+		return starSlot{}
+	}
+
+	corner := max(rect.Dx()/5, 1)
+	var lit, gold, cornerLit, cornerTotal int
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			white, yellow := starPixel(img.At(x, y))
+			inCorner := y < rect.Min.Y+corner && (x < rect.Min.X+corner || x >= rect.Max.X-corner)
+			if inCorner {
+				cornerTotal++
+			}
+			if !white && !yellow {
+				continue
+			}
+			lit++
+			if yellow {
+				gold++
+			}
+			if inCorner {
+				cornerLit++
+			}
+		}
+	}
+
+	area := rect.Dx() * rect.Dy()
+	fill := float64(lit) / float64(area)
+	cornerFill := float64(cornerLit) / float64(max(cornerTotal, 1))
+	if fill < starMinFill || cornerFill > starMaxCornerFill {
+		return starSlot{}
+	}
+	return starSlot{lit: true, gold: gold*2 > lit}
+}
+
+// starPixel reports whether c is part of a lit white star or a gold one.
+func starPixel(c color.Color) (white, gold bool) {
+	r, g, b, _ := c.RGBA()
+	r, g, b = r>>8, g>>8, b>>8
+	white = min(r, g, b) > 180
+	gold = r > 180 && g > 120 && b+60 < g && r > b+90
+	return white, gold
+}
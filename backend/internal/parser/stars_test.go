@@ -0,0 +1,154 @@
+package parser
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountStars(t *testing.T) {
+	testCases := []struct {
+		file      string
+		wantStars int
+	}{
+		{file: "clonehero-Adolescents-20251019101128.png", wantStars: 4},
+		{file: "clonehero-Chemical-Calisthenics-20251210202458.png", wantStars: 5},
+		{file: "clonehero-Discography-20250930000459.png", wantStars: 4},
+		{file: "clonehero-Discography-20250930000459-50.png", wantStars: 4},
+		{file: "clonehero-Made-Your-Mark-20251019165816.png", wantStars: 3},
+		{file: "clonehero-Made-Your-Mark-20251207125212.png", wantStars: 4},
+		{file: "clonehero-Tripping-Billies-20251209195440.png", wantStars: 4},
+		{file: "clonehero-atreyu-20251129173107.png", wantStars: 3},
+	}
+
+	parser := newLayoutOnlyParser(t)
+	for _, tc := range testCases {
+		t.Run(tc.file, func(t *testing.T) {
+			img, err := parser.loadImage(filepath.Join(_testImagePath, "scores", tc.file))
+			require.NoError(t, err)
+
+			stars, gold, ok := parser.countStars(img)
+			assert.True(t, ok)
+			assert.Equal(t, tc.wantStars, stars)
+			assert.False(t, gold)
+		})
+	}
+}
+
+func TestCountStars_Synthetic(t *testing.T) {
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	gold := color.RGBA{R: 250, G: 200, B: 40, A: 255}
+
+	testCases := []struct {
+		name      string
+		lit       int
+		color     color.RGBA
+		wantStars int
+		wantGold  bool
+	}{
+		{name: "white stars", lit: 5, color: white, wantStars: 5},
+		{name: "gold stars", lit: 6, color: gold, wantStars: 6, wantGold: true},
+		{name: "all seven", lit: 7, color: gold, wantStars: 7, wantGold: true},
+	}
+
+	parser := newLayoutOnlyParser(t)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			img := syntheticStarRow(t, parser, 1920, 1080, tc.lit, tc.color)
+
+			stars, gold, ok := parser.countStars(img)
+			assert.True(t, ok)
+			assert.Equal(t, tc.wantStars, stars)
+			assert.Equal(t, tc.wantGold, gold)
+		})
+	}
+}
+
+func TestCountStars_NoStars(t *testing.T) {
+	parser := newLayoutOnlyParser(t)
+
+	t.Run("dark screen", func(t *testing.T) {
+		img := syntheticStarRow(t, parser, 1920, 1080, 0, color.RGBA{})
+		_, _, ok := parser.countStars(img)
+		assert.False(t, ok)
+	})
+
+	t.Run("bright background", func(t *testing.T) {
+		img := image.NewRGBA(image.Rect(0, 0, 1920, 1080))
+		for i := range img.Pix {
+			img.Pix[i] = 255
+		}
+		_, _, ok := parser.countStars(img)
+		assert.False(t, ok, "a white background isn't a row of stars")
+	})
+
+	t.Run("not a results screen", func(t *testing.T) {
+		img, err := parser.loadImage(filepath.Join(_testImagePath, "images", "test-players.png"))
+		require.NoError(t, err)
+		_, _, ok := parser.countStars(img)
+		assert.False(t, ok)
+	})
+}
+
+// syntheticStarRow draws the star row the way the results screen does: lit
+// stars from the left, dim ones after.
+func syntheticStarRow(t *testing.T, parser *Parser, width, height, lit int, c color.RGBA) image.Image {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = 20, 60, 110, 255
+	}
+
+	row := parser.layoutFor(img).Stars
+	size := float64(height) * row.Size / 100
+	pitch := float64(height) * row.Pitch / 100
+	centreY := float64(height) * (row.Top + row.Bottom) / 200
+	firstX := float64(width)/2 - pitch*float64(row.Count-1)/2
+
+	dim := color.RGBA{R: 15, G: 35, B: 60, A: 255}
+	for i := 0; i < row.Count; i++ {
+		fill := dim
+		if i < lit {
+			fill = c
+		}
+		drawStar(img, firstX+float64(i)*pitch, centreY, size/2, fill)
+	}
+	return img
+}
+
+// drawStar fills a five-pointed star centred on (cx, cy).
+func drawStar(img *image.RGBA, cx, cy, radius float64, c color.RGBA) {
+	var points [10][2]float64
+	for i := range points {
+		r := radius
+		if i%2 == 1 {
+			r = radius * 0.4
+		}
+		angle := -math.Pi/2 + float64(i)*math.Pi/5
+		points[i] = [2]float64{cx + r*math.Cos(angle), cy + r*math.Sin(angle)}
+	}
+
+	for y := int(cy - radius); y <= int(cy+radius); y++ {
+		for x := int(cx - radius); x <= int(cx+radius); x++ {
+			// Even-odd point in polygon test
+			inside := false
+			px, py := float64(x)+0.5, float64(y)+0.5
+			for i, j := 0, len(points)-1; i < len(points); j, i = i, i+1 {
+				xi, yi := points[i][0], points[i][1]
+				xj, yj := points[j][0], points[j][1]
+				if (yi > py) != (yj > py) && px < (xj-xi)*(py-yi)/(yj-yi)+xi {
+					inside = !inside
+				}
+			}
+			if inside {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"cloneheroer/internal/db"
+)
+
+// WithTimezone sets the zone of capture times that don't carry one: the
+// stamp in Clone Hero's file names and EXIF dates. The default is the local
+// timezone, like TIMEZONE's.
+func WithTimezone(loc *time.Location) Option {
+	return func(p *Parser) {
+		p.timezone = loc
+	}
+}
+
+// location returns the configured timezone or the local one.
+func (p *Parser) location() *time.Location {
+	if p.timezone == nil {
+		return time.Local
+	}
+	return p.timezone
+}
+
+// captureTime returns when the screenshot at path was taken and where that
+// came from, trying the file name stamp, then the image's metadata, then the
+// file's modification time.
+func (p *Parser) captureTime(path string) (time.Time, string, error) {
+	if t, err := parseTimestampFromFilename(filepath.Base(path), p.location()); err == nil {
+		return t, db.TimeSourceFilename, nil
+	}
+
+	t, err := readMetadataTime(path, p.location())
+	if err == nil {
+		return t, db.TimeSourceMetadata, nil
+	}
+	if !errors.Is(err, errNoMetadataTime) {
+		log.Printf("warning: failed to read capture time from %s metadata: %v", filepath.Base(path), err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("failed to get file info: %w", err)
+	}
+	log.Printf("warning: no capture time in %s name or metadata, using its modification time", filepath.Base(path))
+	return info.ModTime(), db.TimeSourceModTime, nil
+}
+
+// errNoMetadataTime is returned when an image has no capture time in its
+// metadata.
+var errNoMetadataTime = errors.New("no capture time in metadata")
+
+// pngTimeKeys are the tEXt keywords that hold a capture time, most specific
+// first. "Creation Time" is the one the PNG spec defines; ImageMagick writes
+// "date:create".
+var pngTimeKeys = []string{"Creation Time", "date:create"}
+
+// metadataTimeLayouts are the formats seen in PNG text chunks. Layouts
+// without a zone are read in the configured timezone.
+var metadataTimeLayouts = []string{
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	"2006:01:02 15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+}
+
+// readMetadataTime returns the capture time stored in an image's metadata:
+// a PNG tEXt chunk or EXIF in a PNG, JPEG or WebP.
+func readMetadataTime(path string, loc *time.Location) (time.Time, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer file.Close()
+
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return time.Time{}, errNoMetadataTime
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return time.Time{}, err
+	}
+	// JPEG markers are read a byte at a time
+	r := bufio.NewReader(file)
+
+	switch {
+	case bytes.HasPrefix(header, []byte("\x89PNG\r\n\x1a\n")):
+		return pngTime(r, loc)
+	case bytes.HasPrefix(header, []byte{0xff, 0xd8}):
+		return jpegTime(r, loc)
+	case string(header[:4]) == "RIFF" && string(header[8:12]) == "WEBP":
+		return webpTime(r, loc)
+	}
+	return time.Time{}, errNoMetadataTime
+}
+
+// maxMetadataChunk caps the metadata read from one chunk, so a corrupt
+// length can't make us allocate gigabytes.
+const maxMetadataChunk = 1 << 20
+
+// pngTime reads the tEXt chunks and the eXIf chunk of a PNG.
+func pngTime(r io.Reader, loc *time.Location) (time.Time, error) {
+	br := &byteReader{r: r}
+	br.skip(8) // signature
+
+	texts := map[string]string{}
+	var exif []byte
+	for br.err == nil {
+		length := int(br.uint32())
+		typ := string(br.bytes(4))
+		if br.err != nil || typ == "IEND" {
+			break
+		}
+		switch {
+		case length > maxMetadataChunk:
+			br.skip(length)
+		case typ == "tEXt":
+			if key, value, ok := bytes.Cut(br.bytes(length), []byte{0}); ok {
+				texts[string(key)] = string(value)
+			}
+		case typ == "eXIf":
+			exif = br.bytes(length)
+		default:
+			br.skip(length)
+		}
+		br.skip(4) // CRC
+	}
+
+	for _, key := range pngTimeKeys {
+		if value, ok := texts[key]; ok {
+			if t, ok := parseMetadataTime(value, loc); ok {
+				return t, nil
+			}
+		}
+	}
+	if exif != nil {
+		return exifTime(exif, loc)
+	}
+	return time.Time{}, errNoMetadataTime
+}
+
+// jpegTime reads the EXIF APP1 segment of a JPEG.
+func jpegTime(r io.Reader, loc *time.Location) (time.Time, error) {
+	br := &byteReader{r: r}
+	br.skip(2) // SOI
+	for br.err == nil {
+		if br.byte() != 0xff {
+			break
+		}
+		marker := br.byte()
+		// Start of scan: the metadata segments are all before it
+		if marker == 0xda || marker == 0xd9 {
+			break
+		}
+		length := int(br.uint16()) - 2
+		if length < 0 {
+			break
+		}
+		if marker != 0xe1 {
+			br.skip(length)
+			continue
+		}
+		data := br.bytes(length)
+		if exif, ok := bytes.CutPrefix(data, []byte("Exif\x00\x00")); ok {
+			return exifTime(exif, loc)
+		}
+	}
+	return time.Time{}, errNoMetadataTime
+}
+
+// webpTime reads the EXIF chunk of a WebP.
+func webpTime(r io.Reader, loc *time.Location) (time.Time, error) {
+	br := &byteReader{r: r}
+	br.skip(12)
+	for br.err == nil {
+		fourCC := string(br.bytes(4))
+		size := int(br.uint32LE())
+		if br.err != nil {
+			break
+		}
+		if fourCC == "EXIF" && size <= maxMetadataChunk {
+			exif := br.bytes(size)
+			// Some writers keep the JPEG prefix
+			exif, _ = bytes.CutPrefix(exif, []byte("Exif\x00\x00"))
+			return exifTime(exif, loc)
+		}
+		// Chunks are padded to an even size
+		br.skip(size + size%2)
+	}
+	return time.Time{}, errNoMetadataTime
+}
+
+// EXIF tags holding the capture time.
+const (
+	tagDateTime           = 0x0132
+	tagExifIFD            = 0x8769
+	tagDateTimeOriginal   = 0x9003
+	tagOffsetTimeOriginal = 0x9011
+)
+
+// exifTime reads DateTimeOriginal, or DateTime when it's missing, from a TIFF
+// structured EXIF block. OffsetTimeOriginal sets the zone when present.
+func exifTime(exif []byte, loc *time.Location) (time.Time, error) {
+	if len(exif) < 8 {
+		return time.Time{}, errNoMetadataTime
+	}
+	var order binary.ByteOrder
+	switch string(exif[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return time.Time{}, fmt.Errorf("invalid EXIF byte order")
+	}
+
+	ifd0 := readIFD(exif, order, order.Uint32(exif[4:8]))
+	tags := ifd0
+	if offset, ok := ifd0[tagExifIFD]; ok && len(offset) == 4 {
+		for tag, value := range readIFD(exif, order, order.Uint32(offset)) {
+			tags[tag] = value
+		}
+	}
+
+	if offset, ok := tags[tagOffsetTimeOriginal]; ok {
+		if zone, err := time.Parse("-07:00", exifString(offset)); err == nil {
+			loc = zone.Location()
+		}
+	}
+	for _, tag := range []uint16{tagDateTimeOriginal, tagDateTime} {
+		if value, ok := tags[tag]; ok {
+			if t, err := time.ParseInLocation("2006:01:02 15:04:05", exifString(value), loc); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, errNoMetadataTime
+}
+
+// readIFD returns the raw values of the tags in the IFD at offset: ASCII
+// strings and the LONG offset of a sub-IFD. Other types are left out.
+func readIFD(exif []byte, order binary.ByteOrder, offset uint32) map[uint16][]byte {
+	tags := map[uint16][]byte{}
+	if int(offset)+2 > len(exif) {
+		return tags
+	}
+	count := int(order.Uint16(exif[offset:]))
+	for i := 0; i < count; i++ {
+		entry := int(offset) + 2 + i*12
+		if entry+12 > len(exif) {
+			break
+		}
+		tag := order.Uint16(exif[entry:])
+		typ := order.Uint16(exif[entry+2:])
+		n := int(order.Uint32(exif[entry+4:]))
+		value := exif[entry+8 : entry+12]
+
+		switch typ {
+		case 2: // ASCII, stored inline when it fits in 4 bytes
+			if n > 4 {
+				start := int(order.Uint32(value))
+				if start < 0 || start+n > len(exif) {
+					continue
+				}
+				value = exif[start : start+n]
+			} else {
+				value = value[:n]
+			}
+			tags[tag] = value
+		case 4: // LONG
+			tags[tag] = value
+		}
+	}
+	return tags
+}
+
+// exifString trims the NUL terminator and padding of an EXIF ASCII value.
+func exifString(b []byte) string {
+	return strings.TrimSpace(strings.TrimRight(string(b), "\x00"))
+}
+
+// parseMetadataTime parses a capture time in any of metadataTimeLayouts.
+func parseMetadataTime(value string, loc *time.Location) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range metadataTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// byteReader reads big-endian fields and remembers the first error.
+type byteReader struct {
+	r   io.Reader
+	err error
+}
+
+func (b *byteReader) bytes(n int) []byte {
+	if b.err != nil || n < 0 {
+		return nil
+	}
+	buf := make([]byte, n)
+	_, b.err = io.ReadFull(b.r, buf)
+	return buf
+}
+
+func (b *byteReader) skip(n int) {
+	if b.err != nil || n <= 0 {
+		return
+	}
+	_, b.err = io.CopyN(io.Discard, b.r, int64(n))
+}
+
+func (b *byteReader) byte() byte {
+	if buf := b.bytes(1); b.err == nil {
+		return buf[0]
+	}
+	return 0
+}
+
+func (b *byteReader) uint16() uint16 {
+	if buf := b.bytes(2); b.err == nil {
+		return binary.BigEndian.Uint16(buf)
+	}
+	return 0
+}
+
+func (b *byteReader) uint32() uint32 {
+	if buf := b.bytes(4); b.err == nil {
+		return binary.BigEndian.Uint32(buf)
+	}
+	return 0
+}
+
+func (b *byteReader) uint32LE() uint32 {
+	if buf := b.bytes(4); b.err == nil {
+		return binary.LittleEndian.Uint32(buf)
+	}
+	return 0
+}
@@ -0,0 +1,234 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cloneheroer/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTimestampFromFilename_Timezone(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// An evening session in New York is the next day in UTC
+	got, err := parseTimestampFromFilename("clonehero-Tripping-Billies-20251209195440.png", newYork)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 12, 10, 0, 54, 40, 0, time.UTC), got.UTC())
+}
+
+func TestCaptureTime(t *testing.T) {
+	tokyo := time.FixedZone("", 9*60*60)
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name       string
+		filename   string
+		contents   []byte
+		wantTime   time.Time
+		wantSource string
+	}{
+		{
+			name:       "file name stamp",
+			filename:   "clonehero-Song-20251209195440.png",
+			contents:   pngWithChunk(t, "tEXt", []byte("Creation Time\x002020-01-01T00:00:00Z")),
+			wantTime:   time.Date(2025, 12, 9, 19, 54, 40, 0, time.UTC),
+			wantSource: db.TimeSourceFilename,
+		},
+		{
+			name:       "PNG creation time",
+			filename:   "shot.png",
+			contents:   pngWithChunk(t, "tEXt", []byte("Creation Time\x00Tue, 09 Dec 2025 19:54:40 +0100")),
+			wantTime:   time.Date(2025, 12, 9, 18, 54, 40, 0, time.UTC),
+			wantSource: db.TimeSourceMetadata,
+		},
+		{
+			name:       "PNG ImageMagick date",
+			filename:   "shot.png",
+			contents:   pngWithChunk(t, "tEXt", []byte("date:create\x002025-12-09T19:54:40+00:00")),
+			wantTime:   time.Date(2025, 12, 9, 19, 54, 40, 0, time.UTC),
+			wantSource: db.TimeSourceMetadata,
+		},
+		{
+			name:       "PNG EXIF without a zone uses the configured one",
+			filename:   "shot.png",
+			contents:   pngWithChunk(t, "eXIf", exifBlob(binary.BigEndian, "2025:12:09 19:54:40", "")),
+			wantTime:   time.Date(2025, 12, 9, 19, 54, 40, 0, time.UTC),
+			wantSource: db.TimeSourceMetadata,
+		},
+		{
+			name:       "JPEG EXIF with an offset",
+			filename:   "shot.jpg",
+			contents:   jpegWithExif(t, exifBlob(binary.LittleEndian, "2025:12:09 19:54:40", "+09:00")),
+			wantTime:   time.Date(2025, 12, 9, 19, 54, 40, 0, tokyo),
+			wantSource: db.TimeSourceMetadata,
+		},
+		{
+			name:       "WebP EXIF",
+			filename:   "shot.webp",
+			contents:   webpWithExif(exifBlob(binary.LittleEndian, "2025:12:09 19:54:40", "")),
+			wantTime:   time.Date(2025, 12, 9, 19, 54, 40, 0, time.UTC),
+			wantSource: db.TimeSourceMetadata,
+		},
+		{
+			name:       "unrelated text falls back to the modification time",
+			filename:   "shot.png",
+			contents:   pngWithChunk(t, "tEXt", []byte("Software\x00Clone Hero")),
+			wantTime:   modTime,
+			wantSource: db.TimeSourceModTime,
+		},
+		{
+			name:       "not an image falls back to the modification time",
+			filename:   "shot.png",
+			contents:   []byte("png"),
+			wantTime:   modTime,
+			wantSource: db.TimeSourceModTime,
+		},
+	}
+
+	p := &Parser{}
+	WithTimezone(time.UTC)(p)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.filename)
+			require.NoError(t, os.WriteFile(path, tc.contents, 0644))
+			require.NoError(t, os.Chtimes(path, modTime, modTime))
+
+			got, source, err := p.captureTime(path)
+			require.NoError(t, err)
+			assert.True(t, tc.wantTime.Equal(got), "got %v, want %v", got, tc.wantTime)
+			assert.Equal(t, tc.wantSource, source)
+		})
+	}
+}
+
+func TestCaptureTime_Timezone(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "shot.png")
+	require.NoError(t, os.WriteFile(path, pngWithChunk(t, "eXIf", exifBlob(binary.LittleEndian, "2025:12:09 19:54:40", "")), 0644))
+
+	p := &Parser{}
+	WithTimezone(newYork)(p)
+	got, _, err := p.captureTime(path)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 12, 10, 0, 54, 40, 0, time.UTC), got.UTC())
+}
+
+func TestCaptureTime_DefaultTimezone(t *testing.T) {
+	// Clone Hero stamps file names in the local time of the machine it runs on
+	assert.Equal(t, time.Local, (&Parser{}).location())
+}
+
+func TestCaptureTime_MissingFile(t *testing.T) {
+	_, _, err := (&Parser{}).captureTime(filepath.Join(t.TempDir(), "missing.png"))
+	assert.Error(t, err)
+}
+
+// pngWithChunk encodes a tiny PNG with an extra chunk after IHDR.
+func pngWithChunk(t *testing.T, typ string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 2, 2))))
+	encoded := buf.Bytes()
+
+	// Signature, then IHDR: length, type, 13 bytes of data and the CRC
+	ihdrEnd := 8 + 4 + 4 + 13 + 4
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, typ...)
+	chunk = append(chunk, data...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(append([]byte(typ), data...)))
+
+	out := append([]byte{}, encoded[:ihdrEnd]...)
+	out = append(out, chunk...)
+	return append(out, encoded[ihdrEnd:]...)
+}
+
+// jpegWithExif encodes a tiny JPEG with an EXIF APP1 segment after SOI.
+func jpegWithExif(t *testing.T, exif []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 2, 2)), nil))
+	encoded := buf.Bytes()
+
+	payload := append([]byte("Exif\x00\x00"), exif...)
+	segment := []byte{0xff, 0xe1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, encoded[:2]...)
+	out = append(out, segment...)
+	return append(out, encoded[2:]...)
+}
+
+// webpWithExif builds a RIFF container with only an EXIF chunk, enough for
+// the metadata reader.
+func webpWithExif(exif []byte) []byte {
+	chunk := append([]byte("EXIF"), binary.LittleEndian.AppendUint32(nil, uint32(len(exif)))...)
+	chunk = append(chunk, exif...)
+	if len(exif)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	out := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(4+len(chunk)))...)
+	out = append(out, "WEBP"...)
+	return append(out, chunk...)
+}
+
+// exifBlob builds a TIFF structured EXIF block whose IFD0 points to an EXIF
+// IFD holding DateTimeOriginal and, when offset isn't empty,
+// OffsetTimeOriginal.
+func exifBlob(order binary.AppendByteOrder, dateTime, offset string) []byte {
+	type entry struct {
+		tag   uint16
+		value string
+	}
+	entries := []entry{{tagDateTimeOriginal, dateTime}}
+	if offset != "" {
+		entries = append(entries, entry{tagOffsetTimeOriginal, offset})
+	}
+
+	var b []byte
+	if order.String() == binary.LittleEndian.String() {
+		b = append(b, "II"...)
+	} else {
+		b = append(b, "MM"...)
+	}
+	b = order.AppendUint16(b, 42)
+	b = order.AppendUint32(b, 8)
+
+	// IFD0 at 8 with one entry: the EXIF IFD pointer
+	exifIFD := uint32(8 + 2 + 12 + 4)
+	b = order.AppendUint16(b, 1)
+	b = order.AppendUint16(b, tagExifIFD)
+	b = order.AppendUint16(b, 4)
+	b = order.AppendUint32(b, 1)
+	b = order.AppendUint32(b, exifIFD)
+	b = order.AppendUint32(b, 0)
+
+	// EXIF IFD, with the strings after it
+	values := exifIFD + 2 + uint32(len(entries))*12 + 4
+	b = order.AppendUint16(b, uint16(len(entries)))
+	var data []byte
+	for _, e := range entries {
+		value := append([]byte(e.value), 0)
+		b = order.AppendUint16(b, e.tag)
+		b = order.AppendUint16(b, 2)
+		b = order.AppendUint32(b, uint32(len(value)))
+		b = order.AppendUint32(b, values+uint32(len(data)))
+		data = append(data, value...)
+	}
+	b = order.AppendUint32(b, 0)
+	return append(b, data...)
+}
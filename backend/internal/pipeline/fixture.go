@@ -0,0 +1,52 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cloneheroer/internal/db"
+)
+
+// FixtureExtractor is a ScoreExtractor that never looks at the image. It
+// returns the score stored in a JSON sidecar next to it, so the pipeline can
+// run on machines without Tesseract.
+type FixtureExtractor struct{}
+
+// NewFixtureExtractor creates a sidecar-backed extractor.
+func NewFixtureExtractor() *FixtureExtractor {
+	return &FixtureExtractor{}
+}
+
+// SidecarPath returns where the sidecar for imagePath lives: the same path
+// with a .json extension.
+func SidecarPath(imagePath string) string {
+	return strings.TrimSuffix(imagePath, filepath.Ext(imagePath)) + ".json"
+}
+
+// ParseImage reads the sidecar of imagePath. A sidecar without created_at
+// gets the image's modification time, like a screenshot without a timestamp
+// in its name.
+func (f *FixtureExtractor) ParseImage(imagePath string) (*db.CreateScoreData, error) {
+	info, err := os.Stat(imagePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+
+	contents, err := os.ReadFile(SidecarPath(imagePath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sidecar: %w", err)
+	}
+
+	var data db.CreateScoreData
+	if err := json.Unmarshal(contents, &data); err != nil {
+		return nil, fmt.Errorf("failed to parse sidecar %s: %w", filepath.Base(SidecarPath(imagePath)), err)
+	}
+	if data.CreatedAt.IsZero() {
+		data.CreatedAt = info.ModTime()
+		data.CreatedAtSource = db.TimeSourceModTime
+	}
+	return &data, nil
+}
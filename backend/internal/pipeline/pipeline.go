@@ -0,0 +1,179 @@
+// Package pipeline turns screenshot files into stored scores.
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"cloneheroer/internal/db"
+	"cloneheroer/internal/imagehash"
+	"cloneheroer/internal/skip"
+	"cloneheroer/internal/validate"
+)
+
+// ScoreExtractor reads the score data out of a screenshot.
+// *parser.Parser is the OCR-backed implementation.
+type ScoreExtractor interface {
+	ParseImage(imagePath string) (*db.CreateScoreData, error)
+}
+
+// ScoreStore persists extracted scores. *db.Repo is the Postgres implementation.
+type ScoreStore interface {
+	CreateScore(ctx context.Context, data db.CreateScoreData) (int64, error)
+}
+
+// ScreenClassifier tells results screens apart from other screenshots.
+// *parser.Parser is the OCR-backed implementation.
+type ScreenClassifier interface {
+	IsResultsScreen(imagePath string) (bool, error)
+}
+
+// DuplicateFinder looks up the screenshot hashes of stored scores.
+// *db.Repo is the Postgres implementation.
+type DuplicateFinder interface {
+	ImageHashesBetween(ctx context.Context, from, to time.Time) ([]db.ImageHashRef, error)
+}
+
+// DuplicatePolicy says which screenshots count as second captures of a stored
+// score and what happens to them.
+type DuplicatePolicy struct {
+	// Window is how far apart in time the two scores may be. Two captures of
+	// one results screen are seconds apart; playing another song takes minutes
+	Window time.Duration
+	// MaxDistance is how many bits the screenshots' hashes may differ in
+	MaxDistance int
+	// Skip drops duplicates with skip.Err instead of storing them
+	// flagged
+	Skip bool
+}
+
+// Option configures a file processor.
+type Option func(*fileProcessor)
+
+// WithDuplicates checks every screenshot against the scores finder returns
+// and flags or skips the ones policy calls duplicates.
+func WithDuplicates(finder DuplicateFinder, policy DuplicatePolicy) Option {
+	return func(fp *fileProcessor) {
+		fp.duplicates = finder
+		fp.policy = policy
+	}
+}
+
+// WithClassifier checks every screenshot with classifier before extracting
+// its score. Screenshots that aren't results screens are skipped with
+// skip.Err.
+func WithClassifier(classifier ScreenClassifier) Option {
+	return func(fp *fileProcessor) {
+		fp.classifier = classifier
+	}
+}
+
+type fileProcessor struct {
+	extractor  ScoreExtractor
+	store      ScoreStore
+	classifier ScreenClassifier
+	duplicates DuplicateFinder
+	policy     DuplicatePolicy
+
+	// storeMu makes looking for a duplicate and storing the score one step,
+	// so two captures handled by different workers can't both miss each other
+	storeMu sync.Mutex
+}
+
+// NewFileProcessor returns the watcher callback that extracts the score from
+// a new file, validates it and stores it with any warnings and the
+// screenshot's perceptual hash.
+func NewFileProcessor(ctx context.Context, extractor ScoreExtractor, store ScoreStore, opts ...Option) func(string) error {
+	fp := &fileProcessor{extractor: extractor, store: store}
+	for _, opt := range opts {
+		opt(fp)
+	}
+
+	return func(filePath string) error {
+		if fp.classifier != nil {
+			isResults, err := fp.classifier.IsResultsScreen(filePath)
+			if err != nil {
+				return fmt.Errorf("failed to classify image: %w", err)
+			}
+			if !isResults {
+				return fmt.Errorf("%s is not a results screen: %w", filepath.Base(filePath), skip.Err)
+			}
+		}
+
+		log.Printf("parsing image: %s", filePath)
+		scoreData, err := fp.extractor.ParseImage(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to parse image: %w", err)
+		}
+
+		scoreData.Warnings = validate.Score(*scoreData)
+		for _, w := range scoreData.Warnings {
+			log.Printf("warning: %s: %s", filePath, w.Message)
+		}
+
+		// A score is still worth storing when its image can't be hashed
+		hash, hashErr := imagehash.File(filePath)
+		if hashErr != nil {
+			log.Printf("warning: failed to hash %s: %v", filePath, hashErr)
+		}
+
+		fp.storeMu.Lock()
+		defer fp.storeMu.Unlock()
+
+		if hashErr == nil {
+			scoreData.ImageHash = hash.String()
+			duplicateOf, err := fp.findDuplicate(ctx, hash, scoreData.CreatedAt)
+			if err != nil {
+				return fmt.Errorf("failed to look for duplicates: %w", err)
+			}
+			if duplicateOf != nil {
+				if fp.policy.Skip {
+					return fmt.Errorf("%s is a duplicate of score %d: %w", filepath.Base(filePath), *duplicateOf, skip.Err)
+				}
+				log.Printf("warning: %s looks like a duplicate of score %d", filePath, *duplicateOf)
+				scoreData.DuplicateOf = duplicateOf
+			}
+		}
+
+		log.Printf("creating score for: %s - %s", scoreData.Artist, scoreData.SongName)
+		scoreID, err := fp.store.CreateScore(ctx, *scoreData)
+		if err != nil {
+			return fmt.Errorf("failed to create score: %w", err)
+		}
+
+		log.Printf("successfully created score with ID: %d", scoreID)
+		return nil
+	}
+}
+
+// findDuplicate returns the ID of the stored score within the policy's window
+// whose screenshot hash is closest to hash, if it's close enough.
+func (fp *fileProcessor) findDuplicate(ctx context.Context, hash imagehash.Hash, createdAt time.Time) (*int64, error) {
+	if fp.duplicates == nil || fp.policy.Window <= 0 {
+		return nil, nil
+	}
+
+	refs, err := fp.duplicates.ImageHashesBetween(ctx, createdAt.Add(-fp.policy.Window), createdAt.Add(fp.policy.Window))
+	if err != nil {
+		return nil, err
+	}
+
+	var closest *int64
+	best := fp.policy.MaxDistance + 1
+	for _, ref := range refs {
+		stored, err := imagehash.Parse(ref.ImageHash)
+		if err != nil {
+			log.Printf("warning: score %d: %v", ref.ScoreID, err)
+			continue
+		}
+		if d := hash.Distance(stored); d < best {
+			id := ref.ScoreID
+			closest, best = &id, d
+		}
+	}
+	return closest, nil
+}
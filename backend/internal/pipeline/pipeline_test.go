@@ -0,0 +1,496 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"cloneheroer/internal/db"
+	"cloneheroer/internal/db/dbtest"
+	"cloneheroer/internal/skip"
+	"cloneheroer/internal/validate"
+	"cloneheroer/internal/watcher"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	_testDataPath    = "../../../testdata"
+	_trippingBillies = "clonehero-Tripping-Billies-20251209195440"
+	_discography     = "clonehero-Discography-20250930000459"
+)
+
+func TestFixtureExtractor(t *testing.T) {
+	dir := t.TempDir()
+	imagePath := copyFixture(t, dir, _trippingBillies)
+
+	data, err := NewFixtureExtractor().ParseImage(imagePath)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Dave Matthews Band", data.Artist)
+	assert.Equal(t, "Tripping Billies", data.SongName)
+	assert.Equal(t, int64(447253), data.TotalScore)
+	assert.Equal(t, time.Date(2025, 12, 9, 19, 54, 40, 0, time.UTC), data.CreatedAt)
+	require.Len(t, data.Players, 2)
+	assert.Equal(t, "bass", data.Players[1].Instrument)
+	assert.Equal(t, 267, data.Players[1].Overhits)
+}
+
+func TestFixtureExtractor_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("missing image", func(t *testing.T) {
+		_, err := NewFixtureExtractor().ParseImage(filepath.Join(dir, "nonexistent.png"))
+		assert.Error(t, err)
+	})
+
+	t.Run("missing sidecar", func(t *testing.T) {
+		imagePath := filepath.Join(dir, "no-sidecar.png")
+		require.NoError(t, os.WriteFile(imagePath, []byte("png"), 0644))
+
+		_, err := NewFixtureExtractor().ParseImage(imagePath)
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid sidecar", func(t *testing.T) {
+		imagePath := filepath.Join(dir, "bad-sidecar.png")
+		require.NoError(t, os.WriteFile(imagePath, []byte("png"), 0644))
+		require.NoError(t, os.WriteFile(SidecarPath(imagePath), []byte("{"), 0644))
+
+		_, err := NewFixtureExtractor().ParseImage(imagePath)
+		assert.Error(t, err)
+	})
+
+	t.Run("no created_at uses the file time", func(t *testing.T) {
+		imagePath := filepath.Join(dir, "no-time.png")
+		require.NoError(t, os.WriteFile(imagePath, []byte("png"), 0644))
+		require.NoError(t, os.WriteFile(SidecarPath(imagePath), []byte(`{"artist": "Metallica"}`), 0644))
+		modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		require.NoError(t, os.Chtimes(imagePath, modTime, modTime))
+
+		data, err := NewFixtureExtractor().ParseImage(imagePath)
+		require.NoError(t, err)
+		assert.True(t, modTime.Equal(data.CreatedAt))
+		assert.Equal(t, db.TimeSourceModTime, data.CreatedAtSource)
+	})
+}
+
+func TestSidecarPath(t *testing.T) {
+	assert.Equal(t, "/shots/clonehero-x-20251209195440.json", SidecarPath("/shots/clonehero-x-20251209195440.png"))
+	assert.Equal(t, "shot.json", SidecarPath("shot.jpeg"))
+}
+
+func TestFileProcessor(t *testing.T) {
+	data := &db.CreateScoreData{Artist: "Hail The Sun", SongName: "Discography"}
+
+	testCases := []struct {
+		name      string
+		extractor ScoreExtractor
+		store     *memStore
+		wantError bool
+		wantSaved int
+		wantRules []string
+	}{
+		{
+			name:      "stores the extracted score with its warnings",
+			extractor: stubExtractor{data: data},
+			store:     &memStore{},
+			wantSaved: 1,
+			wantRules: []string{validate.RuleMissingField, validate.RuleMissingField},
+		},
+		{
+			name:      "extraction fails",
+			extractor: stubExtractor{err: errors.New("no text")},
+			store:     &memStore{},
+			wantError: true,
+		},
+		{
+			name:      "store fails",
+			extractor: stubExtractor{data: data},
+			store:     &memStore{err: errors.New("connection refused")},
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			process := NewFileProcessor(context.Background(), tc.extractor, tc.store)
+
+			err := process("/shots/whatever.png")
+			if tc.wantError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			saved := tc.store.all()
+			require.Len(t, saved, tc.wantSaved)
+			if tc.wantSaved > 0 {
+				var rules []string
+				for _, w := range saved[0].Warnings {
+					rules = append(rules, w.Rule)
+				}
+				assert.Equal(t, tc.wantRules, rules)
+			}
+		})
+	}
+}
+
+func TestFileProcessor_Classifier(t *testing.T) {
+	data := &db.CreateScoreData{Artist: "Hail The Sun", SongName: "Discography"}
+
+	testCases := []struct {
+		name        string
+		classifier  stubClassifier
+		wantSkipped bool
+		wantError   bool
+		wantSaved   int
+	}{
+		{name: "results screen is stored", classifier: stubClassifier{isResults: true}, wantSaved: 1},
+		{name: "other screens are skipped", classifier: stubClassifier{}, wantSkipped: true, wantError: true},
+		{name: "classification fails", classifier: stubClassifier{err: errors.New("unreadable")}, wantError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &memStore{}
+			process := NewFileProcessor(context.Background(), stubExtractor{data: data}, store, WithClassifier(tc.classifier))
+
+			err := process("/shots/whatever.png")
+			if tc.wantError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.wantSkipped, errors.Is(err, skip.Err))
+			assert.Len(t, store.all(), tc.wantSaved)
+		})
+	}
+}
+
+func TestFileProcessor_Duplicates(t *testing.T) {
+	at := time.Date(2025, 9, 30, 0, 4, 59, 0, time.UTC)
+	discography := filepath.Join(_testDataPath, "scores", _discography+".png")
+	secondCapture := filepath.Join(_testDataPath, "scores", _discography+"-50.png")
+	trippingBillies := filepath.Join(_testDataPath, "scores", _trippingBillies+".png")
+	policy := &DuplicatePolicy{Window: 2 * time.Minute, MaxDistance: 10}
+
+	type shot struct {
+		path string
+		at   time.Time
+	}
+	testCases := []struct {
+		name            string
+		shots           []shot
+		policy          *DuplicatePolicy
+		wantDuplicateOf []*int64
+		wantSkipped     int
+	}{
+		{
+			name:            "second capture is flagged",
+			shots:           []shot{{discography, at}, {secondCapture, at.Add(time.Second)}},
+			policy:          policy,
+			wantDuplicateOf: []*int64{nil, dbtest.Ptr(int64(1))},
+		},
+		{
+			name:            "second capture is skipped",
+			shots:           []shot{{discography, at}, {secondCapture, at}},
+			policy:          &DuplicatePolicy{Window: time.Minute, MaxDistance: 10, Skip: true},
+			wantDuplicateOf: []*int64{nil},
+			wantSkipped:     1,
+		},
+		{
+			name:            "different results screen",
+			shots:           []shot{{discography, at}, {trippingBillies, at}},
+			policy:          policy,
+			wantDuplicateOf: []*int64{nil, nil},
+		},
+		{
+			name:            "outside the window",
+			shots:           []shot{{discography, at}, {secondCapture, at.Add(5 * time.Minute)}},
+			policy:          policy,
+			wantDuplicateOf: []*int64{nil, nil},
+		},
+		{
+			name:            "not checked by default",
+			shots:           []shot{{discography, at}, {secondCapture, at}},
+			wantDuplicateOf: []*int64{nil, nil},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &memStore{}
+			var opts []Option
+			if tc.policy != nil {
+				// The store is the finder, like the repo in production
+				opts = append(opts, WithDuplicates(store, *tc.policy))
+			}
+			times := map[string]time.Time{}
+			for _, s := range tc.shots {
+				times[s.path] = s.at
+			}
+			extractor := extractorFunc(func(path string) (*db.CreateScoreData, error) {
+				return &db.CreateScoreData{Artist: "Hail The Sun", SongName: "Discography", CreatedAt: times[path]}, nil
+			})
+
+			process := NewFileProcessor(context.Background(), extractor, store, opts...)
+			skipped := 0
+			for _, s := range tc.shots {
+				err := process(s.path)
+				if errors.Is(err, skip.Err) {
+					skipped++
+					continue
+				}
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.wantSkipped, skipped)
+
+			saved := store.all()
+			require.Len(t, saved, len(tc.wantDuplicateOf))
+			for i, want := range tc.wantDuplicateOf {
+				assert.Equal(t, want, saved[i].DuplicateOf, "score %d", i+1)
+				assert.Len(t, saved[i].ImageHash, 64, "every stored score has its screenshot hash")
+			}
+		})
+	}
+}
+
+func TestFileProcessor_ConcurrentDuplicates(t *testing.T) {
+	at := time.Date(2025, 9, 30, 0, 4, 59, 0, time.UTC)
+	shots := []string{
+		filepath.Join(_testDataPath, "scores", _discography+".png"),
+		filepath.Join(_testDataPath, "scores", _discography+"-50.png"),
+	}
+	store := &memStore{}
+	extractor := extractorFunc(func(string) (*db.CreateScoreData, error) {
+		return &db.CreateScoreData{Artist: "Hail The Sun", SongName: "Discography", CreatedAt: at}, nil
+	})
+	process := NewFileProcessor(context.Background(), extractor, store, WithDuplicates(store, DuplicatePolicy{Window: time.Minute, MaxDistance: 10}))
+
+	// Watcher workers handle both captures at once
+	var wg sync.WaitGroup
+	for range 4 {
+		for _, shot := range shots {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, process(shot))
+			}()
+		}
+	}
+	wg.Wait()
+
+	originals := 0
+	for _, s := range store.all() {
+		if s.DuplicateOf == nil {
+			originals++
+		}
+	}
+	assert.Equal(t, 1, originals, "every capture after the first is flagged")
+}
+
+func TestWatcherToStore_ExistingFiles(t *testing.T) {
+	dirs := newWatchDirs(t)
+	copyFixture(t, dirs.watch, _trippingBillies)
+	copyFixture(t, dirs.watch, _discography)
+
+	store := &memStore{}
+	startWatcher(t, dirs, NewFileProcessor(context.Background(), NewFixtureExtractor(), store))
+
+	// Files already in the folder are processed before Start returns
+	scores := store.all()
+	require.Len(t, scores, 2)
+	songs := []string{scores[0].SongName, scores[1].SongName}
+	assert.ElementsMatch(t, []string{"Tripping Billies", "Discography"}, songs)
+	assert.Empty(t, scores[0].Warnings, "the fixtures are consistent")
+	assert.Empty(t, scores[1].Warnings, "the fixtures are consistent")
+
+	assert.FileExists(t, filepath.Join(dirs.processed, _trippingBillies+".png"))
+	assert.FileExists(t, filepath.Join(dirs.processed, _discography+".png"))
+	assert.NoFileExists(t, filepath.Join(dirs.watch, _trippingBillies+".png"))
+}
+
+func TestWatcherToStore_NewFile(t *testing.T) {
+	dirs := newWatchDirs(t)
+	store := &memStore{}
+	startWatcher(t, dirs, NewFileProcessor(context.Background(), NewFixtureExtractor(), store))
+
+	// Sidecar first so it's there when the watcher sees the image
+	copyFile(t, filepath.Join(_testDataPath, "extractor", _discography+".json"), filepath.Join(dirs.watch, _discography+".json"))
+	copyFile(t, filepath.Join(_testDataPath, "scores", _discography+".png"), filepath.Join(dirs.watch, _discography+".png"))
+
+	require.Eventually(t, func() bool { return len(store.all()) == 1 }, 10*time.Second, 100*time.Millisecond)
+	assert.Equal(t, "Hail The Sun", store.all()[0].Artist)
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(filepath.Join(dirs.processed, _discography+".png"))
+		return err == nil
+	}, 5*time.Second, 100*time.Millisecond)
+}
+
+func TestWatcherToStore_SkippedScreen(t *testing.T) {
+	dirs := newWatchDirs(t)
+	copyFixture(t, dirs.watch, _discography)
+
+	store := &memStore{}
+	startWatcher(t, dirs, NewFileProcessor(context.Background(), NewFixtureExtractor(), store, WithClassifier(stubClassifier{})))
+
+	assert.Empty(t, store.all())
+	assert.FileExists(t, filepath.Join(dirs.skipped, _discography+".png"))
+	assert.NoFileExists(t, filepath.Join(dirs.failed, _discography+".png"))
+}
+
+func TestWatcherToStore_FailedExtraction(t *testing.T) {
+	dirs := newWatchDirs(t)
+	copyFile(t, filepath.Join(_testDataPath, "scores", _discography+".png"), filepath.Join(dirs.watch, _discography+".png"))
+
+	store := &memStore{}
+	startWatcher(t, dirs, NewFileProcessor(context.Background(), NewFixtureExtractor(), store))
+
+	assert.Empty(t, store.all())
+	assert.FileExists(t, filepath.Join(dirs.failed, _discography+".png"))
+}
+
+// TestWatcherToRepo runs the whole pipeline against Postgres. It needs
+// TEST_DATABASE_URL pointing at a database it may migrate and write to.
+func TestWatcherToRepo(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	dirs := newWatchDirs(t)
+	copyFixture(t, dirs.watch, _trippingBillies)
+	startWatcher(t, dirs, NewFileProcessor(ctx, NewFixtureExtractor(), repo))
+
+	scores, err := repo.ListScores(ctx, 100, 0, db.ScoreFilter{})
+	require.NoError(t, err)
+
+	var found *db.Score
+	for i := range scores {
+		if scores[i].Artist == "Dave Matthews Band" && scores[i].TotalScore != nil && *scores[i].TotalScore == 447253 {
+			found = &scores[i]
+		}
+	}
+	require.NotNil(t, found, "the score should have been stored")
+	require.NotNil(t, found.StarsAchieved)
+	assert.Equal(t, 4, *found.StarsAchieved)
+	assert.Empty(t, found.Warnings)
+	assert.NotNil(t, found.ImageHash)
+}
+
+type watchDirs struct {
+	watch, processed, failed, skipped string
+}
+
+func newWatchDirs(t *testing.T) watchDirs {
+	t.Helper()
+
+	root := t.TempDir()
+	return watchDirs{
+		watch:     filepath.Join(root, "watch"),
+		processed: filepath.Join(root, "processed"),
+		failed:    filepath.Join(root, "failed"),
+		skipped:   filepath.Join(root, "skipped"),
+	}
+}
+
+func startWatcher(t *testing.T, dirs watchDirs, process func(string) error) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w, err := watcher.NewWatcher(dirs.watch, dirs.processed, dirs.failed, process, watcher.WithSkippedDir(dirs.skipped))
+	require.NoError(t, err)
+	require.NoError(t, w.Start(ctx))
+	t.Cleanup(func() {
+		cancel()
+		w.Close()
+	})
+}
+
+// copyFixture copies a screenshot and its sidecar into dir and returns the
+// image's new path.
+func copyFixture(t *testing.T, dir, name string) string {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	imagePath := filepath.Join(dir, name+".png")
+	copyFile(t, filepath.Join(_testDataPath, "scores", name+".png"), imagePath)
+	copyFile(t, filepath.Join(_testDataPath, "extractor", name+".json"), SidecarPath(imagePath))
+	return imagePath
+}
+
+func copyFile(t *testing.T, src, dst string) {
+	t.Helper()
+
+	contents, err := os.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(dst), 0755))
+	require.NoError(t, os.WriteFile(dst, contents, 0644))
+}
+
+func newTestRepo(t *testing.T) *db.Repo {
+	t.Helper()
+	return db.NewRepo(dbtest.Pool(t))
+}
+
+type extractorFunc func(string) (*db.CreateScoreData, error)
+
+func (f extractorFunc) ParseImage(imagePath string) (*db.CreateScoreData, error) {
+	return f(imagePath)
+}
+
+type stubExtractor struct {
+	data *db.CreateScoreData
+	err  error
+}
+
+func (s stubExtractor) ParseImage(string) (*db.CreateScoreData, error) {
+	return s.data, s.err
+}
+
+type stubClassifier struct {
+	isResults bool
+	err       error
+}
+
+func (s stubClassifier) IsResultsScreen(string) (bool, error) {
+	return s.isResults, s.err
+}
+
+// memStore is an in-memory ScoreStore.
+type memStore struct {
+	mu     sync.Mutex
+	scores []db.CreateScoreData
+	err    error
+}
+
+func (m *memStore) CreateScore(_ context.Context, data db.CreateScoreData) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return 0, m.err
+	}
+	m.scores = append(m.scores, data)
+	return int64(len(m.scores)), nil
+}
+
+func (m *memStore) ImageHashesBetween(_ context.Context, from, to time.Time) ([]db.ImageHashRef, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []db.ImageHashRef
+	for i, s := range m.scores {
+		if s.ImageHash != "" && s.DuplicateOf == nil && !s.CreatedAt.Before(from) && !s.CreatedAt.After(to) {
+			out = append(out, db.ImageHashRef{ScoreID: int64(i + 1), ImageHash: s.ImageHash, CreatedAt: s.CreatedAt})
+		}
+	}
+	return out, nil
+}
+
+func (m *memStore) all() []db.CreateScoreData {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]db.CreateScoreData(nil), m.scores...)
+}
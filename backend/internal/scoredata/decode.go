@@ -0,0 +1,176 @@
+// Package scoredata reads Clone Hero's scoredata.bin, the game's own record
+// of the best score on every chart, and ingests it as scores.
+package scoredata
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"cloneheroer/internal/db"
+)
+
+// scoredata.bin is little-endian throughout:
+//
+//	int32   version
+//	int32   chart count
+//	chart count times:
+//	  [16]byte  chart hash (MD5 of notes.mid or notes.chart)
+//	  uint8     instrument count
+//	  int32     play count
+//	  instrument count times:
+//	    int16   instrument
+//	    uint8   difficulty
+//	    uint32  notes hit
+//	    uint32  total notes
+//	    uint8   stars
+//	    int32   unknown, always 1 so far
+//	    int32   score
+
+// maxCharts guards against allocating for a corrupt chart count.
+const maxCharts = 1 << 20
+
+// File is a decoded scoredata.bin.
+type File struct {
+	Version int32
+	Charts  []Chart
+}
+
+// Chart holds the best score on each instrument and difficulty played on one
+// chart.
+type Chart struct {
+	Hash      string // lowercase hex
+	PlayCount int32
+	Scores    []Score
+}
+
+// Score is the best run on one instrument and difficulty.
+type Score struct {
+	Instrument int16
+	Difficulty uint8
+	NotesHit   uint32
+	TotalNotes uint32
+	Stars      uint8
+	Unknown    int32
+	Score      int32
+}
+
+// Instrument names matching the ones OCR stores in db.Player.Instrument.
+var instrumentNames = map[int16]string{
+	0: db.InstrumentGuitar,
+	1: db.InstrumentBass,
+	2: db.InstrumentRhythm,
+	3: db.InstrumentCoop,
+	4: "ghl guitar",
+	5: "ghl bass",
+	6: db.InstrumentDrums,
+	7: db.InstrumentKeys,
+	8: "band",
+}
+
+var difficultyNames = []string{"Easy", "Medium", "Hard", "Expert"}
+
+// InstrumentName returns the stored name of the score's instrument.
+func (s Score) InstrumentName() string {
+	if name, ok := instrumentNames[s.Instrument]; ok {
+		return name
+	}
+	return fmt.Sprintf("instrument %d", s.Instrument)
+}
+
+// DifficultyName returns "Easy" through "Expert".
+func (s Score) DifficultyName() string {
+	if int(s.Difficulty) < len(difficultyNames) {
+		return difficultyNames[s.Difficulty]
+	}
+	return fmt.Sprintf("difficulty %d", s.Difficulty)
+}
+
+// Accuracy returns the percentage of notes hit, 0-100.
+func (s Score) Accuracy() float64 {
+	if s.TotalNotes == 0 {
+		return 0
+	}
+	return 100 * float64(s.NotesHit) / float64(s.TotalNotes)
+}
+
+// FullCombo reports whether every note was hit. scoredata.bin doesn't record
+// overstrums, so like OCR'd scores they don't break a full combo here.
+func (s Score) FullCombo() bool {
+	return s.TotalNotes > 0 && s.NotesHit == s.TotalNotes
+}
+
+// ReadFile decodes the scoredata.bin at path.
+func ReadFile(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open scoredata: %w", err)
+	}
+	defer f.Close()
+
+	return Decode(bufio.NewReader(f))
+}
+
+// Decode reads a scoredata.bin from r.
+func Decode(r io.Reader) (*File, error) {
+	var header struct {
+		Version int32
+		Count   int32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", unexpectedEOF(err))
+	}
+	if header.Count < 0 || header.Count > maxCharts {
+		return nil, fmt.Errorf("invalid chart count %d", header.Count)
+	}
+
+	file := &File{Version: header.Version, Charts: make([]Chart, 0, header.Count)}
+	for i := int32(0); i < header.Count; i++ {
+		chart, err := decodeChart(r)
+		if err != nil {
+			return nil, fmt.Errorf("chart %d: %w", i, unexpectedEOF(err))
+		}
+		file.Charts = append(file.Charts, chart)
+	}
+	return file, nil
+}
+
+func decodeChart(r io.Reader) (Chart, error) {
+	var header struct {
+		Hash        [16]byte
+		Instruments uint8
+		PlayCount   int32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return Chart{}, err
+	}
+
+	chart := Chart{
+		Hash:      hex.EncodeToString(header.Hash[:]),
+		PlayCount: header.PlayCount,
+		Scores:    make([]Score, header.Instruments),
+	}
+	if err := binary.Read(r, binary.LittleEndian, chart.Scores); err != nil {
+		return Chart{}, err
+	}
+	// Counts that don't add up mean the record layout was misread
+	for _, score := range chart.Scores {
+		if score.NotesHit > score.TotalNotes {
+			return Chart{}, fmt.Errorf("%d notes hit of %d", score.NotesHit, score.TotalNotes)
+		}
+	}
+	return chart, nil
+}
+
+// unexpectedEOF reports a file that ends mid-record as truncated rather than
+// as a clean end of input.
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,131 @@
+package scoredata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const _testDataPath = "../../../testdata"
+
+func TestReadFile(t *testing.T) {
+	file, err := ReadFile(filepath.Join(_testDataPath, "scoredata", "scoredata.bin"))
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(20211224), file.Version)
+	require.Len(t, file.Charts, 4)
+
+	tripping := file.Charts[1]
+	assert.Equal(t, "d1387f636f3c7247dacaddcb1aab74f9", tripping.Hash)
+	assert.Equal(t, int32(3), tripping.PlayCount)
+	require.Len(t, tripping.Scores, 2)
+	assert.Equal(t, Score{Instrument: 6, Difficulty: 3, NotesHit: 1773, TotalNotes: 1852, Stars: 4, Unknown: 1, Score: 378745}, tripping.Scores[0])
+	assert.Equal(t, "drums", tripping.Scores[0].InstrumentName())
+	assert.Equal(t, "Expert", tripping.Scores[0].DifficultyName())
+	assert.Equal(t, "bass", tripping.Scores[1].InstrumentName())
+	assert.Equal(t, "Hard", tripping.Scores[1].DifficultyName())
+
+	// Long charts have more notes than 16 bits hold
+	discography := file.Charts[3]
+	require.Len(t, discography.Scores, 1)
+	assert.Equal(t, uint32(115985), discography.Scores[0].NotesHit)
+	assert.Equal(t, uint32(124194), discography.Scores[0].TotalNotes)
+	assert.Equal(t, int32(18951375), discography.Scores[0].Score)
+}
+
+// TestReadFile_Exported decodes a scoredata.bin exported by Clone Hero
+// itself, when one has been dropped into testdata/scoredata/exported, and
+// checks every record is plausible.
+func TestReadFile_Exported(t *testing.T) {
+	path := filepath.Join(_testDataPath, "scoredata", "exported", "scoredata.bin")
+	if _, err := os.Stat(path); err != nil {
+		t.Skip("no exported scoredata.bin in testdata/scoredata/exported")
+	}
+
+	file, err := ReadFile(path)
+	require.NoError(t, err)
+	require.NotEmpty(t, file.Charts)
+	for _, chart := range file.Charts {
+		for _, score := range chart.Scores {
+			assert.LessOrEqual(t, score.NotesHit, score.TotalNotes, chart.Hash)
+			assert.GreaterOrEqual(t, score.Score, int32(0), chart.Hash)
+			assert.LessOrEqual(t, score.Stars, uint8(7), chart.Hash)
+			assert.Contains(t, difficultyNames, score.DifficultyName(), chart.Hash)
+		}
+	}
+}
+
+func TestReadFile_Truncated(t *testing.T) {
+	_, err := ReadFile(filepath.Join(_testDataPath, "scoredata", "scoredata-truncated.bin"))
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestDecode(t *testing.T) {
+	testCases := []struct {
+		name      string
+		data      []byte
+		wantError bool
+		charts    int
+	}{
+		{name: "empty file", data: nil, wantError: true},
+		{name: "no charts", data: header(1, 0), charts: 0},
+		{name: "negative count", data: header(1, -1), wantError: true},
+		{name: "count past the end", data: header(1, 2), wantError: true},
+		{name: "chart without scores", data: append(header(1, 1), make([]byte, 16+1+4)...), charts: 1},
+		{name: "more notes hit than notes", data: append(append(header(1, 1), chartHeader(1)...), record(10, 9)...), wantError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			file, err := Decode(bytes.NewReader(tc.data))
+			if tc.wantError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, file.Charts, tc.charts)
+		})
+	}
+}
+
+func TestScore_Names(t *testing.T) {
+	assert.Equal(t, "keys", Score{Instrument: 7}.InstrumentName())
+	assert.Equal(t, "instrument 42", Score{Instrument: 42}.InstrumentName())
+	assert.Equal(t, "Easy", Score{Difficulty: 0}.DifficultyName())
+	assert.Equal(t, "difficulty 9", Score{Difficulty: 9}.DifficultyName())
+}
+
+func TestScore_Accuracy(t *testing.T) {
+	assert.InDelta(t, 95.73, Score{NotesHit: 1773, TotalNotes: 1852}.Accuracy(), 0.01)
+	assert.Equal(t, 0.0, Score{}.Accuracy())
+}
+
+func TestScore_FullCombo(t *testing.T) {
+	assert.True(t, Score{NotesHit: 357, TotalNotes: 357}.FullCombo())
+	assert.False(t, Score{NotesHit: 356, TotalNotes: 357}.FullCombo())
+	assert.False(t, Score{}.FullCombo())
+}
+
+// chartHeader returns a chart header with a zero hash and n scores.
+func chartHeader(n uint8) []byte {
+	return append(make([]byte, 16), n, 0, 0, 0, 0)
+}
+
+// record returns an Expert guitar score record with the given note counts.
+func record(notesHit, totalNotes uint32) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, Score{Difficulty: 3, NotesHit: notesHit, TotalNotes: totalNotes, Unknown: 1})
+	return buf.Bytes()
+}
+
+func header(version, count int32) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, []int32{version, count})
+	return buf.Bytes()
+}
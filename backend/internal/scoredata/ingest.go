@@ -0,0 +1,106 @@
+package scoredata
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"cloneheroer/internal/db"
+	"cloneheroer/internal/validate"
+)
+
+// Store looks up charts and saves scores. *db.Repo is the Postgres
+// implementation.
+type Store interface {
+	SongByChartHash(ctx context.Context, hash string) (*db.SongRef, error)
+	HasPlayerScore(ctx context.Context, songID int64, p db.Player) (bool, error)
+	CreateScore(ctx context.Context, data db.CreateScoreData) (int64, error)
+}
+
+// Result counts what one Ingest did.
+type Result struct {
+	Imported int
+	// Duplicates were already stored, from a screenshot or an earlier ingest
+	Duplicates int
+	// UnknownCharts have no song with their hash in the catalog
+	UnknownCharts int
+}
+
+// Ingester stores the scores in a scoredata.bin.
+type Ingester struct {
+	store  Store
+	player string
+}
+
+// NewIngester creates an ingester. scoredata.bin doesn't record who played,
+// so every score is stored under player.
+func NewIngester(store Store, player string) *Ingester {
+	return &Ingester{store: store, player: player}
+}
+
+// Ingest reads the scoredata.bin at path and stores each score that isn't
+// stored yet. Charts are matched to songs by hash, so the catalog must be
+// imported first. The file has no dates, so scores are stored without one.
+func (i *Ingester) Ingest(ctx context.Context, path string) (Result, error) {
+	file, err := ReadFile(path)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var result Result
+	for _, chart := range file.Charts {
+		song, err := i.store.SongByChartHash(ctx, chart.Hash)
+		if err != nil {
+			return result, fmt.Errorf("failed to look up chart %s: %w", chart.Hash, err)
+		}
+		if song == nil {
+			result.UnknownCharts++
+			continue
+		}
+
+		for _, score := range chart.Scores {
+			data := i.scoreData(song, score)
+			// Same checks as screenshots get, so a corrupt record is flagged too
+			data.Warnings = validate.Score(data)
+			exists, err := i.store.HasPlayerScore(ctx, song.ID, data.Players[0])
+			if err != nil {
+				return result, fmt.Errorf("failed to check for duplicates: %w", err)
+			}
+			if exists {
+				result.Duplicates++
+				continue
+			}
+
+			if _, err := i.store.CreateScore(ctx, data); err != nil {
+				return result, fmt.Errorf("failed to create score for %s - %s: %w", song.Artist, song.Name, err)
+			}
+			log.Printf("imported %s %s score %d for: %s - %s", score.DifficultyName(), score.InstrumentName(), score.Score, song.Artist, song.Name)
+			result.Imported++
+		}
+	}
+	return result, nil
+}
+
+func (i *Ingester) scoreData(song *db.SongRef, score Score) db.CreateScoreData {
+	return db.CreateScoreData{
+		Artist:        song.Artist,
+		SongName:      song.Name,
+		TotalScore:    int64(score.Score),
+		StarsAchieved: int(score.Stars),
+		Players: []db.Player{{
+			Name:        i.player,
+			Instrument:  score.InstrumentName(),
+			Difficulty:  score.DifficultyName(),
+			Score:       int64(score.Score),
+			Accuracy:    score.Accuracy(),
+			TotalNotes:  int(score.TotalNotes),
+			NotesHit:    int(score.NotesHit),
+			NotesMissed: int(score.TotalNotes - score.NotesHit),
+			FullCombo:   score.FullCombo(),
+		}},
+		// The file records no dates; its modification time is only when the
+		// newest of its runs was played, so CreatedAt stays unknown
+		CreatedAtSource: db.TimeSourceUnknown,
+		Source:          db.SourceScoreData,
+	}
+}
@@ -0,0 +1,181 @@
+package scoredata
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cloneheroer/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIngester_Ingest(t *testing.T) {
+	store := newMemStore()
+	path := fixturePath(t)
+
+	result, err := NewIngester(store, "gem").Ingest(context.Background(), path)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Imported: 4, UnknownCharts: 1}, result)
+	require.Len(t, store.scores, 4)
+
+	drums := store.scores[1]
+	assert.Equal(t, "Dave Matthews Band", drums.Artist)
+	assert.Equal(t, "Tripping Billies", drums.SongName)
+	assert.Equal(t, int64(378745), drums.TotalScore)
+	assert.Equal(t, 4, drums.StarsAchieved)
+	assert.Equal(t, db.SourceScoreData, drums.Source)
+	assert.True(t, drums.CreatedAt.IsZero(), "the file's modification time isn't when its runs were played")
+	assert.Equal(t, db.TimeSourceUnknown, drums.CreatedAtSource)
+	require.Len(t, drums.Players, 1)
+	assert.Equal(t, db.Player{
+		Name:        "gem",
+		Instrument:  "drums",
+		Difficulty:  "Expert",
+		Score:       378745,
+		Accuracy:    100 * 1773.0 / 1852.0,
+		TotalNotes:  1852,
+		NotesHit:    1773,
+		NotesMissed: 79,
+	}, drums.Players[0])
+}
+
+func TestIngester_SkipsStoredScores(t *testing.T) {
+	store := newMemStore()
+	// The drums run was already read from a screenshot
+	store.playerScores[2] = map[runKey]bool{{"gem", "drums", "Expert", 378745}: true}
+	path := fixturePath(t)
+
+	ingester := NewIngester(store, "gem")
+	result, err := ingester.Ingest(context.Background(), path)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Imported: 3, Duplicates: 1, UnknownCharts: 1}, result)
+
+	// Ingesting again finds everything already stored
+	result, err = ingester.Ingest(context.Background(), path)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Duplicates: 4, UnknownCharts: 1}, result)
+	assert.Len(t, store.scores, 3)
+}
+
+func TestIngester_SameScoreOtherRun(t *testing.T) {
+	store := newMemStore()
+	// Another player, and the same player on another instrument, scored the
+	// same on the song; neither is the imported run
+	store.playerScores[2] = map[runKey]bool{
+		{"Jules", "drums", "Expert", 378745}: true,
+		{"gem", "guitar", "Expert", 378745}:  true,
+	}
+
+	result, err := NewIngester(store, "gem").Ingest(context.Background(), fixturePath(t))
+	require.NoError(t, err)
+	assert.Equal(t, Result{Imported: 4, UnknownCharts: 1}, result)
+}
+
+func TestIngester_Warnings(t *testing.T) {
+	store := newMemStore()
+	store.songs["00000000000000000000000000000000"] = &db.SongRef{ID: 4, Name: "Cliffs of Dover", Artist: "Eric Johnson"}
+	// A run saved with no score
+	path := filepath.Join(t.TempDir(), "scoredata.bin")
+	contents := append(append(header(1, 1), chartHeader(1)...), record(10, 10)...)
+	require.NoError(t, os.WriteFile(path, contents, 0644))
+
+	result, err := NewIngester(store, "gem").Ingest(context.Background(), path)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Imported: 1}, result)
+	require.Len(t, store.scores, 1)
+	assert.Equal(t, []db.Warning{{Rule: "missing_field", Field: "total_score", Message: "total score is 0"}}, store.scores[0].Warnings)
+}
+
+func TestIngester_Errors(t *testing.T) {
+	t.Run("missing file", func(t *testing.T) {
+		_, err := NewIngester(newMemStore(), "gem").Ingest(context.Background(), filepath.Join(t.TempDir(), "scoredata.bin"))
+		assert.Error(t, err)
+	})
+
+	t.Run("corrupt file", func(t *testing.T) {
+		_, err := NewIngester(newMemStore(), "gem").Ingest(context.Background(), filepath.Join(_testDataPath, "scoredata", "scoredata-truncated.bin"))
+		assert.Error(t, err)
+	})
+
+	t.Run("store fails", func(t *testing.T) {
+		store := newMemStore()
+		store.err = errors.New("connection refused")
+
+		result, err := NewIngester(store, "gem").Ingest(context.Background(), fixturePath(t))
+		assert.Error(t, err)
+		assert.Zero(t, result.Imported)
+	})
+}
+
+// fixturePath copies the scoredata.bin fixture to a temp dir and returns its
+// path.
+func fixturePath(t *testing.T) string {
+	t.Helper()
+
+	contents, err := os.ReadFile(filepath.Join(_testDataPath, "scoredata", "scoredata.bin"))
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "scoredata.bin")
+	require.NoError(t, os.WriteFile(path, contents, 0644))
+	return path
+}
+
+// memStore is an in-memory Store whose catalog holds the songs in
+// testdata/songs.
+type memStore struct {
+	songs        map[string]*db.SongRef
+	playerScores map[int64]map[runKey]bool
+	scores       []db.CreateScoreData
+	err          error
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		songs: map[string]*db.SongRef{
+			"aa305f376c644eba2abcb68bc55a3c47": {ID: 1, Name: "Master of Puppets", Artist: "Metallica"},
+			"d1387f636f3c7247dacaddcb1aab74f9": {ID: 2, Name: "Tripping Billies", Artist: "Dave Matthews Band"},
+			"4c1b2f0d9e8a7b6c5d4e3f2a1b0c9d8e": {ID: 3, Name: "Discography", Artist: "Hail The Sun"},
+		},
+		playerScores: make(map[int64]map[runKey]bool),
+	}
+}
+
+func (m *memStore) SongByChartHash(_ context.Context, hash string) (*db.SongRef, error) {
+	return m.songs[hash], m.err
+}
+
+// runKey is what identifies a run on a song.
+type runKey struct {
+	name, instrument, difficulty string
+	score                        int64
+}
+
+func keyOf(p db.Player) runKey {
+	return runKey{p.Name, p.Instrument, p.Difficulty, p.Score}
+}
+
+func (m *memStore) HasPlayerScore(_ context.Context, songID int64, p db.Player) (bool, error) {
+	return m.playerScores[songID][keyOf(p)], m.err
+}
+
+func (m *memStore) CreateScore(_ context.Context, data db.CreateScoreData) (int64, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+	for _, song := range m.songs {
+		if song.Name != data.SongName {
+			continue
+		}
+		if m.playerScores[song.ID] == nil {
+			m.playerScores[song.ID] = make(map[runKey]bool)
+		}
+		for _, p := range data.Players {
+			m.playerScores[song.ID][keyOf(p)] = true
+		}
+	}
+	m.scores = append(m.scores, data)
+	return int64(len(m.scores)), nil
+}
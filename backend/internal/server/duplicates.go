@@ -0,0 +1,77 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"cloneheroer/internal/db"
+	"cloneheroer/internal/imagehash"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (s *Server) handleListDuplicates(c echo.Context) error {
+	limit, offset := parsePage(c, 20)
+
+	duplicates, err := s.repo.ListDuplicates(c.Request().Context(), limit, offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	for i := range duplicates {
+		duplicates[i].Distance = hashDistance(duplicates[i].Score, duplicates[i].Original)
+	}
+	return c.JSON(http.StatusOK, duplicates)
+}
+
+// hashDistance returns how far apart the screenshot hashes of two scores are,
+// or nil when either is missing.
+func hashDistance(score db.Score, original *db.Score) *int {
+	if original == nil || score.ImageHash == nil || original.ImageHash == nil {
+		return nil
+	}
+	a, err := imagehash.Parse(*score.ImageHash)
+	if err != nil {
+		return nil
+	}
+	b, err := imagehash.Parse(*original.ImageHash)
+	if err != nil {
+		return nil
+	}
+	d := a.Distance(b)
+	return &d
+}
+
+// Duplicate resolutions.
+const (
+	resolveKeep    = "keep"
+	resolveDiscard = "discard"
+)
+
+type resolveDuplicateRequest struct {
+	// Action is "keep" to keep the score as a separate run or "discard" to
+	// delete it
+	Action string `json:"action"`
+}
+
+func (s *Server) handleResolveDuplicate(c echo.Context) error {
+	id, err := parseIDParam(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+	}
+	req := resolveDuplicateRequest{}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid payload")
+	}
+	if req.Action != resolveKeep && req.Action != resolveDiscard {
+		return echo.NewHTTPError(http.StatusBadRequest, "action must be keep or discard")
+	}
+
+	err = s.repo.ResolveDuplicate(c.Request().Context(), id, req.Action == resolveDiscard)
+	if errors.Is(err, db.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "no duplicate with that id")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
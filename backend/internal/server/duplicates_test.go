@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cloneheroer/internal/db"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleResolveDuplicate_InvalidRequests(t *testing.T) {
+	testCases := []struct {
+		name string
+		path string
+		body string
+	}{
+		{name: "invalid id", path: "/duplicates/abc/resolve", body: `{"action": "keep"}`},
+		{name: "invalid payload", path: "/duplicates/1/resolve", body: `{`},
+		{name: "unknown action", path: "/duplicates/1/resolve", body: `{"action": "merge"}`},
+		{name: "no action", path: "/duplicates/1/resolve", body: `{}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Rejected before the repo is touched
+			s := New(nil)
+
+			req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			s.app.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func TestHashDistance(t *testing.T) {
+	zero := strings.Repeat("0", 64)
+	three := strings.Repeat("0", 63) + "7"
+	invalid := "xyz"
+
+	testCases := []struct {
+		name     string
+		score    db.Score
+		original *db.Score
+		want     *int
+	}{
+		{name: "both hashed", score: db.Score{ImageHash: &zero}, original: &db.Score{ImageHash: &three}, want: intPtr(3)},
+		{name: "original deleted", score: db.Score{ImageHash: &zero}},
+		{name: "original not hashed", score: db.Score{ImageHash: &zero}, original: &db.Score{}},
+		{name: "invalid hash", score: db.Score{ImageHash: &invalid}, original: &db.Score{ImageHash: &zero}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, hashDistance(tc.score, tc.original))
+		})
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
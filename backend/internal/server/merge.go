@@ -0,0 +1,72 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"cloneheroer/internal/db"
+
+	"github.com/labstack/echo/v4"
+)
+
+type mergeArtistRequest struct {
+	// ArtistID is the artist to merge into the one in the path; it's deleted
+	ArtistID int64 `json:"artist_id"`
+}
+
+func (s *Server) handleMergeArtist(c echo.Context) error {
+	id, err := parseIDParam(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+	}
+	req := mergeArtistRequest{}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid payload")
+	}
+	if req.ArtistID == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "artist_id is required")
+	}
+	if req.ArtistID == id {
+		return echo.NewHTTPError(http.StatusBadRequest, "can't merge an artist into itself")
+	}
+
+	err = s.repo.MergeArtists(c.Request().Context(), id, req.ArtistID)
+	if errors.Is(err, db.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "no artist with that id")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+type mergeSongRequest struct {
+	// SongID is the song to merge into the one in the path; it's deleted
+	SongID int64 `json:"song_id"`
+}
+
+func (s *Server) handleMergeSong(c echo.Context) error {
+	id, err := parseIDParam(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+	}
+	req := mergeSongRequest{}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid payload")
+	}
+	if req.SongID == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "song_id is required")
+	}
+	if req.SongID == id {
+		return echo.NewHTTPError(http.StatusBadRequest, "can't merge a song into itself")
+	}
+
+	err = s.repo.MergeSongs(c.Request().Context(), id, req.SongID)
+	if errors.Is(err, db.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "no song with that id")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"cloneheroer/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleMerge_InvalidRequests(t *testing.T) {
+	testCases := []struct {
+		name string
+		path string
+		body string
+	}{
+		{name: "artist invalid id", path: "/artists/abc/merge", body: `{"artist_id": 2}`},
+		{name: "artist invalid payload", path: "/artists/1/merge", body: `{`},
+		{name: "artist without artist", path: "/artists/1/merge", body: `{}`},
+		{name: "artist into itself", path: "/artists/1/merge", body: `{"artist_id": 1}`},
+		{name: "song invalid id", path: "/songs/abc/merge", body: `{"song_id": 2}`},
+		{name: "song invalid payload", path: "/songs/1/merge", body: `{`},
+		{name: "song without song", path: "/songs/1/merge", body: `{}`},
+		{name: "song into itself", path: "/songs/1/merge", body: `{"song_id": 1}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Rejected before the repo is touched
+			rec := serve(New(nil), http.MethodPost, tc.path, tc.body)
+			assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func TestHandleMergeArtist(t *testing.T) {
+	s, pool := newTestServer(t)
+	ctx := context.Background()
+
+	artist := "Server Test Artist " + time.Now().Format(time.RFC3339Nano)
+	intoSong, err := s.repo.UpsertCatalogSong(ctx, db.CatalogSong{Name: "Tripping Billies", Artist: artist})
+	require.NoError(t, err)
+	fromSong, err := s.repo.UpsertCatalogSong(ctx, db.CatalogSong{Name: "Tripping Billies", Artist: artist + " OCR"})
+	require.NoError(t, err)
+	var into, from int64
+	require.NoError(t, pool.QueryRow(ctx, `SELECT artist_id FROM songs WHERE id = $1`, intoSong).Scan(&into))
+	require.NoError(t, pool.QueryRow(ctx, `SELECT artist_id FROM songs WHERE id = $1`, fromSong).Scan(&from))
+
+	rec := serve(s, http.MethodPatch, fmt.Sprintf("/artists/%d", from), fmt.Sprintf(`{"name": %q}`, artist))
+	assert.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+	rec = serve(s, http.MethodPatch, "/artists/-1", `{"name": "Nobody"}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code, rec.Body.String())
+
+	path := fmt.Sprintf("/artists/%d/merge", into)
+	body := fmt.Sprintf(`{"artist_id": %d}`, from)
+	rec = serve(s, http.MethodPost, path, body)
+	assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+
+	rec = serve(s, http.MethodPost, path, body)
+	assert.Equal(t, http.StatusNotFound, rec.Code, "the merged artist is gone")
+}
+
+func TestHandleMergeSong(t *testing.T) {
+	s, _ := newTestServer(t)
+	ctx := context.Background()
+
+	artist := "Server Test Artist " + time.Now().Format(time.RFC3339Nano)
+	into, err := s.repo.UpsertCatalogSong(ctx, db.CatalogSong{Name: "Tripping Billies", Artist: artist})
+	require.NoError(t, err)
+	from, err := s.repo.UpsertCatalogSong(ctx, db.CatalogSong{Name: "Tripp1ng Bi11ies", Artist: artist})
+	require.NoError(t, err)
+
+	rec := serve(s, http.MethodPatch, fmt.Sprintf("/songs/%d", from), `{"name": "Tripping Billies"}`)
+	assert.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+	rec = serve(s, http.MethodPatch, "/songs/-1", `{"name": "Nothing"}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code, rec.Body.String())
+
+	path := fmt.Sprintf("/songs/%d/merge", into)
+	body := fmt.Sprintf(`{"song_id": %d}`, from)
+	rec = serve(s, http.MethodPost, path, body)
+	assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+
+	rec = serve(s, http.MethodPost, path, body)
+	assert.Equal(t, http.StatusNotFound, rec.Code, "the merged song is gone")
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"cloneheroer/internal/db"
+	"cloneheroer/internal/validate"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ImageParser parses screenshots. *parser.Parser is the OCR implementation.
+type ImageParser interface {
+	ParseImage(imagePath string) (*db.CreateScoreData, error)
+	ParseImageDebug(imagePath string) (*db.CreateScoreData, string, error)
+}
+
+// Option configures optional Server behaviour.
+type Option func(*Server)
+
+// WithParser enables POST /parse.
+func WithParser(parser ImageParser) Option {
+	return func(s *Server) {
+		s.parser = parser
+	}
+}
+
+type parseResponse struct {
+	Score    *db.CreateScoreData `json:"score"`
+	DebugDir string              `json:"debug_dir,omitempty"`
+}
+
+// handleParse parses and validates an uploaded screenshot without storing
+// it. With ?debug=true the parser also dumps its crops and OCR text and the
+// response says where.
+func (s *Server) handleParse(c echo.Context) error {
+	if s.parser == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "parsing is not available")
+	}
+
+	debug := false
+	if v := c.QueryParam("debug"); v != "" {
+		var err error
+		if debug, err = strconv.ParseBool(v); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid debug")
+		}
+	}
+
+	file, err := c.FormFile("image")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "missing image")
+	}
+
+	// The parser reads the timestamp from the file name, so keep it
+	dir, err := os.MkdirTemp("", "cloneheroer-upload-")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Base(file.Filename)
+	if name == "." || name == string(filepath.Separator) {
+		return echo.NewHTTPError(http.StatusBadRequest, "missing image file name")
+	}
+	imagePath := filepath.Join(dir, name)
+	if err := saveUpload(file, imagePath); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	resp := parseResponse{}
+	if debug {
+		resp.Score, resp.DebugDir, err = s.parser.ParseImageDebug(imagePath)
+	} else {
+		resp.Score, err = s.parser.ParseImage(imagePath)
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+	resp.Score.Warnings = validate.Score(*resp.Score)
+	return c.JSON(http.StatusOK, resp)
+}
+
+// saveUpload copies an uploaded file to path.
+func saveUpload(file *multipart.FileHeader, path string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cloneheroer/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleParse(t *testing.T) {
+	testCases := []struct {
+		name       string
+		parser     *fakeParser
+		query      string
+		upload     bool
+		wantStatus int
+		wantDebug  bool
+	}{
+		{name: "parses the upload", parser: &fakeParser{}, upload: true, wantStatus: http.StatusOK},
+		{name: "debug", parser: &fakeParser{}, query: "?debug=true", upload: true, wantStatus: http.StatusOK, wantDebug: true},
+		{name: "debug off", parser: &fakeParser{}, query: "?debug=0", upload: true, wantStatus: http.StatusOK},
+		{name: "invalid debug", parser: &fakeParser{}, query: "?debug=maybe", upload: true, wantStatus: http.StatusBadRequest},
+		{name: "no upload", parser: &fakeParser{}, wantStatus: http.StatusBadRequest},
+		{name: "parse fails", parser: &fakeParser{err: errors.New("unreadable")}, upload: true, wantStatus: http.StatusUnprocessableEntity},
+		{name: "no parser", upload: true, wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var opts []Option
+			if tc.parser != nil {
+				opts = append(opts, WithParser(tc.parser))
+			}
+			s := New(nil, opts...)
+
+			body := &bytes.Buffer{}
+			form := multipart.NewWriter(body)
+			if tc.upload {
+				part, err := form.CreateFormFile("image", "clonehero-Song-20251209195440.png")
+				require.NoError(t, err)
+				part.Write([]byte("png"))
+			}
+			require.NoError(t, form.Close())
+
+			req := httptest.NewRequest(http.MethodPost, "/parse"+tc.query, body)
+			req.Header.Set("Content-Type", form.FormDataContentType())
+			rec := httptest.NewRecorder()
+			s.app.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp parseResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "Tripping Billies", resp.Score.SongName)
+			assert.NotEmpty(t, resp.Score.Warnings, "the fake's score has no artist")
+			assert.Equal(t, tc.wantDebug, resp.DebugDir != "")
+
+			// The parser saw the upload under its original name, and it's gone afterwards
+			assert.Equal(t, "clonehero-Song-20251209195440.png", filepath.Base(tc.parser.path))
+			assert.Equal(t, "png", tc.parser.contents)
+			assert.NoFileExists(t, tc.parser.path)
+		})
+	}
+}
+
+type fakeParser struct {
+	err      error
+	path     string
+	contents string
+}
+
+func (f *fakeParser) ParseImage(imagePath string) (*db.CreateScoreData, error) {
+	return f.parse(imagePath)
+}
+
+func (f *fakeParser) ParseImageDebug(imagePath string) (*db.CreateScoreData, string, error) {
+	data, err := f.parse(imagePath)
+	return data, "/debug/" + filepath.Base(imagePath), err
+}
+
+func (f *fakeParser) parse(imagePath string) (*db.CreateScoreData, error) {
+	f.path = imagePath
+	contents, _ := os.ReadFile(imagePath)
+	f.contents = string(contents)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &db.CreateScoreData{SongName: "Tripping Billies"}, nil
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"cloneheroer/internal/db"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (s *Server) handleListPersonalBests(c echo.Context) error {
+	limit, offset := parsePage(c, 20)
+
+	filter := db.PersonalBestFilter{
+		PlayerName: c.QueryParam("player"),
+		Instrument: c.QueryParam("instrument"),
+		Difficulty: c.QueryParam("difficulty"),
+	}
+	if v := c.QueryParam("song_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid song_id")
+		}
+		filter.SongID = &id
+	}
+
+	bests, err := s.repo.ListPersonalBests(c.Request().Context(), limit, offset, filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, bests)
+}
+
+func (s *Server) handlePersonalBestHistory(c echo.Context) error {
+	id, err := parseIDParam(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+	}
+	runs, err := s.repo.PersonalBestHistory(c.Request().Context(), id)
+	if errors.Is(err, db.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "no personal best with that id")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, runs)
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"cloneheroer/internal/db"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (s *Server) handleListProfiles(c echo.Context) error {
+	limit, offset := parsePage(c, 20)
+
+	profiles, err := s.repo.ListProfiles(c.Request().Context(), limit, offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, profiles)
+}
+
+func (s *Server) handleGetProfile(c echo.Context) error {
+	id, err := parseIDParam(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+	}
+
+	profile, err := s.repo.GetProfile(c.Request().Context(), id)
+	if errors.Is(err, db.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "no profile with that id")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, profile)
+}
+
+type createProfileRequest struct {
+	Name string `json:"name"`
+	// Aliases are player names besides Name that belong to the profile
+	Aliases []string `json:"aliases"`
+}
+
+func (s *Server) handleCreateProfile(c echo.Context) error {
+	req := createProfileRequest{}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid payload")
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "name is required")
+	}
+	for i, alias := range req.Aliases {
+		req.Aliases[i] = strings.TrimSpace(alias)
+		if req.Aliases[i] == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "aliases can't be empty")
+		}
+	}
+
+	profile, err := s.repo.CreateProfile(c.Request().Context(), req.Name, req.Aliases)
+	if errors.Is(err, db.ErrConflict) {
+		return echo.NewHTTPError(http.StatusConflict, "a profile with that name or alias exists")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusCreated, profile)
+}
+
+type assignAliasRequest struct {
+	Alias string `json:"alias"`
+}
+
+func (s *Server) handleAssignAlias(c echo.Context) error {
+	id, err := parseIDParam(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+	}
+	req := assignAliasRequest{}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid payload")
+	}
+	req.Alias = strings.TrimSpace(req.Alias)
+	if req.Alias == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "alias is required")
+	}
+
+	err = s.repo.AssignAlias(c.Request().Context(), id, req.Alias)
+	if errors.Is(err, db.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "no profile with that id")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+type mergeProfileRequest struct {
+	// ProfileID is the profile to merge into the one in the path; it's deleted
+	ProfileID int64 `json:"profile_id"`
+}
+
+func (s *Server) handleMergeProfile(c echo.Context) error {
+	id, err := parseIDParam(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+	}
+	req := mergeProfileRequest{}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid payload")
+	}
+	if req.ProfileID == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "profile_id is required")
+	}
+	if req.ProfileID == id {
+		return echo.NewHTTPError(http.StatusBadRequest, "can't merge a profile into itself")
+	}
+
+	err = s.repo.MergeProfiles(c.Request().Context(), id, req.ProfileID)
+	if errors.Is(err, db.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "no profile with that id")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"cloneheroer/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleProfiles_InvalidRequests(t *testing.T) {
+	testCases := []struct {
+		name string
+		path string
+		body string
+	}{
+		{name: "create without name", path: "/profiles", body: `{"aliases": ["Bren"]}`},
+		{name: "create with blank name", path: "/profiles", body: `{"name": "  "}`},
+		{name: "create with empty alias", path: "/profiles", body: `{"name": "Bren", "aliases": [""]}`},
+		{name: "create with blank alias", path: "/profiles", body: `{"name": "Bren", "aliases": ["  "]}`},
+		{name: "create with invalid payload", path: "/profiles", body: `{`},
+		{name: "alias on invalid id", path: "/profiles/abc/aliases", body: `{"alias": "Bren"}`},
+		{name: "empty alias", path: "/profiles/1/aliases", body: `{"alias": ""}`},
+		{name: "blank alias", path: "/profiles/1/aliases", body: `{"alias": " "}`},
+		{name: "merge invalid id", path: "/profiles/abc/merge", body: `{"profile_id": 2}`},
+		{name: "merge without profile", path: "/profiles/1/merge", body: `{}`},
+		{name: "merge into itself", path: "/profiles/1/merge", body: `{"profile_id": 1}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Rejected before the repo is touched
+			rec := serve(New(nil), http.MethodPost, tc.path, tc.body)
+			assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+		})
+	}
+
+	rec := serve(New(nil), http.MethodGet, "/profiles/abc", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+}
+
+func TestHandleProfiles(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	suffix := " " + time.Now().Format(time.RFC3339Nano)
+	bren, jules := "Bren"+suffix, "Jules"+suffix
+
+	rec := serve(s, http.MethodPost, "/profiles", fmt.Sprintf(`{"name": %q, "aliases": [%q]}`, "  "+bren+" ", " 8ren"+suffix+" "))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var profile db.Profile
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &profile))
+	assert.Equal(t, bren, profile.Name, "the name is trimmed")
+	assert.ElementsMatch(t, []string{bren, "8ren" + suffix}, profile.Aliases, "aliases are trimmed")
+
+	rec = serve(s, http.MethodPost, "/profiles", fmt.Sprintf(`{"name": %q}`, bren))
+	assert.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+
+	rec = serve(s, http.MethodGet, fmt.Sprintf("/profiles/%d", profile.ID), "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var got db.Profile
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, profile.ID, got.ID)
+	assert.ElementsMatch(t, profile.Aliases, got.Aliases)
+
+	rec = serve(s, http.MethodGet, "/profiles/-1", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code, rec.Body.String())
+
+	aliases := fmt.Sprintf("/profiles/%d/aliases", profile.ID)
+	rec = serve(s, http.MethodPost, aliases, fmt.Sprintf(`{"alias": %q}`, " BrenOld"+suffix))
+	assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	linked, err := s.repo.GetProfile(context.Background(), profile.ID)
+	require.NoError(t, err)
+	assert.Contains(t, linked.Aliases, "BrenOld"+suffix, "the alias is trimmed")
+	rec = serve(s, http.MethodPost, "/profiles/-1/aliases", fmt.Sprintf(`{"alias": %q}`, "Nobody"+suffix))
+	assert.Equal(t, http.StatusNotFound, rec.Code, rec.Body.String())
+
+	rec = serve(s, http.MethodPost, "/profiles", fmt.Sprintf(`{"name": %q}`, jules))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var other db.Profile
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &other))
+
+	merge := fmt.Sprintf("/profiles/%d/merge", profile.ID)
+	body := fmt.Sprintf(`{"profile_id": %d}`, other.ID)
+	rec = serve(s, http.MethodPost, merge, body)
+	assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	rec = serve(s, http.MethodPost, merge, body)
+	assert.Equal(t, http.StatusNotFound, rec.Code, "the merged profile is gone")
+}
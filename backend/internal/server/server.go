@@ -1,6 +1,7 @@
 package server
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -13,12 +14,13 @@ import (
 
 // Server wraps Echo and database repo.
 type Server struct {
-	app  *echo.Echo
-	repo *db.Repo
+	app    *echo.Echo
+	repo   *db.Repo
+	parser ImageParser
 }
 
 // New creates a configured server instance.
-func New(repo *db.Repo) *Server {
+func New(repo *db.Repo, opts ...Option) *Server {
 	e := echo.New()
 	e.HideBanner = true
 	e.Use(middleware.Logger())
@@ -34,6 +36,9 @@ func New(repo *db.Repo) *Server {
 		app:  e,
 		repo: repo,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
 	s.registerRoutes()
 	return s
 }
@@ -49,12 +54,25 @@ func (s *Server) registerRoutes() {
 	})
 
 	s.app.GET("/scores", s.handleListScores)
+	s.app.GET("/scores/:id", s.handleGetScore)
 	s.app.GET("/artists", s.handleListArtists)
 	s.app.GET("/songs", s.handleListSongs)
 	s.app.PATCH("/artists/:id", s.handleUpdateArtist)
 	s.app.PATCH("/songs/:id", s.handleUpdateSong)
+	s.app.POST("/artists/:id/merge", s.handleMergeArtist)
+	s.app.POST("/songs/:id/merge", s.handleMergeSong)
 	s.app.PATCH("/scores/:id", s.handleUpdateScore)
 	s.app.PATCH("/players/:id", s.handleUpdatePlayer)
+	s.app.GET("/personal-bests", s.handleListPersonalBests)
+	s.app.GET("/personal-bests/:id/history", s.handlePersonalBestHistory)
+	s.app.GET("/profiles", s.handleListProfiles)
+	s.app.POST("/profiles", s.handleCreateProfile)
+	s.app.GET("/profiles/:id", s.handleGetProfile)
+	s.app.POST("/profiles/:id/aliases", s.handleAssignAlias)
+	s.app.POST("/profiles/:id/merge", s.handleMergeProfile)
+	s.app.GET("/duplicates", s.handleListDuplicates)
+	s.app.POST("/duplicates/:id/resolve", s.handleResolveDuplicate)
+	s.app.POST("/parse", s.handleParse)
 
 	// Debug route to list all registered routes (useful for troubleshooting)
 	s.app.GET("/debug/routes", func(c echo.Context) error {
@@ -76,6 +94,19 @@ func parseIDParam(c echo.Context) (int64, error) {
 	return strconv.ParseInt(c.Param("id"), 10, 64)
 }
 
+// parsePage reads the limit and offset query params for list endpoints,
+// falling back to defaultLimit and 0 when they're missing or not numbers.
+func parsePage(c echo.Context, defaultLimit int32) (limit, offset int32) {
+	limit = defaultLimit
+	if v, err := strconv.Atoi(c.QueryParam("limit")); err == nil {
+		limit = int32(v)
+	}
+	if v, err := strconv.Atoi(c.QueryParam("offset")); err == nil {
+		offset = int32(v)
+	}
+	return limit, offset
+}
+
 type updateArtistRequest struct {
 	Name *string `json:"name"`
 }
@@ -89,7 +120,14 @@ func (s *Server) handleUpdateArtist(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid payload")
 	}
-	if err := s.repo.UpdateArtist(c.Request().Context(), id, req.Name); err != nil {
+	err = s.repo.UpdateArtist(c.Request().Context(), id, req.Name)
+	if errors.Is(err, db.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "no artist with that id")
+	}
+	if errors.Is(err, db.ErrConflict) {
+		return echo.NewHTTPError(http.StatusConflict, "an artist already has that name; merge them instead")
+	}
+	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 	return c.NoContent(http.StatusNoContent)
@@ -110,7 +148,14 @@ func (s *Server) handleUpdateSong(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid payload")
 	}
-	if err := s.repo.UpdateSong(c.Request().Context(), id, req.Name, req.ArtistID, req.Charters); err != nil {
+	err = s.repo.UpdateSong(c.Request().Context(), id, req.Name, req.ArtistID, req.Charters)
+	if errors.Is(err, db.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "no song with that id")
+	}
+	if errors.Is(err, db.ErrConflict) {
+		return echo.NewHTTPError(http.StatusConflict, "the artist already has a song with that name; merge them instead")
+	}
+	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 	return c.NoContent(http.StatusNoContent)
@@ -131,21 +176,32 @@ func (s *Server) handleUpdateScore(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid payload")
 	}
-	if err := s.repo.UpdateScore(c.Request().Context(), id, req.TotalScore, req.Stars, req.Charter); err != nil {
+	err = s.repo.UpdateScore(c.Request().Context(), id, req.TotalScore, req.Stars, req.Charter)
+	if errors.Is(err, db.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "no score with that id")
+	}
+	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 	return c.NoContent(http.StatusNoContent)
 }
 
 type updatePlayerRequest struct {
-	Name       *string  `json:"name"`
-	Instrument *string  `json:"instrument"`
-	Difficulty *string  `json:"difficulty"`
-	Score      *int64   `json:"score"`
-	Combo      *int     `json:"combo"`
-	Accuracy   *float64 `json:"accuracy"`
-	Misses     *int     `json:"misses"`
-	Rank       *int     `json:"rank"`
+	Name           *string  `json:"name"`
+	Instrument     *string  `json:"instrument"`
+	Difficulty     *string  `json:"difficulty"`
+	Score          *int64   `json:"score"`
+	Accuracy       *float64 `json:"accuracy"`
+	TotalNotes     *int     `json:"total_notes"`
+	NotesHit       *int     `json:"notes_hit"`
+	NotesMissed    *int     `json:"notes_missed"`
+	BestStreak     *int     `json:"best_streak"`
+	Overhits       *int     `json:"overhits"`
+	AvgMultiplier  *float64 `json:"avg_multiplier"`
+	StarPowerHit   *int     `json:"star_power_hit"`
+	StarPowerTotal *int     `json:"star_power_total"`
+	FullCombo      *bool    `json:"full_combo"`
+	Rank           *int     `json:"rank"`
 }
 
 func (s *Server) handleUpdatePlayer(c echo.Context) error {
@@ -157,18 +213,27 @@ func (s *Server) handleUpdatePlayer(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid payload")
 	}
-	if err := s.repo.UpdatePlayer(
-		c.Request().Context(),
-		id,
-		req.Name,
-		req.Instrument,
-		req.Difficulty,
-		req.Score,
-		req.Combo,
-		req.Accuracy,
-		req.Misses,
-		req.Rank,
-	); err != nil {
+	err = s.repo.UpdatePlayer(c.Request().Context(), id, db.PlayerUpdate{
+		Name:           req.Name,
+		Instrument:     req.Instrument,
+		Difficulty:     req.Difficulty,
+		Score:          req.Score,
+		Accuracy:       req.Accuracy,
+		TotalNotes:     req.TotalNotes,
+		NotesHit:       req.NotesHit,
+		NotesMissed:    req.NotesMissed,
+		BestStreak:     req.BestStreak,
+		Overhits:       req.Overhits,
+		AvgMultiplier:  req.AvgMultiplier,
+		StarPowerHit:   req.StarPowerHit,
+		StarPowerTotal: req.StarPowerTotal,
+		FullCombo:      req.FullCombo,
+		Rank:           req.Rank,
+	})
+	if errors.Is(err, db.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "no player with that id")
+	}
+	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 	return c.NoContent(http.StatusNoContent)
@@ -192,13 +257,71 @@ func (s *Server) handleListScores(c echo.Context) error {
 		}
 	}
 
-	scores, err := s.repo.ListScores(c.Request().Context(), limit, offset)
+	filter := db.ScoreFilter{
+		SortBy: c.QueryParam("sort"),
+		Order:  c.QueryParam("order"),
+	}
+	if v := c.QueryParam("min_confidence"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid min_confidence")
+		}
+		filter.MinConfidence = &f
+	}
+	if v := c.QueryParam("max_confidence"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid max_confidence")
+		}
+		filter.MaxConfidence = &f
+	}
+	if v := c.QueryParam("has_warnings"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid has_warnings")
+		}
+		filter.HasWarnings = &b
+	}
+	if v := c.QueryParam("players"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid players")
+		}
+		filter.OmitPlayers = !b
+	}
+	switch filter.SortBy {
+	case "", "created_at", "confidence":
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "sort must be created_at or confidence")
+	}
+	switch filter.Order {
+	case "", "asc", "desc":
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "order must be asc or desc")
+	}
+
+	scores, err := s.repo.ListScores(c.Request().Context(), limit, offset, filter)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 	return c.JSON(http.StatusOK, scores)
 }
 
+func (s *Server) handleGetScore(c echo.Context) error {
+	id, err := parseIDParam(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+	}
+	score, err := s.repo.GetScore(c.Request().Context(), id)
+	if errors.Is(err, db.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "no score with that id")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, score)
+}
+
 func (s *Server) handleListArtists(c echo.Context) error {
 	limitParam := c.QueryParam("limit")
 	offsetParam := c.QueryParam("offset")
@@ -248,4 +371,3 @@ func (s *Server) handleListSongs(c echo.Context) error {
 	}
 	return c.JSON(http.StatusOK, songs)
 }
-
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cloneheroer/internal/db"
+	"cloneheroer/internal/db/dbtest"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleGet_InvalidRequests(t *testing.T) {
+	testCases := []struct {
+		name string
+		path string
+	}{
+		{name: "invalid score id", path: "/scores/abc"},
+		{name: "invalid players flag", path: "/scores?players=some"},
+		{name: "invalid has_warnings", path: "/scores?has_warnings=maybe"},
+		{name: "invalid sort", path: "/scores?sort=artist"},
+		{name: "invalid personal best song", path: "/personal-bests?song_id=abc"},
+		{name: "invalid personal best id", path: "/personal-bests/abc/history"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Rejected before the repo is touched
+			s := New(nil)
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			rec := httptest.NewRecorder()
+			s.app.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+// serve sends a request through the server's router and returns the response.
+func serve(s *Server, method, path, body string) *httptest.ResponseRecorder {
+	var r io.Reader
+	if body != "" {
+		r = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, path, r)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	s.app.ServeHTTP(rec, req)
+	return rec
+}
+
+// newTestServer returns a Server on the database TEST_DATABASE_URL points
+// at, and the pool under it for checking rows the API doesn't expose. It
+// skips the test when TEST_DATABASE_URL isn't set.
+func newTestServer(t *testing.T) (*Server, *pgxpool.Pool) {
+	t.Helper()
+
+	pool := dbtest.Pool(t)
+	return New(db.NewRepo(pool)), pool
+}
@@ -0,0 +1,11 @@
+// Package skip holds the error the pipeline and the watcher share for files
+// that aren't meant to be processed.
+package skip
+
+import "errors"
+
+// Err is returned, possibly wrapped, for files that aren't meant to be
+// processed, like screenshots of something other than a results screen or
+// second captures of a stored score. The watcher moves them to its skipped
+// directory rather than the failed one and doesn't retry them.
+var Err = errors.New("file skipped")
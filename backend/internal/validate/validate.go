@@ -0,0 +1,108 @@
+// Package validate checks that a parsed score is consistent with itself.
+// OCR mistakes usually leave fields that disagree with each other, like a
+// player score larger than the band score, so each broken rule becomes a
+// warning stored with the score.
+package validate
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"cloneheroer/internal/db"
+)
+
+// Rule names, stored in db.Warning.Rule.
+const (
+	RuleMissingField    = "missing_field"
+	RuleStarsRange      = "stars_range"
+	RulePlayerScore     = "player_score_exceeds_total"
+	RuleScoreSum        = "player_scores_sum"
+	RuleAccuracyRange   = "accuracy_range"
+	RuleAccuracyNotes   = "accuracy_notes"
+	RuleNoteCounts      = "note_counts"
+	RuleStreak          = "best_streak"
+	RuleMultiplierRange = "avg_multiplier_range"
+	RuleDifficulty      = "difficulty"
+)
+
+const (
+	// MaxStars is the number of star slots on the results screen.
+	MaxStars = 7
+	// maxAvgMultiplier is 4x doubled by star power.
+	maxAvgMultiplier = 8
+	// accuracyTolerance allows for Clone Hero rounding the percentage down.
+	accuracyTolerance = 1
+)
+
+var difficulties = map[string]bool{"Easy": true, "Medium": true, "Hard": true, "Expert": true}
+
+// Score runs every rule against data and returns the broken ones in field
+// order. It returns nil for a consistent score.
+func Score(data db.CreateScoreData) []db.Warning {
+	var warnings []db.Warning
+	warn := func(rule, field, format string, args ...any) {
+		warnings = append(warnings, db.Warning{Rule: rule, Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if strings.TrimSpace(data.Artist) == "" {
+		warn(RuleMissingField, "artist", "artist is empty")
+	}
+	if strings.TrimSpace(data.SongName) == "" {
+		warn(RuleMissingField, "song_name", "song name is empty")
+	}
+	if data.TotalScore == 0 {
+		warn(RuleMissingField, "total_score", "total score is 0")
+	}
+	if len(data.Players) == 0 {
+		warn(RuleMissingField, "players", "no players")
+	}
+
+	if data.StarsAchieved < 0 || data.StarsAchieved > MaxStars {
+		warn(RuleStarsRange, "stars_achieved", "%d stars is outside 0-%d", data.StarsAchieved, MaxStars)
+	}
+
+	var sum int64
+	for i, p := range data.Players {
+		sum += p.Score
+		field := func(name string) string {
+			return fmt.Sprintf("players.%d.%s", i, name)
+		}
+
+		if data.TotalScore > 0 && p.Score > data.TotalScore {
+			warn(RulePlayerScore, field("score"), "player score %d is more than the total score %d", p.Score, data.TotalScore)
+		}
+
+		if p.Difficulty != "" && !difficulties[p.Difficulty] {
+			warn(RuleDifficulty, field("difficulty"), "unknown difficulty %q", p.Difficulty)
+		}
+
+		if p.Accuracy < 0 || p.Accuracy > 100 {
+			warn(RuleAccuracyRange, field("accuracy"), "accuracy %v%% is outside 0-100%%", p.Accuracy)
+		} else if p.TotalNotes > 0 && p.NotesHit <= p.TotalNotes {
+			hitPct := 100 * float64(p.NotesHit) / float64(p.TotalNotes)
+			if math.Abs(math.Floor(hitPct)-p.Accuracy) > accuracyTolerance {
+				warn(RuleAccuracyNotes, field("accuracy"), "accuracy %v%% but %d of %d notes hit is %.1f%%", p.Accuracy, p.NotesHit, p.TotalNotes, hitPct)
+			}
+		}
+
+		if p.TotalNotes > 0 && p.NotesHit+p.NotesMissed != p.TotalNotes {
+			warn(RuleNoteCounts, field("total_notes"), "%d notes hit and %d missed don't add up to %d total notes", p.NotesHit, p.NotesMissed, p.TotalNotes)
+		}
+
+		if p.NotesHit > 0 && p.BestStreak > p.NotesHit {
+			warn(RuleStreak, field("best_streak"), "best streak %d is longer than the %d notes hit", p.BestStreak, p.NotesHit)
+		}
+
+		if p.AvgMultiplier < 0 || p.AvgMultiplier > maxAvgMultiplier {
+			warn(RuleMultiplierRange, field("avg_multiplier"), "average multiplier %vx is outside 0-%dx", p.AvgMultiplier, maxAvgMultiplier)
+		}
+	}
+
+	// The band score is the players' scores added up
+	if data.TotalScore > 0 && len(data.Players) > 0 && sum != data.TotalScore {
+		warn(RuleScoreSum, "total_score", "player scores add up to %d, not the total score %d", sum, data.TotalScore)
+	}
+
+	return warnings
+}
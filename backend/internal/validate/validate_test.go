@@ -0,0 +1,113 @@
+package validate
+
+import (
+	"testing"
+
+	"cloneheroer/internal/db"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// trippingBillies is a consistent score, as read off a real screenshot.
+func trippingBillies() db.CreateScoreData {
+	return db.CreateScoreData{
+		Artist:        "Dave Matthews Band",
+		SongName:      "Tripping Billies",
+		TotalScore:    447253,
+		StarsAchieved: 4,
+		Players: []db.Player{
+			{Name: "_gem_", Difficulty: "Expert", Score: 378745, Accuracy: 95, TotalNotes: 1852, NotesHit: 1773, NotesMissed: 79, BestStreak: 173, AvgMultiplier: 2.921},
+			{Name: "A_Hole_Pro", Difficulty: "Expert", Score: 68508, Accuracy: 81, TotalNotes: 1244, NotesHit: 1015, NotesMissed: 229, BestStreak: 50, AvgMultiplier: 0.945},
+		},
+	}
+}
+
+func TestScore(t *testing.T) {
+	testCases := []struct {
+		name   string
+		modify func(*db.CreateScoreData)
+		want   []db.Warning
+	}{
+		{
+			name:   "consistent score",
+			modify: func(*db.CreateScoreData) {},
+		},
+		{
+			name: "accuracy rounded down",
+			modify: func(d *db.CreateScoreData) {
+				// 478 of 533 is 89.7%, shown as 89%
+				d.Players[1].TotalNotes, d.Players[1].NotesHit, d.Players[1].NotesMissed, d.Players[1].Accuracy = 533, 478, 55, 89
+			},
+		},
+		{
+			name: "missing fields",
+			modify: func(d *db.CreateScoreData) {
+				d.Artist, d.SongName, d.TotalScore, d.Players = "", " ", 0, nil
+			},
+			want: []db.Warning{
+				{Rule: RuleMissingField, Field: "artist", Message: "artist is empty"},
+				{Rule: RuleMissingField, Field: "song_name", Message: "song name is empty"},
+				{Rule: RuleMissingField, Field: "total_score", Message: "total score is 0"},
+				{Rule: RuleMissingField, Field: "players", Message: "no players"},
+			},
+		},
+		{
+			name:   "too many stars",
+			modify: func(d *db.CreateScoreData) { d.StarsAchieved = 9 },
+			want:   []db.Warning{{Rule: RuleStarsRange, Field: "stars_achieved", Message: "9 stars is outside 0-7"}},
+		},
+		{
+			name:   "player score larger than the total",
+			modify: func(d *db.CreateScoreData) { d.Players[0].Score = 3787450 },
+			want: []db.Warning{
+				{Rule: RulePlayerScore, Field: "players.0.score", Message: "player score 3787450 is more than the total score 447253"},
+				{Rule: RuleScoreSum, Field: "total_score", Message: "player scores add up to 3855958, not the total score 447253"},
+			},
+		},
+		{
+			name:   "accuracy disagrees with the notes",
+			modify: func(d *db.CreateScoreData) { d.Players[0].Accuracy = 85 },
+			want:   []db.Warning{{Rule: RuleAccuracyNotes, Field: "players.0.accuracy", Message: "accuracy 85% but 1773 of 1852 notes hit is 95.7%"}},
+		},
+		{
+			name:   "accuracy over 100",
+			modify: func(d *db.CreateScoreData) { d.Players[1].Accuracy = 810 },
+			want:   []db.Warning{{Rule: RuleAccuracyRange, Field: "players.1.accuracy", Message: "accuracy 810% is outside 0-100%"}},
+		},
+		{
+			name:   "note counts don't add up",
+			modify: func(d *db.CreateScoreData) { d.Players[0].NotesMissed = 7 },
+			want:   []db.Warning{{Rule: RuleNoteCounts, Field: "players.0.total_notes", Message: "1773 notes hit and 7 missed don't add up to 1852 total notes"}},
+		},
+		{
+			name:   "streak longer than the notes hit",
+			modify: func(d *db.CreateScoreData) { d.Players[1].BestStreak = 5000 },
+			want:   []db.Warning{{Rule: RuleStreak, Field: "players.1.best_streak", Message: "best streak 5000 is longer than the 1015 notes hit"}},
+		},
+		{
+			name:   "multiplier out of range",
+			modify: func(d *db.CreateScoreData) { d.Players[0].AvgMultiplier = 2921 },
+			want:   []db.Warning{{Rule: RuleMultiplierRange, Field: "players.0.avg_multiplier", Message: "average multiplier 2921x is outside 0-8x"}},
+		},
+		{
+			name:   "unknown difficulty",
+			modify: func(d *db.CreateScoreData) { d.Players[1].Difficulty = "Expart" },
+			want:   []db.Warning{{Rule: RuleDifficulty, Field: "players.1.difficulty", Message: `unknown difficulty "Expart"`}},
+		},
+		{
+			name: "no part",
+			modify: func(d *db.CreateScoreData) {
+				d.Players[1] = db.Player{Name: "A_Hole_Pro", Instrument: "no part"}
+				d.TotalScore = 378745
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data := trippingBillies()
+			tc.modify(&data)
+			assert.Equal(t, tc.want, Score(data))
+		})
+	}
+}
@@ -2,14 +2,18 @@ package watcher
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
+	"cloneheroer/internal/skip"
+
 	"github.com/fsnotify/fsnotify"
 )
 
@@ -18,9 +22,33 @@ type Watcher struct {
 	watchDir     string
 	processedDir string
 	failedDir    string
+	skippedDir   string
 	onNewFile    func(string) error
 	watcher      *fsnotify.Watcher
-	processed    map[string]bool
+	workers      int
+
+	mu        sync.Mutex
+	processed map[string]bool
+}
+
+// Option configures optional Watcher behaviour.
+type Option func(*Watcher)
+
+// WithWorkers sets how many files already in the watch directory are
+// processed at once on start. The default is 1. onNewFile must be safe for
+// concurrent use when this is above 1.
+func WithWorkers(n int) Option {
+	return func(w *Watcher) {
+		w.workers = n
+	}
+}
+
+// WithSkippedDir sets where files onNewFile skips with skip.Err are moved.
+// Without it they stay in the watch directory.
+func WithSkippedDir(dir string) Option {
+	return func(w *Watcher) {
+		w.skippedDir = dir
+	}
 }
 
 // normalizePath normalizes a file path, handling spaces and ensuring it's absolute.
@@ -39,7 +67,7 @@ func normalizePath(path string) (string, error) {
 }
 
 // NewWatcher creates a new file watcher.
-func NewWatcher(watchDir, processedDir, failedDir string, onNewFile func(string) error) (*Watcher, error) {
+func NewWatcher(watchDir, processedDir, failedDir string, onNewFile func(string) error, opts ...Option) (*Watcher, error) {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create watcher: %w", err)
@@ -73,8 +101,21 @@ func NewWatcher(watchDir, processedDir, failedDir string, onNewFile func(string)
 		failedDir:    normalizedFailedDir,
 		onNewFile:    onNewFile,
 		watcher:      watcher,
+		workers:      1,
 		processed:    make(map[string]bool),
 	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if w.workers < 1 {
+		w.workers = 1
+	}
+	if w.skippedDir != "" {
+		w.skippedDir, err = normalizePath(w.skippedDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to normalize skipped directory: %w", err)
+		}
+	}
 
 	return w, nil
 }
@@ -95,6 +136,11 @@ func (w *Watcher) Start(ctx context.Context) error {
 			return fmt.Errorf("failed to create failed directory: %w", err)
 		}
 	}
+	if w.skippedDir != "" {
+		if err := os.MkdirAll(w.skippedDir, 0755); err != nil {
+			return fmt.Errorf("failed to create skipped directory: %w", err)
+		}
+	}
 
 	// Add watch directory (already normalized in NewWatcher)
 	log.Printf("adding watch directory: %q", w.watchDir)
@@ -118,13 +164,15 @@ func (w *Watcher) Start(ctx context.Context) error {
 	return nil
 }
 
-// processExistingFiles processes all image files already in the watch directory.
+// processExistingFiles processes all image files already in the watch
+// directory, spread across the configured number of workers.
 func (w *Watcher) processExistingFiles() error {
 	entries, err := os.ReadDir(w.watchDir)
 	if err != nil {
 		return err
 	}
 
+	var paths []string
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
@@ -141,25 +189,56 @@ func (w *Watcher) processExistingFiles() error {
 			normalizedLoc = loc
 		}
 
-		log.Printf("processing existing file: %q", normalizedLoc)
-		if w.processed[normalizedLoc] {
+		if w.isProcessed(normalizedLoc) {
 			log.Printf("skipping already processed file: %q", normalizedLoc)
 			continue
 		}
+		paths = append(paths, normalizedLoc)
+	}
 
-		// Wait a bit to ensure file is fully written
-		time.Sleep(100 * time.Millisecond)
+	queue := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < min(w.workers, len(paths)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for loc := range queue {
+				log.Printf("processing existing file: %q", loc)
 
-		if err := w.handleFile(normalizedLoc); err != nil {
-			log.Printf("error processing existing file %s: %v", normalizedLoc, err)
-		} else {
-			w.processed[normalizedLoc] = true
-		}
+				// Wait a bit to ensure file is fully written
+				time.Sleep(100 * time.Millisecond)
+
+				if err := w.handleFile(loc); err != nil {
+					log.Printf("error processing existing file %s: %v", loc, err)
+				} else {
+					w.markProcessed(loc)
+				}
+			}
+		}()
 	}
+	for _, loc := range paths {
+		queue <- loc
+	}
+	close(queue)
+	wg.Wait()
 
 	return nil
 }
 
+// isProcessed reports whether path was already handled successfully.
+func (w *Watcher) isProcessed(path string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.processed[path]
+}
+
+// markProcessed records that path was handled successfully.
+func (w *Watcher) markProcessed(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.processed[path] = true
+}
+
 // pollLoop periodically checks for new files as a fallback for fsnotify.
 // This is especially important for Windows mounts in WSL2 where fsnotify
 // may not reliably detect files created on the Windows side.
@@ -195,7 +274,7 @@ func (w *Watcher) pollLoop(ctx context.Context) {
 				}
 
 				// Skip if already processed
-				if w.processed[normalizedLoc] {
+				if w.isProcessed(normalizedLoc) {
 					continue
 				}
 
@@ -204,7 +283,7 @@ func (w *Watcher) pollLoop(ctx context.Context) {
 				if err := w.handleFile(normalizedLoc); err != nil {
 					log.Printf("error processing file from poll: %s: %v", normalizedLoc, err)
 				} else {
-					w.processed[normalizedLoc] = true
+					w.markProcessed(normalizedLoc)
 				}
 			}
 		}
@@ -232,7 +311,7 @@ func (w *Watcher) watchLoop(ctx context.Context) {
 
 				if isImageFile(normalizedPath) {
 					// Check if already processed
-					if w.processed[normalizedPath] {
+					if w.isProcessed(normalizedPath) {
 						log.Printf("skipping already processed file: %q", normalizedPath)
 						continue
 					}
@@ -250,7 +329,7 @@ func (w *Watcher) watchLoop(ctx context.Context) {
 					if err := w.handleFile(normalizedPath); err != nil {
 						log.Printf("error processing file %s: %v", normalizedPath, err)
 					} else {
-						w.processed[normalizedPath] = true
+						w.markProcessed(normalizedPath)
 					}
 				}
 			}
@@ -406,7 +485,7 @@ func (w *Watcher) handleFile(loc string) error {
 		normalizedLoc = loc
 	}
 
-	if w.processed[normalizedLoc] {
+	if w.isProcessed(normalizedLoc) {
 		log.Printf("file already processed, skipping: %q", normalizedLoc)
 		return nil
 	}
@@ -419,7 +498,17 @@ func (w *Watcher) handleFile(loc string) error {
 	}
 
 	// Call the callback to process the file
-	if err := w.onNewFile(normalizedLoc); err != nil {
+	err = w.onNewFile(normalizedLoc)
+	if errors.Is(err, skip.Err) {
+		log.Printf("skipped file: %v", err)
+		if w.skippedDir != "" {
+			if moveErr := w.moveFile(normalizedLoc, w.skippedDir); moveErr != nil {
+				log.Printf("error: failed to move file to skipped directory: %v", moveErr)
+			}
+		}
+		return nil
+	}
+	if err != nil {
 		// Move to failed directory if configured
 		if w.failedDir != "" {
 			if moveErr := w.moveFile(normalizedLoc, w.failedDir); moveErr != nil {
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_scores_confidence;
+ALTER TABLE scores DROP COLUMN IF EXISTS ocr_confidence;
+ALTER TABLE scores DROP COLUMN IF EXISTS confidence;
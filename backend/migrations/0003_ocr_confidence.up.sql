@@ -0,0 +1,3 @@
+ALTER TABLE scores ADD COLUMN confidence NUMERIC;
+ALTER TABLE scores ADD COLUMN ocr_confidence JSONB;
+CREATE INDEX IF NOT EXISTS idx_scores_confidence ON scores(confidence);
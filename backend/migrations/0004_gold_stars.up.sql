@@ -0,0 +1 @@
+ALTER TABLE scores ADD COLUMN gold_stars BOOLEAN NOT NULL DEFAULT false;
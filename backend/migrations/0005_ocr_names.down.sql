@@ -0,0 +1,2 @@
+ALTER TABLE scores DROP COLUMN IF EXISTS ocr_song_name;
+ALTER TABLE scores DROP COLUMN IF EXISTS ocr_artist;
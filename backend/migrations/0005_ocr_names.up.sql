@@ -0,0 +1,2 @@
+ALTER TABLE scores ADD COLUMN ocr_artist TEXT;
+ALTER TABLE scores ADD COLUMN ocr_song_name TEXT;
@@ -0,0 +1,5 @@
+ALTER TABLE songs DROP COLUMN IF EXISTS difficulties;
+ALTER TABLE songs DROP COLUMN IF EXISTS song_length_ms;
+ALTER TABLE songs DROP COLUMN IF EXISTS genre;
+ALTER TABLE songs DROP COLUMN IF EXISTS year;
+ALTER TABLE songs DROP COLUMN IF EXISTS album;
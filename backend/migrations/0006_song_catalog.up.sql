@@ -0,0 +1,5 @@
+ALTER TABLE songs ADD COLUMN album TEXT;
+ALTER TABLE songs ADD COLUMN year INTEGER;
+ALTER TABLE songs ADD COLUMN genre TEXT;
+ALTER TABLE songs ADD COLUMN song_length_ms INTEGER;
+ALTER TABLE songs ADD COLUMN difficulties JSONB;
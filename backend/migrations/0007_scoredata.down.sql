@@ -0,0 +1,8 @@
+UPDATE players SET created_at = now() WHERE created_at IS NULL;
+UPDATE scores SET created_at = now() WHERE created_at IS NULL;
+ALTER TABLE players ALTER COLUMN created_at SET NOT NULL;
+ALTER TABLE scores ALTER COLUMN created_at SET NOT NULL;
+
+ALTER TABLE scores DROP COLUMN IF EXISTS source;
+DROP INDEX IF EXISTS idx_songs_chart_hash;
+ALTER TABLE songs DROP COLUMN IF EXISTS chart_hash;
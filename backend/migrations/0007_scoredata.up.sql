@@ -0,0 +1,7 @@
+ALTER TABLE songs ADD COLUMN chart_hash TEXT;
+CREATE INDEX IF NOT EXISTS idx_songs_chart_hash ON songs(chart_hash);
+ALTER TABLE scores ADD COLUMN source TEXT NOT NULL DEFAULT 'screenshot';
+
+-- scoredata.bin doesn't record when a run was played
+ALTER TABLE scores ALTER COLUMN created_at DROP NOT NULL;
+ALTER TABLE players ALTER COLUMN created_at DROP NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE players DROP COLUMN IF EXISTS full_combo;
+ALTER TABLE players DROP COLUMN IF EXISTS star_power_total;
+ALTER TABLE players DROP COLUMN IF EXISTS star_power_hit;
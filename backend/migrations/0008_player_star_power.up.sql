@@ -0,0 +1,3 @@
+ALTER TABLE players ADD COLUMN star_power_hit INTEGER;
+ALTER TABLE players ADD COLUMN star_power_total INTEGER;
+ALTER TABLE players ADD COLUMN full_combo BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,2 @@
+ALTER TABLE scores ADD COLUMN warnings JSONB;
+CREATE INDEX IF NOT EXISTS idx_scores_has_warnings ON scores((warnings IS NOT NULL));
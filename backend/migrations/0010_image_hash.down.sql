@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_scores_duplicate;
+ALTER TABLE scores DROP COLUMN IF EXISTS duplicate;
+ALTER TABLE scores DROP COLUMN IF EXISTS duplicate_of;
+ALTER TABLE scores DROP COLUMN IF EXISTS image_hash;
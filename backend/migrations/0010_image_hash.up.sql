@@ -0,0 +1,6 @@
+ALTER TABLE scores ADD COLUMN image_hash TEXT;
+ALTER TABLE scores ADD COLUMN duplicate_of INTEGER REFERENCES scores(id) ON DELETE SET NULL;
+-- duplicate_of is cleared when the original is deleted, so the flag needs its
+-- own column to survive that
+ALTER TABLE scores ADD COLUMN duplicate BOOLEAN NOT NULL DEFAULT false;
+CREATE INDEX IF NOT EXISTS idx_scores_duplicate ON scores(created_at DESC, id DESC) WHERE duplicate;
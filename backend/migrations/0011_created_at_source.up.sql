@@ -0,0 +1 @@
+ALTER TABLE scores ADD COLUMN created_at_source TEXT;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_players_personal_best_id;
+ALTER TABLE players DROP COLUMN IF EXISTS personal_best_id;
+ALTER TABLE players DROP COLUMN IF EXISTS previous_best;
+ALTER TABLE players DROP COLUMN IF EXISTS new_pb;
+DROP TABLE IF EXISTS personal_bests;
@@ -0,0 +1,75 @@
+CREATE TABLE IF NOT EXISTS personal_bests (
+    id SERIAL PRIMARY KEY,
+    player_name TEXT NOT NULL,
+    song_id INTEGER NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    instrument TEXT NOT NULL DEFAULT '',
+    difficulty TEXT NOT NULL DEFAULT '',
+    score BIGINT NOT NULL,
+    accuracy NUMERIC,
+    best_streak INTEGER,
+    full_combo BOOLEAN NOT NULL DEFAULT false,
+    player_id INTEGER REFERENCES players(id) ON DELETE SET NULL,
+    achieved_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (player_name, song_id, instrument, difficulty)
+);
+
+ALTER TABLE players ADD COLUMN new_pb BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE players ADD COLUMN previous_best BIGINT;
+-- Each run points at the personal best it counts towards, so a best's history
+-- survives renames
+ALTER TABLE players ADD COLUMN personal_best_id INTEGER REFERENCES personal_bests(id) ON DELETE SET NULL;
+
+-- Replay the scores stored so far in capture order, runs with no capture time
+-- first, leaving duplicates out
+WITH runs AS (
+    SELECT p.id,
+           p.score,
+           MAX(p.score) OVER (
+               PARTITION BY p.name, s.song_id, COALESCE(p.instrument, ''), COALESCE(p.difficulty, '')
+               ORDER BY s.created_at NULLS FIRST, p.id
+               ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING
+           ) AS previous_best
+    FROM players p
+    JOIN scores s ON s.id = p.score_id
+    JOIN songs ON songs.id = s.song_id
+    WHERE p.name <> '' AND songs.name <> '' AND NOT s.duplicate
+)
+UPDATE players
+SET new_pb = runs.score IS NOT NULL AND (runs.previous_best IS NULL OR runs.score > runs.previous_best),
+    previous_best = runs.previous_best
+FROM runs
+WHERE players.id = runs.id;
+
+INSERT INTO personal_bests (player_name, song_id, instrument, difficulty, score, accuracy, best_streak, full_combo, player_id, achieved_at)
+SELECT best.name, best.song_id, best.instrument, best.difficulty, best.score, totals.accuracy, totals.best_streak, totals.full_combo, best.id, best.created_at
+FROM (
+    SELECT DISTINCT ON (p.name, s.song_id, COALESCE(p.instrument, ''), COALESCE(p.difficulty, ''))
+           p.id, p.name, s.song_id, COALESCE(p.instrument, '') AS instrument, COALESCE(p.difficulty, '') AS difficulty, p.score, s.created_at
+    FROM players p
+    JOIN scores s ON s.id = p.score_id
+    JOIN songs ON songs.id = s.song_id
+    WHERE p.name <> '' AND songs.name <> '' AND NOT s.duplicate AND p.score IS NOT NULL
+    ORDER BY p.name, s.song_id, COALESCE(p.instrument, ''), COALESCE(p.difficulty, ''), p.score DESC, s.created_at NULLS FIRST, p.id
+) best
+JOIN (
+    SELECT p.name, s.song_id, COALESCE(p.instrument, '') AS instrument, COALESCE(p.difficulty, '') AS difficulty,
+           MAX(p.accuracy) AS accuracy, MAX(p.best_streak) AS best_streak, COALESCE(bool_or(p.full_combo), false) AS full_combo
+    FROM players p
+    JOIN scores s ON s.id = p.score_id
+    WHERE NOT s.duplicate
+    GROUP BY p.name, s.song_id, COALESCE(p.instrument, ''), COALESCE(p.difficulty, '')
+) totals USING (name, song_id, instrument, difficulty);
+
+UPDATE players p
+SET personal_best_id = pb.id
+FROM scores s, personal_bests pb
+WHERE s.id = p.score_id
+  AND NOT s.duplicate
+  AND pb.player_name = p.name
+  AND pb.song_id = s.song_id
+  AND pb.instrument = COALESCE(p.instrument, '')
+  AND pb.difficulty = COALESCE(p.difficulty, '');
+
+CREATE INDEX IF NOT EXISTS idx_personal_bests_song_id ON personal_bests(song_id);
+CREATE INDEX IF NOT EXISTS idx_players_personal_best_id ON players(personal_best_id);
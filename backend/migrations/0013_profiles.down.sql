@@ -0,0 +1,12 @@
+-- A profile's personal bests can't be split back up by name; they're dropped
+DELETE FROM personal_bests WHERE profile_id IS NOT NULL;
+DROP INDEX IF EXISTS idx_personal_bests_profile;
+DROP INDEX IF EXISTS idx_personal_bests_name;
+ALTER TABLE personal_bests ADD CONSTRAINT personal_bests_player_name_song_id_instrument_difficulty_key UNIQUE (player_name, song_id, instrument, difficulty);
+ALTER TABLE personal_bests DROP COLUMN IF EXISTS profile_id;
+
+DROP INDEX IF EXISTS idx_players_name;
+DROP INDEX IF EXISTS idx_players_profile_id;
+ALTER TABLE players DROP COLUMN IF EXISTS profile_id;
+DROP TABLE IF EXISTS profile_aliases;
+DROP TABLE IF EXISTS profiles;
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS profiles (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Each player name belongs to at most one profile
+CREATE TABLE IF NOT EXISTS profile_aliases (
+    alias TEXT PRIMARY KEY,
+    profile_id INTEGER NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE players ADD COLUMN profile_id INTEGER REFERENCES profiles(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_profile_aliases_profile_id ON profile_aliases(profile_id);
+CREATE INDEX IF NOT EXISTS idx_players_profile_id ON players(profile_id);
+CREATE INDEX IF NOT EXISTS idx_players_name ON players(name);
+
+-- Runs under every alias of a profile share one personal best, kept by
+-- profile instead of name. No profiles exist yet, so the stored bests stay
+-- keyed by name.
+ALTER TABLE personal_bests ADD COLUMN profile_id INTEGER REFERENCES profiles(id) ON DELETE CASCADE;
+ALTER TABLE personal_bests DROP CONSTRAINT IF EXISTS personal_bests_player_name_song_id_instrument_difficulty_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_personal_bests_name ON personal_bests(player_name, song_id, instrument, difficulty) WHERE profile_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_personal_bests_profile ON personal_bests(profile_id, song_id, instrument, difficulty) WHERE profile_id IS NOT NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_songs_name_trgm;
+DROP INDEX IF EXISTS idx_artists_name_trgm;
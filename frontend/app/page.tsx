@@ -8,7 +8,8 @@ type Score = {
   charter?: string | null;
   total_score?: number | null;
   stars_achieved?: number | null;
-  created_at: string;
+  // null when the source has no capture time, like scoredata.bin imports
+  created_at: string | null;
 };
 
 type Artist = {
@@ -91,7 +92,11 @@ export default function Page() {
                     <td>{s.charter ?? "—"}</td>
                     <td>{s.total_score?.toLocaleString() ?? "—"}</td>
                     <td>{s.stars_achieved ?? "—"}</td>
-                    <td>{new Date(s.created_at).toLocaleString()}</td>
+                    <td>
+                      {s.created_at
+                        ? new Date(s.created_at).toLocaleString()
+                        : "—"}
+                    </td>
                   </tr>
                 ))
               )}